@@ -2,13 +2,18 @@ module github.com/jbarasa/jbmdb // v2.0.0
 
 go 1.23.4
 
-require github.com/jackc/pgx/v5 v5.7.2
+require (
+	github.com/jackc/pgx/v5 v5.7.2
+	golang.org/x/sync v0.10.0
+	golang.org/x/term v0.27.0
+)
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/golang/snappy v0.0.3 // indirect
 	github.com/hailocab/go-hostpool v0.0.0-20160125115350-e80d13ce29ed // indirect
 	github.com/rogpeppe/go-internal v1.13.1 // indirect
+	golang.org/x/sys v0.28.0 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
 )
 
@@ -19,6 +24,5 @@ require (
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	golang.org/x/crypto v0.31.0 // indirect
-	golang.org/x/sync v0.10.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 )
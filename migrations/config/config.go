@@ -6,10 +6,23 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
 )
 
 const (
 	configFile = ".jbmdb.conf"
+
+	// globalConfigDir and globalConfigFile locate the global config under $HOME
+	globalConfigDir  = ".jbmdb"
+	globalConfigFile = "config.json"
+
+	// configPathEnv names a config file to use exclusively, like --config, and
+	// configDirEnv a directory to use the .jbmdb.conf of, like --config-dir
+	configPathEnv = "JBMDB_CONFIG"
+	configDirEnv  = "JBMDB_CONFIG_DIR"
 )
 
 // Config represents the base configuration structure
@@ -22,6 +35,7 @@ type Config struct {
 // PostgresConfig represents PostgreSQL specific configuration
 type PostgresConfig struct {
 	MigrationPath string `json:"migration_path"`
+	TablePrefix   string `json:"table_prefix,omitempty"` // Scope jbmdb to tables named <prefix>_*
 	SQLFolder     string `json:"sql_folder"`
 	Host          string `json:"host"`
 	Port          string `json:"port"`
@@ -30,11 +44,25 @@ type PostgresConfig struct {
 	DBName        string `json:"dbname"`
 	SuperUser     string `json:"super_user"`
 	SuperPass     string `json:"super_pass"`
+
+	BackupBeforeFresh bool   `json:"backup_before_fresh"` // Dump the database before a fresh migration
+	BackupPath        string `json:"backup_path"`         // Directory for backups, defaults to "backups"
+	DisplayTimezone   string `json:"display_timezone"`    // "local" (default), "utc" or an IANA name
+
+	CaptureWALPosition bool `json:"capture_wal_position"` // Print the WAL position before and after migrate
+
+	TemplateDir string `json:"template_dir,omitempty"` // Directory of .tmpl files new migrations are generated from
+
+	SSLMode     string `json:"ssl_mode,omitempty"`      // disable (default), require, verify-ca or verify-full
+	SSLRootCert string `json:"ssl_root_cert,omitempty"` // CA certificate verify-ca and verify-full check the server with
+	SSLCert     string `json:"ssl_cert,omitempty"`      // Client certificate, requires SSLKey
+	SSLKey      string `json:"ssl_key,omitempty"`       // Client private key
 }
 
 // MySQLConfig represents MySQL/MariaDB specific configuration
 type MySQLConfig struct {
 	MigrationPath string `json:"migration_path"`
+	TablePrefix   string `json:"table_prefix,omitempty"` // Scope jbmdb to tables named <prefix>_*
 	SQLFolder     string `json:"sql_folder"`
 	Host          string `json:"host"`
 	Port          string `json:"port"`
@@ -43,38 +71,251 @@ type MySQLConfig struct {
 	DBName        string `json:"dbname"`
 	SuperUser     string `json:"super_user"`
 	SuperPass     string `json:"super_pass"`
+
+	BackupBeforeFresh bool   `json:"backup_before_fresh"` // Dump the database before a fresh migration
+	BackupPath        string `json:"backup_path"`         // Directory for backups, defaults to "backups"
+	DisplayTimezone   string `json:"display_timezone"`    // "local" (default), "utc" or an IANA name
+	UsePtOSC          bool   `json:"use_pt_osc"`          // Run ALTER TABLE through pt-online-schema-change
+
+	CaptureBinlogPosition bool `json:"capture_binlog_position"` // Print the binlog position before and after migrate
+	LockTimeout           int  `json:"lock_timeout,omitempty"`  // Seconds to wait for another migration process, defaults to 30
+
+	TLSMode     string `json:"tls_mode,omitempty"` // true, skip-verify, preferred, false or a registered TLS config name
+	TLSCAFile   string `json:"tls_ca_file"`        // CA certificate to verify the server with
+	TLSCertFile string `json:"tls_cert_file"`      // Client certificate, requires TLSKeyFile
+	TLSKeyFile  string `json:"tls_key_file"`       // Client private key
+
+	Charset   string `json:"charset"`   // Connection and new table charset, defaults to utf8mb4
+	Collation string `json:"collation"` // Connection and new table collation, defaults to utf8mb4_unicode_ci
 }
 
 // ScyllaConfig represents CQL database (Cassandra/ScyllaDB) specific configuration
 type ScyllaConfig struct {
-	MigrationPath string   `json:"migration_path"`
-	CQLFolder     string   `json:"cql_folder"`
-	Hosts         []string `json:"hosts"`
-	Port          int      `json:"port"`         // Using int as gocql expects port as integer
-	Keyspace      string   `json:"keyspace"`
-	User          string   `json:"user"`
-	Password      string   `json:"password"`
-	SuperUser     string   `json:"super_user"`
-	SuperPass     string   `json:"super_pass"`
-	Datacenter    string   `json:"datacenter"`   // For NetworkTopologyStrategy
-	Consistency   string   `json:"consistency"`  // For custom consistency levels
+	MigrationPath   string   `json:"migration_path"`
+	TablePrefix     string   `json:"table_prefix,omitempty"` // Scope jbmdb to tables named <prefix>_*
+	CQLFolder       string   `json:"cql_folder"`
+	Hosts           []string `json:"hosts"`
+	Port            int      `json:"port"` // Using int as gocql expects port as integer
+	Keyspace        string   `json:"keyspace"`
+	User            string   `json:"user"`
+	Password        string   `json:"password"`
+	SuperUser       string   `json:"super_user"`
+	SuperPass       string   `json:"super_pass"`
+	Datacenter      string   `json:"datacenter"`       // For NetworkTopologyStrategy
+	Consistency     string   `json:"consistency"`      // For custom consistency levels
+	DisplayTimezone string   `json:"display_timezone"` // "local" (default), "utc" or an IANA name
+
+	SchemaAgreementTimeout int `json:"schema_agreement_timeout"` // Seconds to wait for schema agreement after DDL, defaults to 60
+
+	ProtoVersion            int `json:"proto_version"`              // CQL native protocol version, defaults to 4
+	Timeout                 int `json:"timeout"`                    // Seconds to wait for a query, defaults to 11
+	ConnectTimeoutSeconds   int `json:"connect_timeout_seconds"`    // Seconds to wait for a connection, defaults to 11
+	WriteCoalesceWaitMicros int `json:"write_coalesce_wait_micros"` // Microseconds to batch writes to a connection, defaults to 200
+
+	PoolConfig PoolConfig `json:"pool_config"` // Connection pool tuning
+}
+
+// PoolConfig tunes the gocql connection pool. Unset values keep the gocql defaults.
+type PoolConfig struct {
+	NumConns          int    `json:"num_conns"`          // Connections per host, defaults to 2
+	ReconnectInterval string `json:"reconnect_interval"` // Duration between reconnection attempts, e.g. "1s"
+	MaxPreparedStmts  int    `json:"max_prepared_stmts"` // Size of the prepared statement cache, defaults to 1000
+	HostFilter        string `json:"host_filter"`        // Datacenter to connect to, empty for all hosts
+}
+
+// ToolConfig holds settings of the jbmdb tool itself
+type ToolConfig struct {
+	AutoCheckUpdates    bool `json:"auto_check_updates"`     // Check for a new release on every run
+	AutoUpdateOnStartup bool `json:"auto_update_on_startup"` // Install a new release before running the command
 }
 
 // JBMDBConfig represents the complete configuration
 type JBMDBConfig struct {
+	Version  int             `json:"version"` // Config file format version, see MigrateFormat
 	Postgres *PostgresConfig `json:"postgres,omitempty"`
 	Scylla   *ScyllaConfig   `json:"scylla,omitempty"`
 	MySQL    *MySQLConfig    `json:"mysql,omitempty"`
+	Tool     *ToolConfig     `json:"tool,omitempty"`
+
+	legacy legacyFields // Values from older formats, only used by format migrations
 }
 
 var currentConfig *JBMDBConfig
 
+// configPath overrides the config file location when set with SetConfigPath.
+// An explicit path is used exclusively: no global fallback or merging applies.
+// Config files take precedence in this order: --config, JBMDB_CONFIG, .jbmdb.conf in
+// the working directory, .jbmdb.conf in a parent directory, ~/.jbmdb/config.json.
+// An explicit --config-dir overrides JBMDB_CONFIG.
+var configPath string
+
+// SetConfigPath sets an explicit config file path to read from and write to
+func SetConfigPath(path string) {
+	configPath = path
+}
+
+// configDir overrides the directory .jbmdb.conf is looked up in, see SetConfigDir
+var configDir string
+
+// foundConfigFile caches the result of findConfigFile
+var foundConfigFile string
+
+// SetConfigDir makes jbmdb use the .jbmdb.conf in dir instead of searching for one
+func SetConfigDir(dir string) {
+	configDir = dir
+	foundConfigFile = ""
+}
+
+// findConfigFile returns the .jbmdb.conf to use. Unless a config dir is set with
+// --config-dir or JBMDB_CONFIG_DIR, it walks up from the working directory and returns
+// the first one found, stopping at the repository root (a directory containing .git)
+// or the filesystem root. Without a match, the file in the working directory is
+// returned so it can be created there.
+func findConfigFile() (string, error) {
+	if foundConfigFile != "" {
+		return foundConfigFile, nil
+	}
+	if configDir != "" {
+		foundConfigFile = filepath.Join(configDir, configFile)
+		return foundConfigFile, nil
+	}
+	if dir := os.Getenv(configDirEnv); dir != "" {
+		foundConfigFile = filepath.Join(dir, configFile)
+		return foundConfigFile, nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	for dir := wd; ; {
+		path := filepath.Join(dir, configFile)
+		if _, err := os.Stat(path); err == nil {
+			if dir != wd {
+				fmt.Printf("[CONFIG] Using config from %s\n", path)
+				foundConfigFile = path
+			} else {
+				foundConfigFile = configFile
+			}
+			return foundConfigFile, nil
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	foundConfigFile = configFile
+	return foundConfigFile, nil
+}
+
+// DisplayLocation resolves a DisplayTimezone setting to a location for formatting
+// timestamps. An empty value or "local" uses the local timezone, "utc" uses UTC and
+// anything else is loaded as an IANA timezone name.
+func DisplayLocation(timezone string) (*time.Location, error) {
+	switch strings.ToLower(timezone) {
+	case "", "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid display timezone %q: %w", timezone, err)
+	}
+	return loc, nil
+}
+
+// Consistency levels accepted by the consistency setting of the cql section
+const validConsistencyLevels = "ONE, QUORUM, LOCAL_QUORUM, EACH_QUORUM, ALL, TWO, THREE, LOCAL_ONE, ANY"
+
+// validateConsistency checks a consistency setting when the config is loaded, so a
+// typo fails with the valid levels instead of deep inside a CQL command.
+// Empty means the QUORUM default.
+func validateConsistency(consistency string) error {
+	if consistency == "" {
+		return nil
+	}
+	if _, err := gocql.ParseConsistencyWrapper(consistency); err != nil {
+		return fmt.Errorf("invalid consistency %q in the cql config: must be one of %s",
+			consistency, validConsistencyLevels)
+	}
+	return nil
+}
+
+// GlobalConfigPath returns the path of the global config file, $HOME/.jbmdb/config.json
+func GlobalConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, globalConfigDir, globalConfigFile), nil
+}
+
+// ConfigPath returns the config file that SaveConfig writes to
+func ConfigPath() string {
+	if configPath != "" {
+		return configPath
+	}
+	if path := os.Getenv(configPathEnv); path != "" && configDir == "" {
+		return path
+	}
+	if path, err := findConfigFile(); err == nil {
+		return path
+	}
+	return configFile
+}
+
+// LoadFullConfig loads the complete configuration, merged from all config files
+func LoadFullConfig() (*JBMDBConfig, error) {
+	if err := loadConfigFile(); err != nil {
+		return nil, fmt.Errorf("failed to load config file: %w", err)
+	}
+	return currentConfig, nil
+}
+
+// LoadedToolConfig returns the tool settings of the config read by the last LoadConfig
+// or LoadFullConfig call, or nil if none were read or the config has no tool section
+func LoadedToolConfig() *ToolConfig {
+	if currentConfig == nil {
+		return nil
+	}
+	return currentConfig.Tool
+}
+
 // LoadConfig loads configuration from file
 func LoadConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig](configType string) (*T, error) {
+	return loadConfig[T](configType, true)
+}
+
+// LoadFileConfig loads configuration like LoadConfig, but without the JBMDB_<TYPE>_<FIELD>
+// overrides. The init wizards start from it, so the values they save to the config
+// file never include variables meant for a single run, such as passwords.
+func LoadFileConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig](configType string) (*T, error) {
+	return loadConfig[T](configType, false)
+}
+
+// loadConfig loads the configType section from the config files, with the environment
+// overrides applied if withEnv is set
+func loadConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig](configType string, withEnv bool) (*T, error) {
 	if err := loadConfigFile(); err != nil {
 		return nil, fmt.Errorf("failed to load config file: %w", err)
 	}
 
+	// JBMDB_<TYPE>_<FIELD> variables override the file, which only provides defaults
+	if withEnv {
+		if _, err := applyEnv(currentConfig); err != nil {
+			return nil, err
+		}
+	}
+
 	var config T
 	switch configType {
 	case "postgres":
@@ -93,6 +334,9 @@ func LoadConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig](configTy
 		if sc, ok := any(&config).(*ScyllaConfig); ok {
 			*sc = *currentConfig.Scylla
 		}
+		if err := validateConsistency(currentConfig.Scylla.Consistency); err != nil {
+			return nil, err
+		}
 	case "mysql":
 		if currentConfig.MySQL == nil {
 			// Return default config if not configured
@@ -110,19 +354,24 @@ func LoadConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig](configTy
 
 // SaveConfig saves configuration to file and creates necessary directories
 func SaveConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig](config T, configType string) error {
-	if err := loadConfigFile(); err != nil && !os.IsNotExist(err) {
+	// Only the target file is read so merged global values are not copied into it
+	currentConfig = &JBMDBConfig{}
+	if err := readConfigFile(ConfigPath(), currentConfig); err != nil {
 		return fmt.Errorf("failed to load existing config: %w", err)
 	}
 
-	if currentConfig == nil {
-		currentConfig = &JBMDBConfig{}
-	}
-
-	// Get current working directory
-	cwd, err := os.Getwd()
+	// Relative paths in a .jbmdb.conf are relative to its directory, see resolveMigrationPaths
+	baseDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %w", err)
 	}
+	if localPath, err := findConfigFile(); err == nil && localPath == ConfigPath() {
+		if dir := filepath.Dir(localPath); filepath.IsAbs(dir) {
+			baseDir = dir
+		} else {
+			baseDir = filepath.Join(baseDir, dir)
+		}
+	}
 
 	// Create migration directories based on config type
 	var migrationPath, subFolder string
@@ -165,7 +414,7 @@ func SaveConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig](config T
 	if migrationPath != "" {
 		// Convert to absolute path if relative
 		if !filepath.IsAbs(migrationPath) {
-			migrationPath = filepath.Join(cwd, migrationPath)
+			migrationPath = filepath.Join(baseDir, migrationPath)
 		}
 
 		// Create migration directory if it doesn't exist
@@ -188,32 +437,105 @@ func SaveConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig](config T
 		}
 	}
 
-	data, err := json.MarshalIndent(currentConfig, "", "  ")
+	return writeConfigFile(currentConfig)
+}
+
+// loadConfigFile loads the configuration into currentConfig. Without an explicit
+// config path, the global config is loaded first and the local .jbmdb.conf, found by
+// findConfigFile, is merged over it, so local values override global ones field by field.
+func loadConfigFile() error {
+	currentConfig = &JBMDBConfig{}
+
+	if configPath != "" {
+		return readConfigFile(configPath, currentConfig)
+	}
+	if path := os.Getenv(configPathEnv); path != "" && configDir == "" {
+		return readConfigFile(path, currentConfig)
+	}
+
+	if globalPath, err := GlobalConfigPath(); err == nil {
+		if err := readConfigFile(globalPath, currentConfig); err != nil {
+			return err
+		}
+	}
+
+	localPath, err := findConfigFile()
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
+	if err := readConfigFile(localPath, currentConfig); err != nil {
+		return err
+	}
+	return resolveMigrationPaths(localPath, currentConfig)
+}
 
-	if err := os.WriteFile(configFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+// resolveMigrationPaths makes the relative migration paths set by the .jbmdb.conf at
+// path relative to its directory, so a config found in a parent directory or given
+// with --config-dir points at the same migrations from any working directory.
+func resolveMigrationPaths(path string, cfg *JBMDBConfig) error {
+	dir := filepath.Dir(path)
+	if dir == "." {
+		return nil
 	}
 
+	// Only the paths set by this file, not the ones merged from the global config
+	fileCfg := &JBMDBConfig{}
+	if err := readConfigFile(path, fileCfg); err != nil {
+		return err
+	}
+	resolve := func(set string, target *string) {
+		if set != "" && !filepath.IsAbs(set) {
+			*target = filepath.Join(dir, set)
+		}
+	}
+	if fileCfg.Postgres != nil {
+		resolve(fileCfg.Postgres.MigrationPath, &cfg.Postgres.MigrationPath)
+	}
+	if fileCfg.Scylla != nil {
+		resolve(fileCfg.Scylla.MigrationPath, &cfg.Scylla.MigrationPath)
+	}
+	if fileCfg.MySQL != nil {
+		resolve(fileCfg.MySQL.MigrationPath, &cfg.MySQL.MigrationPath)
+	}
 	return nil
 }
 
-// loadConfigFile loads the configuration file into currentConfig
-func loadConfigFile() error {
-	data, err := os.ReadFile(configFile)
+// readConfigFile decodes the config file at path into cfg. Only the fields present in
+// the file are overwritten, which lets successive reads merge several files.
+// A missing file is not an error.
+func readConfigFile(path string, cfg *JBMDBConfig) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			currentConfig = &JBMDBConfig{}
 			return nil
 		}
 		return err
 	}
 
-	currentConfig = &JBMDBConfig{}
-	if err := json.Unmarshal(data, currentConfig); err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s (run 'jbmdb config migrate-format' if it was written by an older jbmdb): %w", path, err)
+	}
+
+	return nil
+}
+
+// writeConfigFile writes cfg to the target config file, creating its directory if needed
+func writeConfigFile(cfg *JBMDBConfig) error {
+	cfg.Version = CurrentFormatVersion
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	path := ConfigPath()
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create config directory: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
@@ -244,6 +566,7 @@ func createDefaultConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig]
 				MigrationPath: "migrations/cql",
 				CQLFolder:     "cql",
 				Port:          9042,
+				ProtoVersion:  4,
 				Hosts:         []string{"localhost"},
 				Keyspace:      "system",
 				User:          "",
@@ -252,6 +575,11 @@ func createDefaultConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig]
 				SuperPass:     "",
 				Datacenter:    "",
 				Consistency:   "",
+				PoolConfig: PoolConfig{
+					NumConns:          2,
+					ReconnectInterval: "1s",
+					MaxPreparedStmts:  1000,
+				},
 			}
 		}
 	case "mysql":
@@ -266,6 +594,8 @@ func createDefaultConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig]
 				DBName:        "mysql",
 				SuperUser:     "root",
 				SuperPass:     "",
+				Charset:       "utf8mb4",
+				Collation:     "utf8mb4_unicode_ci",
 			}
 		}
 	}
@@ -275,13 +605,8 @@ func createDefaultConfig[T Config | PostgresConfig | ScyllaConfig | MySQLConfig]
 
 // SaveFullConfig saves a complete configuration
 func SaveFullConfig(config *JBMDBConfig) error {
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	if err := writeConfigFile(config); err != nil {
+		return err
 	}
 
 	currentConfig = config
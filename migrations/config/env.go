@@ -0,0 +1,226 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// envPrefix starts every environment variable holding a config value. Variables are
+// named JBMDB_<TYPE>_<FIELD>, where FIELD is the upper-cased JSON key, e.g.
+// JBMDB_POSTGRES_PASSWORD or JBMDB_CQL_SUPER_USER.
+const envPrefix = "JBMDB_"
+
+// envSection is a config section together with the TYPE part of its variable names.
+type envSection struct {
+	name  string
+	value reflect.Value // addressable struct value of the section
+}
+
+// envSections returns the sections of cfg that are set. With create, missing sections
+// are first filled with the defaults so values can be stored in them.
+func envSections(cfg *JBMDBConfig, create bool) []envSection {
+	if create {
+		if cfg.Postgres == nil {
+			cfg.Postgres, _ = createDefaultConfig[PostgresConfig]("postgres")
+		}
+		if cfg.MySQL == nil {
+			cfg.MySQL, _ = createDefaultConfig[MySQLConfig]("mysql")
+		}
+		if cfg.Scylla == nil {
+			cfg.Scylla, _ = createDefaultConfig[ScyllaConfig]("cql")
+		}
+	}
+
+	var sections []envSection
+	if cfg.Postgres != nil {
+		sections = append(sections, envSection{"POSTGRES", reflect.ValueOf(cfg.Postgres).Elem()})
+	}
+	if cfg.MySQL != nil {
+		sections = append(sections, envSection{"MYSQL", reflect.ValueOf(cfg.MySQL).Elem()})
+	}
+	if cfg.Scylla != nil {
+		sections = append(sections, envSection{"CQL", reflect.ValueOf(cfg.Scylla).Elem()})
+	}
+	return sections
+}
+
+// envVarName returns the environment variable name for a config field.
+func envVarName(section string, field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return envPrefix + section + "_" + strings.ToUpper(key)
+}
+
+// eachEnvField calls fn with the variable name of every config field of the struct
+// value. Nested structs such as pool_config are walked with their key added to the
+// name, e.g. JBMDB_CQL_POOL_CONFIG_NUM_CONNS.
+func eachEnvField(section string, value reflect.Value, fn func(name string, field reflect.StructField, v reflect.Value) error) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "" {
+			continue
+		}
+
+		name := envVarName(section, field)
+		if field.Type.Kind() == reflect.Struct {
+			if err := eachEnvField(strings.TrimPrefix(name, envPrefix), value.Field(i), fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(name, field, value.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// secretFields are masked by ExportAsEnv unless secrets are requested.
+var secretFields = map[string]bool{"Password": true, "SuperPass": true}
+
+// shellSafePattern matches values that need no quoting in a shell.
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9_./:@,+-]+$`)
+
+// formatEnvValue formats a config field the way setFieldFromEnv parses it.
+func formatEnvValue(field reflect.Value) string {
+	switch field.Kind() {
+	case reflect.Slice:
+		values := make([]string, field.Len())
+		for i := range values {
+			values[i] = fmt.Sprint(field.Index(i).Interface())
+		}
+		return strings.Join(values, ",")
+	default:
+		return fmt.Sprint(field.Interface())
+	}
+}
+
+// shellQuote quotes a value for use in a POSIX shell when it is not already safe.
+func shellQuote(value string) string {
+	if shellSafePattern.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// ExportAsEnv writes every field of the configured sections of cfg to w as
+// "export JBMDB_<TYPE>_<FIELD>=value" lines that can be sourced by a shell.
+// Passwords are masked unless showSecrets is set.
+func ExportAsEnv(cfg *JBMDBConfig, w io.Writer, showSecrets bool) error {
+	for _, section := range envSections(cfg, false) {
+		err := eachEnvField(section.name, section.value, func(name string, field reflect.StructField, v reflect.Value) error {
+			value := formatEnvValue(v)
+			if secretFields[field.Name] && !showSecrets && value != "" {
+				value = "********"
+			}
+
+			_, err := fmt.Fprintf(w, "export %s=%s\n", name, shellQuote(value))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFieldFromEnv parses raw into the config field. Lists are comma-separated.
+func setFieldFromEnv(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(value)
+	case reflect.Int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(value))
+	case reflect.Slice:
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// applyEnv overlays every JBMDB_<TYPE>_<FIELD> variable that is set onto cfg and
+// returns the number of values applied. Sections that receive no values are left nil.
+func applyEnv(cfg *JBMDBConfig) (int, error) {
+	before := *cfg
+	applied := make(map[string]bool)
+
+	count := 0
+	for _, section := range envSections(cfg, true) {
+		err := eachEnvField(section.name, section.value, func(name string, _ reflect.StructField, v reflect.Value) error {
+			raw, ok := os.LookupEnv(name)
+			if !ok {
+				return nil
+			}
+			if err := setFieldFromEnv(v, raw); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", name, err)
+			}
+			applied[section.name] = true
+			count++
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+	}
+
+	// Drop the sections allocated only to receive values that never came
+	if !applied["POSTGRES"] {
+		cfg.Postgres = before.Postgres
+	}
+	if !applied["MYSQL"] {
+		cfg.MySQL = before.MySQL
+	}
+	if !applied["CQL"] {
+		cfg.Scylla = before.Scylla
+	}
+
+	return count, nil
+}
+
+// FromEnv reports whether the field with the JSON key of the configType section
+// ("postgres", "mysql" or "cql") is set by its JBMDB_<TYPE>_<FIELD> variable, so
+// LoadConfig returns the variable's value instead of the file's. Nested keys are
+// joined with an underscore, e.g. pool_config_num_conns.
+func FromEnv(configType, key string) bool {
+	_, ok := os.LookupEnv(envPrefix + strings.ToUpper(configType) + "_" + strings.ToUpper(key))
+	return ok
+}
+
+// ImportFromEnv reads the JBMDB_<TYPE>_<FIELD> environment variables into the config
+// file selected by ConfigPath, keeping any values already in the file that have no
+// variable set. It returns the number of values imported; nothing is written if no
+// variables are set.
+func ImportFromEnv() (int, error) {
+	cfg := &JBMDBConfig{}
+	if err := readConfigFile(ConfigPath(), cfg); err != nil {
+		return 0, fmt.Errorf("failed to load existing config: %w", err)
+	}
+
+	count, err := applyEnv(cfg)
+	if err != nil || count == 0 {
+		return count, err
+	}
+
+	return count, SaveFullConfig(cfg)
+}
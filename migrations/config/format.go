@@ -0,0 +1,111 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CurrentFormatVersion is the config file format written by this version of jbmdb.
+// Increment it and append a step to formatMigrations on each structural change.
+const CurrentFormatVersion = 1
+
+// formatMigration upgrades a config from one format version to the next
+type formatMigration func(*JBMDBConfig) error
+
+// formatMigrations holds the upgrade steps in order: formatMigrations[i] upgrades
+// format version i to i+1.
+var formatMigrations = []formatMigration{
+	migrateScyllaPort,
+}
+
+// legacyFields holds values from older formats that no longer decode into the
+// current structs. They are filled by readLegacyConfig for the format migrations.
+type legacyFields struct {
+	scyllaPort string
+}
+
+// migrateScyllaPort upgrades format 0 to 1: the scylla port used to be stored as a
+// string and is now an int.
+func migrateScyllaPort(cfg *JBMDBConfig) error {
+	if cfg.Scylla == nil || cfg.legacy.scyllaPort == "" {
+		return nil
+	}
+
+	port, err := strconv.Atoi(strings.TrimSpace(cfg.legacy.scyllaPort))
+	if err != nil {
+		return fmt.Errorf("invalid scylla port %q: %w", cfg.legacy.scyllaPort, err)
+	}
+	cfg.Scylla.Port = port
+	return nil
+}
+
+// MigrateFormat upgrades the config file to CurrentFormatVersion and writes it back.
+// It returns the format versions before and after the upgrade.
+func MigrateFormat() (from, to int, err error) {
+	path := ConfigPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	cfg, err := readLegacyConfig(data)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	from = cfg.Version
+	if from > CurrentFormatVersion {
+		return from, from, fmt.Errorf("config format version %d is newer than this jbmdb supports (%d)", from, CurrentFormatVersion)
+	}
+
+	for version := from; version < CurrentFormatVersion; version++ {
+		if err := formatMigrations[version](cfg); err != nil {
+			return from, version, fmt.Errorf("format migration %d -> %d failed: %w", version, version+1, err)
+		}
+	}
+
+	if err := SaveFullConfig(cfg); err != nil {
+		return from, from, err
+	}
+	return from, CurrentFormatVersion, nil
+}
+
+// readLegacyConfig decodes a config file of any format version. Values whose type
+// changed between formats are moved into cfg.legacy before decoding.
+func readLegacyConfig(data []byte) (*JBMDBConfig, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var legacy legacyFields
+	if section, ok := raw["scylla"]; ok {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(section, &fields); err == nil {
+			var port string
+			if err := json.Unmarshal(fields["port"], &port); err == nil {
+				legacy.scyllaPort = port
+				delete(fields, "port")
+				if raw["scylla"], err = json.Marshal(fields); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &JBMDBConfig{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	cfg.legacy = legacy
+
+	return cfg, nil
+}
@@ -0,0 +1,64 @@
+package cql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// AnalyzeSlowest prints the n applied migrations that took the longest to apply.
+// CQL can't order by a regular column, so the records are sorted client side.
+func AnalyzeSlowest(session *gocql.Session, n int) error {
+	hasDuration, err := hasDurationColumn(session)
+	if err != nil {
+		return fmt.Errorf("failed to check migrations table: %w", err)
+	}
+	if !hasDuration {
+		fmt.Printf("%sNo migration durations recorded yet, they are recorded from the next migrate on%s\n",
+			ColorYellow, ColorReset)
+		return nil
+	}
+
+	type timedMigration struct {
+		version    int64
+		name       string
+		durationMs int64
+	}
+
+	var migrations []timedMigration
+	iter := session.Query(`SELECT version, name, duration_ms FROM ` + migrationsTable).Iter()
+	var m timedMigration
+	var durationMs *int64
+	for iter.Scan(&m.version, &m.name, &durationMs) {
+		if durationMs != nil {
+			m.durationMs = *durationMs
+			migrations = append(migrations, m)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return fmt.Errorf("failed to query migrations table: %w", err)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		if migrations[i].durationMs != migrations[j].durationMs {
+			return migrations[i].durationMs > migrations[j].durationMs
+		}
+		return migrations[i].version < migrations[j].version
+	})
+	if len(migrations) > n {
+		migrations = migrations[:n]
+	}
+
+	fmt.Printf("\n%sSlowest Migrations%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-20s %-45s %s\n", "Version", "Name", "Duration")
+	fmt.Println(strings.Repeat("-", 80))
+	for _, m := range migrations {
+		fmt.Printf("%-20d %-45s %.3fs\n", m.version, m.name, float64(m.durationMs)/1000)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package cql
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/gocql/gocql"
+	"github.com/jbarasa/jbmdb/migrations/internal/checksum"
+)
+
+// Whether Migrate skips comparing applied migration files with their checksums, see
+// SetSkipChecksum
+var skipChecksum bool
+
+// SetSkipChecksum makes Migrate go ahead even when applied migration files changed
+// since they were applied, for emergencies.
+func SetSkipChecksum(skip bool) {
+	skipChecksum = skip
+}
+
+// fileChecksum returns the hex encoded SHA-256 of the file of a migration
+func fileChecksum(version int64, name string) (string, error) {
+	return checksum.File(filepath.Join(migrationPath, "cql", fmt.Sprintf("%d_%s.cql", version, name)))
+}
+
+// verifyChecksums compares the checksum recorded for each applied migration with the
+// SHA-256 of its file and fails with ErrChecksumMismatch when any file changed. Records
+// from before checksums were recorded and migrations whose file is gone aren't checked.
+func verifyChecksums(session *gocql.Session) error {
+	if skipChecksum {
+		fmt.Printf("%s[WARNING]%s Checksums of applied migrations are not verified\n", ColorYellow, ColorReset)
+		return nil
+	}
+
+	var records []checksum.Record
+	iter := session.Query(`SELECT version, name, checksum FROM ` + migrationsTable).Iter()
+	var r checksum.Record
+	for iter.Scan(&r.Version, &r.Name, &r.Checksum) {
+		if r.Checksum != "" {
+			records = append(records, r)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	// The partition key orders the rows by token, not by version
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Version < records[j].Version
+	})
+
+	return checksum.Verify(records, fileChecksum, ErrChecksumMismatch)
+}
@@ -0,0 +1,95 @@
+package cql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/jbarasa/jbmdb/migrations/config"
+)
+
+// defaultProtoVersion is used when ProtoVersion is not configured
+const defaultProtoVersion = 4
+
+// NewCluster returns the cluster config for the keyspace and user of the config, with
+// its port, consistency, datacenter, protocol version, timeouts and pool settings
+// applied. The CLI and the migrator package both connect through it.
+func NewCluster(cqlConfig *config.ScyllaConfig) (*gocql.ClusterConfig, error) {
+	cluster := gocql.NewCluster(cqlConfig.Hosts...)
+	cluster.Keyspace = cqlConfig.Keyspace
+	if err := ConfigureCluster(cluster, cqlConfig); err != nil {
+		return nil, err
+	}
+	if cqlConfig.Port != 0 {
+		cluster.Port = cqlConfig.Port
+	}
+	if cqlConfig.User != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: cqlConfig.User,
+			Password: cqlConfig.Password,
+		}
+	}
+
+	cluster.Consistency = gocql.Quorum
+	if cqlConfig.Consistency != "" {
+		level, err := gocql.ParseConsistencyWrapper(cqlConfig.Consistency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid consistency level: %v", err)
+		}
+		cluster.Consistency = level
+	}
+
+	// Prefer the local datacenter when one is configured
+	if cqlConfig.Datacenter != "" {
+		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(
+			gocql.DCAwareRoundRobinPolicy(cqlConfig.Datacenter))
+	}
+	return cluster, nil
+}
+
+// ConfigureCluster applies the protocol version, timeouts and pool settings of the
+// config to the cluster. Settings that are not configured keep the gocql defaults.
+func ConfigureCluster(cluster *gocql.ClusterConfig, cqlConfig *config.ScyllaConfig) error {
+	cluster.ProtoVersion = defaultProtoVersion
+	if cqlConfig.ProtoVersion != 0 {
+		if cqlConfig.ProtoVersion < 1 || cqlConfig.ProtoVersion > 5 {
+			return fmt.Errorf("invalid CQL protocol version %d: must be between 1 and 5", cqlConfig.ProtoVersion)
+		}
+		cluster.ProtoVersion = cqlConfig.ProtoVersion
+	}
+
+	if cqlConfig.Timeout > 0 {
+		cluster.Timeout = time.Duration(cqlConfig.Timeout) * time.Second
+	}
+	if cqlConfig.ConnectTimeoutSeconds > 0 {
+		cluster.ConnectTimeout = time.Duration(cqlConfig.ConnectTimeoutSeconds) * time.Second
+	}
+	if cqlConfig.WriteCoalesceWaitMicros > 0 {
+		cluster.WriteCoalesceWaitTime = time.Duration(cqlConfig.WriteCoalesceWaitMicros) * time.Microsecond
+	}
+	return configurePool(cluster, cqlConfig.PoolConfig)
+}
+
+// configurePool applies the connection pool settings of the config to the cluster
+func configurePool(cluster *gocql.ClusterConfig, pool config.PoolConfig) error {
+	if pool.NumConns < 0 || pool.MaxPreparedStmts < 0 {
+		return fmt.Errorf("invalid pool config: num_conns and max_prepared_stmts must not be negative")
+	}
+	if pool.NumConns > 0 {
+		cluster.NumConns = pool.NumConns
+	}
+	if pool.MaxPreparedStmts > 0 {
+		cluster.MaxPreparedStmts = pool.MaxPreparedStmts
+	}
+	if pool.ReconnectInterval != "" {
+		interval, err := time.ParseDuration(pool.ReconnectInterval)
+		if err != nil {
+			return fmt.Errorf("invalid reconnect_interval %q: %w", pool.ReconnectInterval, err)
+		}
+		cluster.ReconnectInterval = interval
+	}
+	if pool.HostFilter != "" {
+		cluster.HostFilter = gocql.DataCentreHostFilter(pool.HostFilter)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package cql
+
+import (
+	"fmt"
+
+	"github.com/gocql/gocql"
+	"github.com/jbarasa/jbmdb/migrations/internal/report"
+)
+
+// dryRunMigrate prints the Up CQL of the migrations that aren't applied yet instead of
+// applying them. Applied migrations are reported as skipped, as applyMigration does.
+// Nothing is written, so a missing migrations table means no migration is applied, as
+// does fresh, for which the migrations table would be dropped first.
+func dryRunMigrate(session *gocql.Session, migrations []Migration, fresh bool) (MigrateResult, error) {
+	var result MigrateResult
+
+	exists := false
+	if !fresh {
+		var count int
+		if err := session.Query(`SELECT COUNT(*) FROM system_schema.tables WHERE keyspace_name = ? AND table_name = ?`,
+			currentKeyspace(session), migrationsTable).Scan(&count); err != nil {
+			return result, fmt.Errorf("failed to check migrations table: %w", err)
+		}
+		exists = count > 0
+	}
+
+	var applied func(Migration) (bool, error)
+	if exists {
+		applied = func(migration Migration) (bool, error) {
+			return isMigrationApplied(session, migration.Version)
+		}
+	}
+	var err error
+	result.Result, err = report.DryRunMigrate(migrations, applied, func(migration Migration) (string, string) {
+		return migration.UpCQL, ""
+	})
+	return result, err
+}
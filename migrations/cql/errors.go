@@ -0,0 +1,25 @@
+package cql
+
+import "errors"
+
+// Sentinel errors returned by the cql package. They are wrapped with context, so
+// test for them with errors.Is, e.g. errors.Is(err, cql.ErrMigrationNotFound).
+var (
+	// ErrMigrationNotFound is returned when a migration version has no file or record
+	ErrMigrationNotFound = errors.New("migration not found")
+
+	// ErrChecksumMismatch is returned when a migration file changed after it was applied
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+	// ErrDuplicateVersion is returned when two migration files share a version
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+
+	// ErrConnectionFailed is returned when the database can't be reached
+	ErrConnectionFailed = errors.New("unable to connect to the cluster")
+
+	// ErrNodetoolNotFound is returned when a snapshot needs nodetool and it isn't in PATH
+	ErrNodetoolNotFound = errors.New("nodetool not found in PATH")
+
+	// ErrInvalidTablePrefix is returned when a table prefix isn't a plain identifier
+	ErrInvalidTablePrefix = errors.New("invalid table prefix")
+)
@@ -1,16 +1,22 @@
 package cql
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
 	"github.com/gocql/gocql"
 	"github.com/jbarasa/jbmdb/migrations/config"
+	"github.com/jbarasa/jbmdb/migrations/internal/migfile"
+	"github.com/jbarasa/jbmdb/migrations/internal/naming"
+	"github.com/jbarasa/jbmdb/migrations/internal/report"
 )
 
 // Color constants for terminal output
@@ -35,7 +41,10 @@ func CreateKeyspace(cqlConfig *config.ScyllaConfig, replicationStrategy string,
 		Username: cqlConfig.SuperUser,
 		Password: cqlConfig.SuperPass,
 	}
-	
+	if err := ConfigureCluster(cluster, cqlConfig); err != nil {
+		return err
+	}
+
 	// Set consistency level if specified
 	if cqlConfig.Consistency != "" {
 		level, err := gocql.ParseConsistencyWrapper(cqlConfig.Consistency)
@@ -49,7 +58,7 @@ func CreateKeyspace(cqlConfig *config.ScyllaConfig, replicationStrategy string,
 
 	session, err := cluster.CreateSession()
 	if err != nil {
-		return fmt.Errorf("error connecting to cluster: %v", err)
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
 	}
 	defer session.Close()
 
@@ -68,14 +77,14 @@ func CreateKeyspace(cqlConfig *config.ScyllaConfig, replicationStrategy string,
 		case "SimpleStrategy":
 			query = fmt.Sprintf(
 				"CREATE KEYSPACE %s WITH REPLICATION = {'class': 'SimpleStrategy', 'replication_factor': %d}",
-				cqlConfig.Keyspace, replicationFactor)
+				quoteIdentifier(cqlConfig.Keyspace), replicationFactor)
 		case "NetworkTopologyStrategy":
 			if cqlConfig.Datacenter == "" {
 				return fmt.Errorf("datacenter must be specified for NetworkTopologyStrategy")
 			}
 			query = fmt.Sprintf(
 				"CREATE KEYSPACE %s WITH REPLICATION = {'class': 'NetworkTopologyStrategy', '%s': %d}",
-				cqlConfig.Keyspace, cqlConfig.Datacenter, replicationFactor)
+				quoteIdentifier(cqlConfig.Keyspace), cqlConfig.Datacenter, replicationFactor)
 		default:
 			return fmt.Errorf("unsupported replication strategy: %s", replicationStrategy)
 		}
@@ -94,6 +103,99 @@ func CreateKeyspace(cqlConfig *config.ScyllaConfig, replicationStrategy string,
 	return nil
 }
 
+// DropKeyspace drops the configured keyspace and everything in it
+func DropKeyspace(cqlConfig *config.ScyllaConfig) error {
+	// Connect to Cassandra/ScyllaDB cluster
+	cluster := gocql.NewCluster(cqlConfig.Hosts...)
+	cluster.Port = cqlConfig.Port
+	cluster.Authenticator = gocql.PasswordAuthenticator{
+		Username: cqlConfig.SuperUser,
+		Password: cqlConfig.SuperPass,
+	}
+	if err := ConfigureCluster(cluster, cqlConfig); err != nil {
+		return err
+	}
+
+	// Set consistency level if specified
+	if cqlConfig.Consistency != "" {
+		level, err := gocql.ParseConsistencyWrapper(cqlConfig.Consistency)
+		if err != nil {
+			return fmt.Errorf("invalid consistency level: %v", err)
+		}
+		cluster.Consistency = level
+	} else {
+		cluster.Consistency = gocql.Quorum
+	}
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer session.Close()
+
+	if err := session.Query("DROP KEYSPACE IF EXISTS " + quoteIdentifier(cqlConfig.Keyspace)).Exec(); err != nil {
+		return fmt.Errorf("error dropping keyspace: %v", err)
+	}
+
+	fmt.Printf("%sKeyspace '%s' dropped successfully%s\n",
+		ColorGreen, cqlConfig.Keyspace, ColorReset)
+
+	return nil
+}
+
+// identifierPattern matches an unquoted keyspace or table name
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// quoteIdentifier quotes a keyspace or role name with double quotes for use in a
+// statement, keeping its case
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// DropTable drops a single table outside of the migration flow. The table may be
+// qualified as keyspace.table. CQL has no dependent objects to cascade to, so
+// cascade only exists to match the other drivers and has no effect.
+func DropTable(session *gocql.Session, table string, cascade bool) error {
+	for _, part := range strings.Split(table, ".") {
+		if !identifierPattern.MatchString(part) {
+			return fmt.Errorf("invalid table name: %s", table)
+		}
+	}
+
+	if err := session.Query(fmt.Sprintf("DROP TABLE IF EXISTS %s", table)).Exec(); err != nil {
+		return fmt.Errorf("error dropping table %s: %w", table, err)
+	}
+
+	fmt.Printf("%sTable '%s' dropped successfully%s\n", ColorGreen, table, ColorReset)
+	return nil
+}
+
+// UntrackTable removes the records of the migrations that create the given table,
+// so the next migrate recreates it. It returns the number of records removed.
+func UntrackTable(session *gocql.Session, table string) (int, error) {
+	iter := session.Query(`SELECT version, name FROM ` + migrationsTable).Iter()
+
+	var versions []int64
+	var version int64
+	var name string
+	for iter.Scan(&version, &name) {
+		if strings.EqualFold(extractTableName(name), table) {
+			versions = append(versions, version)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	for _, v := range versions {
+		if err := session.Query(`DELETE FROM `+migrationsTable+` WHERE version = ?`, v).Exec(); err != nil {
+			return 0, fmt.Errorf("failed to remove migration record %d: %w", v, err)
+		}
+	}
+
+	return len(versions), nil
+}
+
 // CreateUser creates a new user if it doesn't exist and grants privileges
 func CreateUser(cqlConfig *config.ScyllaConfig, privileges string) error {
 	// Connect to Cassandra/ScyllaDB cluster
@@ -103,7 +205,10 @@ func CreateUser(cqlConfig *config.ScyllaConfig, privileges string) error {
 		Username: cqlConfig.SuperUser,
 		Password: cqlConfig.SuperPass,
 	}
-	
+	if err := ConfigureCluster(cluster, cqlConfig); err != nil {
+		return err
+	}
+
 	// Set consistency level if specified
 	if cqlConfig.Consistency != "" {
 		level, err := gocql.ParseConsistencyWrapper(cqlConfig.Consistency)
@@ -117,10 +222,14 @@ func CreateUser(cqlConfig *config.ScyllaConfig, privileges string) error {
 
 	session, err := cluster.CreateSession()
 	if err != nil {
-		return fmt.Errorf("error connecting to cluster: %v", err)
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
 	}
 	defer session.Close()
 
+	// Quoted, so the names keep their case and match the role looked up below
+	role := quoteIdentifier(cqlConfig.User)
+	keyspace := quoteIdentifier(cqlConfig.Keyspace)
+
 	// Check if user exists
 	var count int
 	if err := session.Query(
@@ -130,10 +239,11 @@ func CreateUser(cqlConfig *config.ScyllaConfig, privileges string) error {
 	}
 
 	if count == 0 {
-		// Create user
+		// CREATE USER is deprecated in favour of roles that can log in, which
+		// Cassandra 2.2+ and every ScyllaDB release with authentication support
 		if err := session.Query(
-			fmt.Sprintf("CREATE USER %s WITH PASSWORD '%s' NOSUPERUSER",
-				cqlConfig.User, cqlConfig.Password)).Exec(); err != nil {
+			fmt.Sprintf("CREATE ROLE %s WITH PASSWORD = '%s' AND LOGIN = true AND SUPERUSER = false",
+				role, strings.ReplaceAll(cqlConfig.Password, "'", "''"))).Exec(); err != nil {
 			return fmt.Errorf("error creating user: %v", err)
 		}
 
@@ -144,28 +254,37 @@ func CreateUser(cqlConfig *config.ScyllaConfig, privileges string) error {
 			ColorBlue, cqlConfig.User, ColorReset)
 	}
 
-	// Grant privileges based on the specified level
-	var grantCmd string
+	// Grant privileges based on the specified level. CQL grants one permission
+	// per statement, so read and write take several.
+	var grantCmds []string
 	switch privileges {
 	case "read":
-		grantCmd = fmt.Sprintf("GRANT SELECT ON KEYSPACE %s TO %s",
-			cqlConfig.Keyspace, cqlConfig.User)
+		grantCmds = []string{
+			fmt.Sprintf("GRANT SELECT ON KEYSPACE %s TO %s", keyspace, role),
+		}
 	case "write":
-		grantCmd = fmt.Sprintf(
-			"GRANT SELECT, MODIFY ON KEYSPACE %s TO %s",
-			cqlConfig.Keyspace, cqlConfig.User)
+		grantCmds = []string{
+			fmt.Sprintf("GRANT SELECT ON KEYSPACE %s TO %s", keyspace, role),
+			fmt.Sprintf("GRANT MODIFY ON KEYSPACE %s TO %s", keyspace, role),
+		}
 	case "all":
-		grantCmd = fmt.Sprintf("GRANT ALL PERMISSIONS ON KEYSPACE %s TO %s",
-			cqlConfig.Keyspace, cqlConfig.User)
+		grantCmds = []string{
+			fmt.Sprintf("GRANT ALL PERMISSIONS ON KEYSPACE %s TO %s", keyspace, role),
+		}
 	case "admin":
-		grantCmd = fmt.Sprintf("GRANT ALL PERMISSIONS ON ALL KEYSPACES TO %s",
-			cqlConfig.User)
+		// AUTHORIZE lets the user grant and revoke permissions on the keyspace to others
+		grantCmds = []string{
+			fmt.Sprintf("GRANT ALL PERMISSIONS ON ALL KEYSPACES TO %s", role),
+			fmt.Sprintf("GRANT AUTHORIZE ON KEYSPACE %s TO %s", keyspace, role),
+		}
 	default:
 		return fmt.Errorf("invalid privilege level: %s", privileges)
 	}
 
-	if err := session.Query(grantCmd).Exec(); err != nil {
-		return fmt.Errorf("error granting privileges: %v", err)
+	for _, grantCmd := range grantCmds {
+		if err := session.Query(grantCmd).Exec(); err != nil {
+			return fmt.Errorf("error granting privileges: %v", err)
+		}
 	}
 
 	fmt.Printf("%sPrivileges '%s' granted to user '%s' on keyspace '%s'%s\n",
@@ -181,6 +300,19 @@ type Migration struct {
 	Name    string // Name of the migration
 	UpCQL   string // CQL script for applying the migration
 	DownCQL string // CQL script for rolling back the migration
+
+	Description string // Description from the header of the migration file
+	Author      string // Author from the header of the migration file
+}
+
+// String returns the <version>_<name> of the migration
+func (m Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// MigrateResult summarizes a migration run.
+type MigrateResult struct {
+	report.Result[Migration]
 }
 
 // Path to the migration files.
@@ -191,6 +323,77 @@ func SetMigrationPath(path string) {
 	migrationPath = path
 }
 
+// Prefix of the tables this application owns, including the trailing underscore, and
+// the table applied migrations are recorded in. See SetTablePrefix.
+var (
+	tablePrefix     string
+	migrationsTable = "migrations"
+)
+
+// SetTablePrefix scopes jbmdb to the tables starting with prefix, for applications
+// sharing a database: new tables are created as <prefix>_<name>, fresh migrations only
+// drop prefixed tables and migrations are recorded in <prefix>_migrations. The prefix
+// is inlined into queries, so it fails with ErrInvalidTablePrefix unless the prefix has
+// only letters, digits and underscores.
+func SetTablePrefix(prefix string) error {
+	if !naming.ValidTablePrefix(prefix) {
+		return fmt.Errorf("%w %q: only letters, digits and underscores are allowed", ErrInvalidTablePrefix, prefix)
+	}
+
+	tablePrefix = ""
+	if prefix = strings.TrimSuffix(prefix, "_"); prefix != "" {
+		tablePrefix = prefix + "_"
+	}
+	migrationsTable = tablePrefix + "migrations"
+	return nil
+}
+
+// prefixedTable returns the table name with the table prefix, unless it already has it
+func prefixedTable(name string) string {
+	if strings.HasPrefix(name, tablePrefix) {
+		return name
+	}
+	return tablePrefix + name
+}
+
+// Location used to display timestamps in ListMigrations.
+var displayLocation = time.Local
+
+// SetDisplayLocation sets the timezone used to display applied_at timestamps
+func SetDisplayLocation(loc *time.Location) {
+	displayLocation = loc
+}
+
+// IsAlterMigrationName reports whether name follows the alter_<table>_<description>
+// naming convention.
+func IsAlterMigrationName(name string) bool {
+	return naming.IsAlter(name)
+}
+
+// migrationTables returns the tables the migrations create, named as in the migration
+// names, which alter migration names are matched against
+func migrationTables() (map[string]bool, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	tables := make(map[string]bool, len(migrations))
+	for _, migration := range migrations {
+		tables[strings.ToLower(extractTableName(migration.Name))] = true
+	}
+	return tables, nil
+}
+
+// parseAlterMigrationName parses an alter_<table>_<description> migration name against
+// the tables of the migrations, see naming.ParseAlter
+func parseAlterMigrationName(name string) (naming.Alter, error) {
+	tables, err := migrationTables()
+	if err != nil {
+		return naming.Alter{}, err
+	}
+	return naming.ParseAlter(name, tables)
+}
+
 // extractTableName extracts the table name from the migration name.
 // This function removes common prefixes and suffixes from the migration name,
 // and converts it to snake_case if necessary.
@@ -241,8 +444,34 @@ func checkDuplicateTableName(newTableName string) error {
 	return nil
 }
 
+// TableOptions customizes the CREATE TABLE statement generated by CreateMigration.
+// Keys are "name:type" pairs and the clustering order "name:asc|desc" pairs.
+// References are tables to add a <table>_id column for; CQL has no foreign keys, so
+// the column is only marked as a logical reference.
+type TableOptions struct {
+	PartitionKey    []string
+	ClusteringKey   []string
+	ClusteringOrder []string
+	References      []string
+
+	// Compaction strategy (lcs, stcs or twcs), empty for the cluster default, and the
+	// time window of twcs, e.g. 7 DAYS
+	CompactionStrategy string
+	TWCSWindowUnit     string
+	TWCSWindowSize     int
+}
+
 // CreateMigration creates new migration file with the given name and current timestamp.
-func CreateMigration(name string) error {
+func CreateMigration(name string, opts TableOptions) error {
+	// Alter migrations change an existing table instead of creating one
+	if IsAlterMigrationName(name) {
+		alter, err := parseAlterMigrationName(name)
+		if err != nil {
+			return err
+		}
+		return createAlterMigration(name, alter.Action, alter.Column, prefixedTable(alter.Table))
+	}
+
 	// Extract table name from migration name
 	tableName := extractTableName(name)
 
@@ -251,25 +480,77 @@ func CreateMigration(name string) error {
 		return err
 	}
 
-	timestamp := time.Now().Format("20060102150405")
+	table := prefixedTable(strings.ToLower(tableName))
+	up := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+    id uuid PRIMARY KEY,
+%s    created_at timestamp,
+    updated_at timestamp
+);`, table, referenceColumns(opts.References))
+	if len(opts.PartitionKey) > 0 || len(opts.ClusteringKey) > 0 || len(opts.ClusteringOrder) > 0 {
+		var err error
+		if up, err = createTableWithKeys(table, opts); err != nil {
+			return err
+		}
+	}
+
+	compaction, err := compactionOption(opts)
+	if err != nil {
+		return err
+	}
+	if compaction != "" {
+		// Table options are joined with AND after an existing WITH clause
+		up = strings.TrimSuffix(up, ";")
+		if strings.Contains(up, " WITH CLUSTERING ORDER BY ") {
+			up += " AND " + compaction + ";"
+		} else {
+			up += " WITH " + compaction + ";"
+		}
+	}
+	down := fmt.Sprintf("DROP TABLE IF EXISTS %s;", table)
+
+	return writeMigrationFile(name, up, down)
+}
+
+// createAlterMigration writes an ALTER TABLE migration for an alter_<table>_<description>
+// name. Adding or dropping a column defaults to a text column, other descriptions get a
+// template to fill in.
+func createAlterMigration(name, action, column, table string) error {
+	addColumn := fmt.Sprintf("-- TODO: adjust the column type\nALTER TABLE %s ADD %s text;", table, column)
+	dropColumn := fmt.Sprintf("ALTER TABLE %s DROP %s;", table, column)
+
+	switch action {
+	case "add":
+		return writeMigrationFile(name, addColumn, dropColumn)
+	case "drop":
+		return writeMigrationFile(name, dropColumn, addColumn)
+	}
+
+	up := fmt.Sprintf("-- TODO: alter the table, e.g.\n-- ALTER TABLE %s WITH default_time_to_live = 86400;", table)
+	down := fmt.Sprintf("-- TODO: undo the changes to %s", table)
+	return writeMigrationFile(name, up, down)
+}
+
+// writeMigrationFile writes a new timestamped migration file with the given up and down
+// CQL wrapped in the standard sections.
+func writeMigrationFile(name, up, down string) error {
+	timestamp, err := migfile.FreeTimestamp(filepath.Join(migrationPath, "cql"), time.Now(), ErrDuplicateVersion)
+	if err != nil {
+		return err
+	}
 	filename := fmt.Sprintf("%s_%s.cql", timestamp, name)
 
-	content := fmt.Sprintf(`-- Migration: %s
+	content := migfile.CommentHeader(migrationComment, time.Now()) + fmt.Sprintf(`-- Migration: %s
 
 -- Up Migration
 ----------------------- Write your up migration here ----------------------------
 
-CREATE TABLE IF NOT EXISTS %s (
-    id uuid PRIMARY KEY,
-    created_at timestamp,
-    updated_at timestamp
-);
+%s
 
 
 -- Down Migration
 ----------------------- Write your down migration here ----------------------------
 
-DROP TABLE IF EXISTS %s;`, name, strings.ToLower(tableName), strings.ToLower(tableName))
+%s`, name, up, down)
 
 	// Create the migration file in the CQL folder within the migration path
 	cqlPath := filepath.Join(migrationPath, "cql")
@@ -310,7 +591,10 @@ func loadMigrations() ([]Migration, error) {
 			}
 
 			// Parse version and name from filename
-			version := parseInt(parts[0])
+			version, err := parseInt(parts[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid migration file %s: %w", file.Name(), err)
+			}
 			name := strings.TrimSuffix(strings.Join(parts[1:], "_"), filepath.Ext(file.Name()))
 
 			// Read the content of the migration file
@@ -325,16 +609,23 @@ func loadMigrations() ([]Migration, error) {
 				return nil, fmt.Errorf("invalid migration format in file %s", file.Name())
 			}
 
-			// Extract UpCQL and DownCQL scripts from the content
-			up := strings.TrimSpace(strings.TrimPrefix(upDown[0], "-- Up Migration"))
+			// Extract UpCQL and DownCQL scripts from the content, and the comment header above them
+			header, up, found := strings.Cut(upDown[0], "-- Up Migration")
+			if !found {
+				header, up = "", upDown[0]
+			}
+			up = strings.TrimSpace(up)
 			down := strings.TrimSpace(upDown[1])
+			description, author := migfile.ParseCommentHeader(header)
 
 			// Append the parsed migration to the slice
 			migrations = append(migrations, Migration{
-				Version: version,
-				Name:    name,
-				UpCQL:   up,
-				DownCQL: down,
+				Version:     version,
+				Name:        name,
+				UpCQL:       up,
+				DownCQL:     down,
+				Description: description,
+				Author:      author,
 			})
 		}
 	}
@@ -344,32 +635,59 @@ func loadMigrations() ([]Migration, error) {
 		return migrations[i].Version < migrations[j].Version
 	})
 
-	return migrations, nil
+	return migfile.Exclude(migrations, func(m Migration) string { return m.Name }, excludePatterns)
 }
 
 // Migrate applies all pending migrations to the database.
 // It first creates the migrations table if it does not exist,
-// then applies each migration in order.
-func Migrate(session *gocql.Session) error {
+// then applies each migration in order. With dryRun their CQL is
+// printed instead and the database is left untouched.
+func Migrate(session *gocql.Session, dryRun bool) (MigrateResult, error) {
+	var result MigrateResult
+	start := time.Now()
+
+	if dryRun {
+		migrations, err := loadMigrations()
+		if err != nil {
+			return result, err
+		}
+		return dryRunMigrate(session, migrations, false)
+	}
+
 	// Create the migrations table if it doesn't exist
 	if err := createMigrationsTable(session); err != nil {
-		return err
+		return result, err
+	}
+
+	// Refuse to build on applied migrations whose files were edited since
+	if err := verifyChecksums(session); err != nil {
+		return result, err
 	}
 
 	// Load all migrations from the migration directory
 	migrations, err := loadMigrations()
 	if err != nil {
-		return err
+		return result, err
 	}
 
-	// Apply each migration to the database
+	// Apply each migration to the database, timing the ones that actually run
 	for _, migration := range migrations {
-		if err := applyMigration(session, migration); err != nil {
-			return err
+		migrationStart := time.Now()
+		applied, err := applyMigration(session, migration)
+		if err != nil {
+			return result, err
+		}
+		if !applied {
+			result.Skipped++
+			continue
 		}
+		result.Record(migration, time.Since(migrationStart))
 	}
 
-	return nil
+	result.TotalDuration = time.Since(start)
+	result.Print()
+
+	return result, nil
 }
 
 // RollbackLast rolls back the most recently applied migration.
@@ -404,7 +722,7 @@ func RollbackLast(session *gocql.Session) error {
 
 	// Check if the migration to rollback is found
 	if migrationToRollback.Version == 0 {
-		return fmt.Errorf("migration %d not found", latestMigration)
+		return fmt.Errorf("%w: %d", ErrMigrationNotFound, latestMigration)
 	}
 
 	// Apply the rollback operation
@@ -417,8 +735,9 @@ func RollbackLast(session *gocql.Session) error {
 	return nil
 }
 
-// RollbackSteps rolls back a specified number of migrations
-func RollbackSteps(session *gocql.Session, steps int) error {
+// RollbackSteps rolls back a specified number of migrations. With dryRun their Down CQL
+// is printed instead.
+func RollbackSteps(session *gocql.Session, steps int, dryRun bool) error {
 	// Get all applied migrations
 	appliedMigrations, err := getAppliedMigrations(session)
 	if err != nil {
@@ -445,6 +764,11 @@ func RollbackSteps(session *gocql.Session, steps int) error {
 	// Rollback each migration
 	for i := 0; i < steps; i++ {
 		migration := appliedMigrations[i]
+		if dryRun {
+			report.DryRun(migration, migration.DownCQL, " (rollback)")
+			continue
+		}
+
 		fmt.Printf("%s[ROLLBACK]%s Rolling back migration %s%d_%s%s... ",
 			ColorBlue, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset)
 
@@ -464,7 +788,7 @@ func RollbackSteps(session *gocql.Session, steps int) error {
 func getAppliedMigrations(session *gocql.Session) ([]Migration, error) {
 	var migrations []Migration
 
-	iter := session.Query(`SELECT version, name FROM migrations`).Iter()
+	iter := session.Query(`SELECT version, name FROM ` + migrationsTable).Iter()
 	var version int64
 	var name string
 
@@ -499,37 +823,81 @@ func getAppliedMigrations(session *gocql.Session) ([]Migration, error) {
 }
 
 // createMigrationsTable creates the migrations table if it doesn't exist.
-// This table keeps track of the applied migrations.
+// This table keeps track of the applied migrations. Tables created by older
+// versions get the duration_ms and checksum columns added.
 func createMigrationsTable(session *gocql.Session) error {
-	return session.Query(`
-		CREATE TABLE IF NOT EXISTS migrations (
+	if err := session.Query(`
+		CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (
 			version bigint PRIMARY KEY,
 			name text,
-			applied_at timestamp
+			applied_at timestamp,
+			duration_ms bigint,
+			checksum text
 		)
-	`).Exec()
+	`).Exec(); err != nil {
+		return err
+	}
+
+	hasDuration, err := hasDurationColumn(session)
+	if err != nil {
+		return err
+	}
+	if !hasDuration {
+		if err := session.Query(`ALTER TABLE ` + migrationsTable + ` ADD duration_ms bigint`).Exec(); err != nil {
+			return err
+		}
+	}
+	hasChecksum, err := hasMigrationsColumn(session, "checksum")
+	if err != nil || hasChecksum {
+		return err
+	}
+	return session.Query(`ALTER TABLE ` + migrationsTable + ` ADD checksum text`).Exec()
 }
 
-// applyMigration applies a single migration to the database.
-// It executes the UpCQL script and records the migration in the migrations table.
-func applyMigration(session *gocql.Session, migration Migration) error {
+// hasDurationColumn reports whether the migrations table records migration durations
+func hasDurationColumn(session *gocql.Session) (bool, error) {
+	return hasMigrationsColumn(session, "duration_ms")
+}
+
+// hasMigrationsColumn reports whether the migrations table has the column
+func hasMigrationsColumn(session *gocql.Session, name string) (bool, error) {
+	query := session.Query(`
+		SELECT column_name FROM system_schema.columns
+		WHERE keyspace_name = ? AND table_name = '` + migrationsTable + `' AND column_name = ?
+	`)
+	var column string
+	err := query.Bind(query.Keyspace(), name).Scan(&column)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// applyMigration applies a single migration to the database. It reports whether the
+// migration was applied, false meaning it was skipped as already applied.
+func applyMigration(session *gocql.Session, migration Migration) (bool, error) {
 	applied, err := isMigrationApplied(session, migration.Version)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	if applied {
-		fmt.Printf("%s[SKIPPED]%s Migration %s%d_%s%s already applied\n",
-			ColorYellow,
-			ColorReset,
-			ColorCyan,
-			migration.Version,
-			migration.Name,
-			ColorReset,
-		)
-		return nil
+		report.Skipped(migration)
+		return false, nil
+	}
+
+	return true, applyPendingMigration(session, migration)
+}
+
+// applyPendingMigration applies a migration the caller knows isn't applied yet.
+// It executes the UpCQL script and records the migration in the migrations table.
+func applyPendingMigration(session *gocql.Session, migration Migration) error {
+	checksum, err := fileChecksum(migration.Version, migration.Name)
+	if err != nil {
+		return err
 	}
 
+	start := time.Now()
 	fmt.Printf("%s[MIGRATING]%s %s%d_%s%s... ",
 		ColorBlue,
 		ColorReset,
@@ -539,21 +907,27 @@ func applyMigration(session *gocql.Session, migration Migration) error {
 		ColorReset,
 	)
 
-	statements := strings.Split(migration.UpCQL, ";")
-	for _, stmt := range statements {
-		stmt = strings.TrimSpace(stmt)
-		if stmt == "" {
-			continue
-		}
+	// Schema agreement progress is printed on its own lines
+	if waitForAgreement {
+		fmt.Println()
+	}
+
+	for _, stmt := range splitStatements(migration.UpCQL) {
 		if err := session.Query(stmt).Exec(); err != nil {
 			fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
 			return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
 		}
+		if waitForAgreement && isDDL(stmt) {
+			if err := waitForSchemaAgreement(session); err != nil {
+				fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
 	}
 
 	if err := session.Query(`
-		INSERT INTO migrations (version, name, applied_at) VALUES (?, ?, ?)
-	`, migration.Version, migration.Name, time.Now()).Exec(); err != nil {
+		INSERT INTO `+migrationsTable+` (version, name, applied_at, duration_ms, checksum) VALUES (?, ?, ?, ?, ?)
+	`, migration.Version, migration.Name, time.Now(), time.Since(start).Milliseconds(), checksum).Exec(); err != nil {
 		fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
 		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
 	}
@@ -563,17 +937,67 @@ func applyMigration(session *gocql.Session, migration Migration) error {
 	return nil
 }
 
-// rollbackMigration rolls back a single migration
-func rollbackMigration(session *gocql.Session, migration Migration) error {
-	// Split the down migration into individual statements
-	statements := strings.Split(migration.DownCQL, ";")
+// batchStartPattern and batchEndPattern match the boundaries of a CQL batch.
+var (
+	batchStartPattern = regexp.MustCompile(`(?i)\bBEGIN\s+(UNLOGGED\s+|COUNTER\s+)?BATCH\b`)
+	batchEndPattern   = regexp.MustCompile(`(?i)\bAPPLY\s+BATCH\b`)
+)
+
+// isBatchStatement reports whether the CQL starts a BEGIN ... BATCH block.
+// Comment lines are ignored.
+func isBatchStatement(cql string) bool {
+	return batchStartPattern.MatchString(stripComments(cql))
+}
 
-	for _, stmt := range statements {
-		stmt = strings.TrimSpace(stmt)
-		if stmt == "" {
+// splitStatements splits CQL into individual statements on semicolons.
+// Everything from BEGIN BATCH up to APPLY BATCH is kept together as a single
+// statement, since the statements inside a batch are themselves separated by
+// semicolons. Fragments consisting only of comments are dropped.
+func splitStatements(cql string) []string {
+	var statements []string
+	var batch []string
+
+	for _, part := range strings.Split(cql, ";") {
+		if batch == nil && isBatchStatement(part) {
+			batch = []string{}
+		}
+		if batch != nil {
+			batch = append(batch, part)
+			if batchEndPattern.MatchString(stripComments(part)) {
+				statements = append(statements, strings.TrimSpace(strings.Join(batch, ";")))
+				batch = nil
+			}
 			continue
 		}
+		if stmt := strings.TrimSpace(part); stripComments(stmt) != "" {
+			statements = append(statements, stmt)
+		}
+	}
+
+	// An unterminated batch is passed through so the server reports the error
+	if batch != nil {
+		statements = append(statements, strings.TrimSpace(strings.Join(batch, ";")))
+	}
+
+	return statements
+}
+
+// stripComments removes full-line comments from a CQL fragment.
+func stripComments(fragment string) string {
+	var lines []string
+	for _, line := range strings.Split(fragment, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") && !strings.HasPrefix(line, "//") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
 
+// rollbackMigration rolls back a single migration
+func rollbackMigration(session *gocql.Session, migration Migration) error {
+	// Split the down migration into individual statements
+	for _, stmt := range splitStatements(migration.DownCQL) {
 		// Execute each statement
 		if err := session.Query(stmt).Exec(); err != nil {
 			return fmt.Errorf("failed to execute down migration: %w", err)
@@ -582,7 +1006,7 @@ func rollbackMigration(session *gocql.Session, migration Migration) error {
 
 	// Remove migration record
 	if err := session.Query(`
-		DELETE FROM migrations WHERE version = ?
+		DELETE FROM `+migrationsTable+` WHERE version = ?
 	`, migration.Version).Exec(); err != nil {
 		return fmt.Errorf("failed to remove migration record: %w", err)
 	}
@@ -594,7 +1018,7 @@ func rollbackMigration(session *gocql.Session, migration Migration) error {
 // It queries the migrations table to check if the version exists.
 func isMigrationApplied(session *gocql.Session, version int64) (bool, error) {
 	var count int
-	if err := session.Query(`SELECT COUNT(*) FROM migrations WHERE version = ?`, version).Scan(&count); err != nil {
+	if err := session.Query(`SELECT COUNT(*) FROM `+migrationsTable+` WHERE version = ?`, version).Scan(&count); err != nil {
 		return false, fmt.Errorf("failed to check if migration is applied: %w", err)
 	}
 	return count > 0, nil
@@ -604,7 +1028,7 @@ func isMigrationApplied(session *gocql.Session, version int64) (bool, error) {
 // It queries the migrations table for the highest version number.
 func getLatestMigration(session *gocql.Session) (int64, error) {
 	var version int64
-	if err := session.Query(`SELECT version FROM migrations ORDER BY version DESC LIMIT 1`).Scan(&version); err != nil {
+	if err := session.Query(`SELECT version FROM ` + migrationsTable + ` ORDER BY version DESC LIMIT 1`).Scan(&version); err != nil {
 		if err == gocql.ErrNotFound {
 			// No migrations have been applied yet
 			return 0, nil
@@ -614,8 +1038,46 @@ func getLatestMigration(session *gocql.Session) (int64, error) {
 	return version, nil
 }
 
+// Number of Up CQL lines ListMigrations previews below each migration, see SetSQLPreview
+var sqlPreviewLines int
+
+// SetSQLPreview makes ListMigrations print the first maxLines non-empty lines of each
+// migration's Up CQL. 0 disables the preview.
+func SetSQLPreview(maxLines int) {
+	sqlPreviewLines = maxLines
+}
+
+// Description written into the header of new migration files, see SetMigrationComment
+var migrationComment string
+
+// SetMigrationComment makes new migration files start with a header holding comment
+// as the description, plus the current user and date. Empty writes no header.
+func SetMigrationComment(comment string) {
+	migrationComment = strings.Join(strings.Fields(comment), " ")
+}
+
+// Whether ListMigrations shows the author and description of each migration, see SetVerbose
+var verboseList bool
+
+// SetVerbose makes ListMigrations print the author and description from the header
+// of each migration below it.
+func SetVerbose(verbose bool) {
+	verboseList = verbose
+}
+
+// Filters of ListMigrations, see SetListFilter
+var listFilter report.ListFilter
+
+// SetListFilter makes ListMigrations show only the migrations that aren't applied yet,
+// and with countOnly print just the number of listed migrations as a bare integer, e.g.
+// for pending=$(jbmdb cql-list --only-pending --count).
+func SetListFilter(onlyPending, countOnly bool) {
+	listFilter = report.ListFilter{OnlyPending: onlyPending, CountOnly: countOnly}
+}
+
 // ListMigrations retrieves and lists all migrations along with their status.
-func ListMigrations(session *gocql.Session) error {
+// order is "asc" or "desc" by version, and a limit above 0 shows only that many.
+func ListMigrations(session *gocql.Session, order string, limit int) error {
 	// Load all migrations from files
 	migrations, err := loadMigrations()
 	if err != nil {
@@ -624,7 +1086,7 @@ func ListMigrations(session *gocql.Session) error {
 
 	// Get all applied migrations from the database
 	appliedMigrations := make(map[int64]time.Time)
-	iter := session.Query("SELECT version, applied_at FROM migrations").Iter()
+	iter := session.Query("SELECT version, applied_at FROM " + migrationsTable).Iter()
 	var version int64
 	var appliedAt time.Time
 	for iter.Scan(&version, &appliedAt) {
@@ -634,38 +1096,98 @@ func ListMigrations(session *gocql.Session) error {
 		return fmt.Errorf("failed to query migrations table: %w", err)
 	}
 
+	// Filter before ordering, so a limit applies to the pending migrations
+	listed, done := report.FilterList(listFilter, migrations, func(m Migration) bool {
+		_, ok := appliedMigrations[m.Version]
+		return ok
+	})
+	if done {
+		return nil
+	}
+
+	shown, err := migfile.Order(listed, order, limit)
+	if err != nil {
+		return err
+	}
+
 	// Print header
-	fmt.Printf("\n%sMigration Status%s\n", ColorBold, ColorReset)
+	fmt.Printf("\n%sMigration Status%s", ColorBold, ColorReset)
+	if limit > 0 {
+		position := "first"
+		if order == "desc" {
+			position = "last"
+		}
+		fmt.Printf(" (showing %s %d of %d)", position, len(shown), len(listed))
+	}
+	fmt.Println()
 	fmt.Println(strings.Repeat("-", 80))
 	fmt.Printf("%-20s %-30s %-15s %s\n", "Version", "Name", "Status", "Applied At")
 	fmt.Println(strings.Repeat("-", 80))
 
 	// Print each migration with its status
-	for _, m := range migrations {
+	for _, m := range shown {
 		appliedAt, isApplied := appliedMigrations[m.Version]
 		status := fmt.Sprintf("%sPending%s", ColorYellow, ColorReset)
 		appliedAtStr := "Not Applied"
 		if isApplied {
 			status = fmt.Sprintf("%sApplied%s", ColorGreen, ColorReset)
-			appliedAtStr = appliedAt.Format("2006-01-02 15:04:05")
+			appliedAtStr = appliedAt.In(displayLocation).Format("2006-01-02 15:04:05 MST")
 		}
 		fmt.Printf("%-20d %-30s %-15s %s\n", m.Version, m.Name, status, appliedAtStr)
+		if verboseList {
+			report.CommentHeader(m.Author, m.Description)
+		}
+		if sqlPreviewLines > 0 {
+			report.SQLPreview(m.UpCQL, sqlPreviewLines)
+		}
 	}
 	fmt.Println(strings.Repeat("-", 80))
 
+	// Summary counts cover all migrations, not just the ones shown
+	applied := 0
+	for _, m := range migrations {
+		if _, ok := appliedMigrations[m.Version]; ok {
+			applied++
+		}
+	}
+	fmt.Printf("\n%sSummary%s\n", ColorBold, ColorReset)
+	fmt.Printf("Total: %d, %sApplied: %d%s, %sPending: %d%s\n",
+		len(migrations), ColorGreen, applied, ColorReset, ColorYellow, len(migrations)-applied, ColorReset)
+
 	return nil
 }
 
-// parseInt converts a string to an integer.
-// It uses Sscanf to parse the integer value from the string.
-func parseInt(s string) int64 {
-	var result int64
-	fmt.Sscanf(s, "%d", &result)
-	return result
+// parseInt converts a migration version to an integer, failing on anything that
+// isn't a number instead of returning 0.
+func parseInt(s string) (int64, error) {
+	result, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid migration version %q: %w", s, err)
+	}
+	return result, nil
 }
 
-// MigrateFresh drops all tables and reapplies all migrations
-func MigrateFresh(session *gocql.Session) error {
+// MigrateFresh drops all tables and reapplies all migrations, after taking a snapshot
+// if SetSnapshotBeforeFresh was called. With dryRun the CQL of all migrations is printed
+// instead and nothing is dropped.
+func MigrateFresh(session *gocql.Session, dryRun bool) error {
+	if dryRun {
+		fmt.Printf("%s[DRY RUN]%s All tables would be dropped\n", ColorYellow, ColorReset)
+		migrations, err := loadMigrations()
+		if err != nil {
+			return err
+		}
+		_, err = dryRunMigrate(session, migrations, true)
+		return err
+	}
+
+	// Nothing is dropped without the snapshot that was asked for
+	if freshSnapshotTag != "" {
+		if err := snapshotKeyspace(currentKeyspace(session), freshSnapshotTag); err != nil {
+			return fmt.Errorf("failed to snapshot before fresh: %w", err)
+		}
+	}
+
 	fmt.Printf("%s[FRESH]%s Dropping all tables...\n", ColorYellow, ColorReset)
 
 	// Drop all user-created tables
@@ -677,17 +1199,21 @@ func MigrateFresh(session *gocql.Session) error {
 	fmt.Printf("%s[FRESH]%s Reapplying all migrations...\n", ColorBlue, ColorYellow)
 
 	// Reapply all migrations
-	if err := Migrate(session); err != nil {
+	if _, err := Migrate(session, false); err != nil {
 		return fmt.Errorf("failed to reapply migrations: %w", err)
 	}
 
 	return nil
 }
 
-// dropAllTables drops all user-created tables in the keyspace
+// dropAllTables drops all user-defined functions and user-created tables in the keyspace
 func dropAllTables(session *gocql.Session) error {
 	// Get the current keyspace name
-	keyspace := session.Query(`SELECT keyspace_name FROM system_schema.tables WHERE table_name = 'migrations'`).Keyspace()
+	keyspace := currentKeyspace(session)
+
+	if err := dropAllFunctions(session, keyspace); err != nil {
+		return err
+	}
 
 	// Query to get only user-created tables in the keyspace
 	query := `SELECT table_name 
@@ -707,11 +1233,12 @@ func dropAllTables(session *gocql.Session) error {
 		"system_traces":      true,
 	}
 
-	// Collect all user-created table names
+	// Collect all user-created table names, only the prefixed ones with a table prefix
 	for iter.Scan(&tableName) {
 		// Skip system tables and migrations table
 		if !systemKeyspaces[tableName] && !strings.HasPrefix(tableName, "system_") &&
-			!strings.HasPrefix(tableName, "scylla_") && tableName != "migrations" {
+			!strings.HasPrefix(tableName, "scylla_") && tableName != migrationsTable &&
+			strings.HasPrefix(tableName, tablePrefix) {
 			tables = append(tables, tableName)
 		}
 	}
@@ -739,7 +1266,7 @@ func dropAllTables(session *gocql.Session) error {
 
 	// Finally, drop the migrations table
 	fmt.Printf("%s[DROP]%s Dropping migrations table...", ColorYellow, ColorReset)
-	if err := session.Query("DROP TABLE IF EXISTS migrations").Exec(); err != nil {
+	if err := session.Query("DROP TABLE IF EXISTS " + migrationsTable).Exec(); err != nil {
 		fmt.Printf(" %sFAILED%s\n", ColorRed, ColorReset)
 		return fmt.Errorf("failed to drop migrations table: %w", err)
 	}
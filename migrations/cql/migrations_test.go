@@ -0,0 +1,30 @@
+package cql
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadMigrationsRejectsNonNumericVersion(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "cql"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	SetMigrationPath(dir)
+	t.Cleanup(func() { SetMigrationPath("") })
+
+	content := "-- Up Migration\nCREATE TABLE users (id bigint PRIMARY KEY);\n\n-- Down Migration\nDROP TABLE users;\n"
+	if err := os.WriteFile(filepath.Join(dir, "cql", "notanumber_create_users_table.cql"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := loadMigrations()
+	if err == nil {
+		t.Fatalf("loadMigrations() = %v, want an error for the non-numeric version", migrations)
+	}
+	if !strings.Contains(err.Error(), "notanumber_create_users_table.cql") {
+		t.Errorf("error %q doesn't name the migration file", err)
+	}
+}
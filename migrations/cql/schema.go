@@ -0,0 +1,154 @@
+package cql
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// schemaAgreementPollInterval is how often WaitForSchemaAgreement re-checks the cluster.
+const schemaAgreementPollInterval = 5 * time.Second
+
+// ddlPollInterval is how often applyMigration re-checks the cluster after a DDL statement.
+const ddlPollInterval = 200 * time.Millisecond
+
+// defaultSchemaAgreementTimeout is used when SchemaAgreementTimeout is not configured.
+const defaultSchemaAgreementTimeout = 60 * time.Second
+
+// ddlPattern matches statements that change the schema.
+var ddlPattern = regexp.MustCompile(`(?i)^(CREATE|ALTER|DROP|TRUNCATE)\b`)
+
+// Whether applyMigration waits for schema agreement after each DDL statement, and for how long.
+var (
+	waitForAgreement       bool
+	schemaAgreementTimeout = defaultSchemaAgreementTimeout
+)
+
+// SetWaitForSchemaAgreement makes applyMigration wait for all nodes to agree on the
+// schema after every DDL statement. A zero timeout uses the default of 60 seconds.
+func SetWaitForSchemaAgreement(enabled bool, timeout time.Duration) {
+	waitForAgreement = enabled
+	if timeout > 0 {
+		schemaAgreementTimeout = timeout
+	} else {
+		schemaAgreementTimeout = defaultSchemaAgreementTimeout
+	}
+}
+
+// isDDL reports whether the statement changes the schema, ignoring leading comments.
+func isDDL(stmt string) bool {
+	return ddlPattern.MatchString(stripComments(stmt))
+}
+
+// waitForSchemaAgreement waits after a DDL statement until all nodes report the same
+// schema version or schemaAgreementTimeout expires.
+func waitForSchemaAgreement(session *gocql.Session) error {
+	fmt.Printf("%s[WAIT]%s Waiting for schema agreement...\n", ColorYellow, ColorReset)
+
+	start := time.Now()
+	if _, err := awaitSchemaAgreement(session, schemaAgreementTimeout, ddlPollInterval); err != nil {
+		return err
+	}
+	fmt.Printf("%s[AGREE]%s Schema agreement reached in %.1fs\n",
+		ColorGreen, ColorReset, time.Since(start).Seconds())
+	return nil
+}
+
+// awaitSchemaAgreement polls the cluster every interval until all nodes report the same
+// schema version, and returns the versions they agreed on. When timeout expires first,
+// it prints the nodes grouped by the version they still report and fails.
+func awaitSchemaAgreement(session *gocql.Session, timeout, interval time.Duration) (map[string]string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		versions, err := schemaVersions(session)
+		if err != nil {
+			return nil, err
+		}
+		if inAgreement(versions) {
+			return versions, nil
+		}
+		if !time.Now().Before(deadline) {
+			fmt.Printf("%sNodes still disagree:%s\n", ColorRed, ColorReset)
+			printSchemaVersions(versions)
+			return nil, fmt.Errorf("schema agreement not reached within %s", timeout)
+		}
+		time.Sleep(min(interval, time.Until(deadline)))
+	}
+}
+
+// schemaVersions returns the schema version reported for each node, keyed by address.
+// The node the session is connected to is reported as "local".
+func schemaVersions(session *gocql.Session) (map[string]string, error) {
+	versions := make(map[string]string)
+
+	var localVersion gocql.UUID
+	if err := session.Query("SELECT schema_version FROM system.local").Scan(&localVersion); err != nil {
+		return nil, fmt.Errorf("failed to read local schema version: %w", err)
+	}
+	versions["local"] = localVersion.String()
+
+	var peer net.IP
+	var peerVersion gocql.UUID
+	iter := session.Query("SELECT peer, schema_version FROM system.peers").Iter()
+	for iter.Scan(&peer, &peerVersion) {
+		versions[peer.String()] = peerVersion.String()
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read peer schema versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// inAgreement reports whether all nodes report the same schema version.
+func inAgreement(versions map[string]string) bool {
+	var first string
+	for _, version := range versions {
+		if first == "" {
+			first = version
+		} else if version != first {
+			return false
+		}
+	}
+	return true
+}
+
+// printSchemaVersions prints the nodes grouped by the schema version they report.
+func printSchemaVersions(versions map[string]string) {
+	nodes := make(map[string][]string)
+	for node, version := range versions {
+		nodes[version] = append(nodes[version], node)
+	}
+
+	for version, hosts := range nodes {
+		sort.Strings(hosts)
+		fmt.Printf("  %s%s%s: %s\n", ColorCyan, version, ColorReset, strings.Join(hosts, ", "))
+	}
+}
+
+// WaitForSchemaAgreement checks whether all nodes agree on the schema version and, if
+// not, prints the disagreeing nodes and polls every 5 seconds until they converge or
+// the timeout expires.
+func WaitForSchemaAgreement(session *gocql.Session, timeout time.Duration) error {
+	versions, err := schemaVersions(session)
+	if err != nil {
+		return err
+	}
+	if !inAgreement(versions) {
+		fmt.Printf("%s[WARNING]%s Schema disagreement detected:\n", ColorYellow, ColorReset)
+		printSchemaVersions(versions)
+		fmt.Printf("Waiting up to %s for schema convergence...\n", timeout)
+
+		if versions, err = awaitSchemaAgreement(session, timeout, schemaAgreementPollInterval); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%sAll %d nodes agree on the schema%s\n", ColorGreen, len(versions), ColorReset)
+	return nil
+}
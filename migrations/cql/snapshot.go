@@ -0,0 +1,123 @@
+package cql
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Tag of the snapshot MigrateFresh takes before dropping tables, see SetSnapshotBeforeFresh
+var freshSnapshotTag string
+
+// SetSnapshotBeforeFresh makes MigrateFresh snapshot the keyspace with nodetool before
+// dropping its tables. An empty tag defaults to jbmdb_fresh_<timestamp>.
+func SetSnapshotBeforeFresh(tag string) {
+	if tag == "" {
+		tag = "jbmdb_fresh_" + time.Now().Format("20060102150405")
+	}
+	freshSnapshotTag = tag
+}
+
+// currentKeyspace returns the keyspace the session is connected to
+func currentKeyspace(session *gocql.Session) string {
+	return session.Query(`SELECT keyspace_name FROM system_schema.tables WHERE table_name = '` + migrationsTable + `'`).Keyspace()
+}
+
+// snapshotKeyspace snapshots the tables of keyspace under tag with nodetool. The
+// snapshot only covers the node nodetool talks to.
+func snapshotKeyspace(keyspace, tag string) error {
+	nodetool, err := exec.LookPath("nodetool")
+	if err != nil {
+		return ErrNodetoolNotFound
+	}
+
+	fmt.Printf("%s[SNAPSHOT]%s Snapshotting keyspace %s as %s...\n", ColorBlue, ColorReset, keyspace, tag)
+	cmd := exec.Command(nodetool, "snapshot", "-t", tag, keyspace)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nodetool snapshot failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	fmt.Printf("%s[SNAPSHOT]%s Created snapshot %s of keyspace %s\n", ColorGreen, ColorReset, tag, keyspace)
+	return nil
+}
+
+// CreateSnapshot snapshots the tables of the session's keyspace under tag with
+// nodetool. Without nodetool, e.g. for a remote cluster, it lists the existing
+// snapshots and prints the command to run on each node instead.
+func CreateSnapshot(session *gocql.Session, tag string) error {
+	keyspace := currentKeyspace(session)
+	err := snapshotKeyspace(keyspace, tag)
+	if !errors.Is(err, ErrNodetoolNotFound) {
+		return err
+	}
+
+	fmt.Printf("%s[WARNING]%s nodetool not found in PATH, no snapshot was taken\n", ColorYellow, ColorReset)
+	if err := ListSnapshots(session); err != nil {
+		fmt.Printf("%s[WARNING]%s %v\n", ColorYellow, ColorReset, err)
+	}
+	fmt.Printf("\nRun this on every node of the cluster to take the snapshot:\n")
+	fmt.Printf("    %snodetool snapshot -t %s %s%s\n", ColorCyan, tag, keyspace, ColorReset)
+	return nil
+}
+
+// ListSnapshots prints the snapshots in ScyllaDB's system.snapshots table, which
+// Cassandra doesn't have; use nodetool listsnapshots there.
+func ListSnapshots(session *gocql.Session) error {
+	iter := session.Query(`SELECT keyspace_name, table_name, snapshot_name, live, total FROM system.snapshots`).Iter()
+
+	// Print header
+	fmt.Printf("\n%sSnapshots%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-24s %-20s %-20s %7s %7s\n", "Snapshot", "Keyspace", "Table", "Live", "Total")
+	fmt.Println(strings.Repeat("-", 80))
+
+	var keyspace, table, snapshot string
+	var live, total int64
+	count := 0
+	for iter.Scan(&keyspace, &table, &snapshot, &live, &total) {
+		fmt.Printf("%s%-24s%s %-20s %-20s %7s %7s\n",
+			ColorCyan, snapshot, ColorReset, keyspace, table, formatBytes(live), formatBytes(total))
+		count++
+	}
+	if err := iter.Close(); err != nil {
+		return fmt.Errorf("failed to query system.snapshots (ScyllaDB only, use nodetool listsnapshots on Cassandra): %w", err)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("Total: %d snapshot tables\n", count)
+
+	return nil
+}
+
+// ClearSnapshot removes the snapshot with the given tag with nodetool clearsnapshot
+func ClearSnapshot(tag string) error {
+	nodetool, err := exec.LookPath("nodetool")
+	if err != nil {
+		return fmt.Errorf("%w: run nodetool clearsnapshot -t %s on every node", ErrNodetoolNotFound, tag)
+	}
+
+	cmd := exec.Command(nodetool, "clearsnapshot", "-t", tag)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nodetool clearsnapshot failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	fmt.Printf("%s[SNAPSHOT]%s Cleared snapshot %s\n", ColorGreen, ColorReset, tag)
+	return nil
+}
+
+// formatBytes formats a size in bytes with a binary unit, e.g. 1.5 MiB
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,154 @@
+package cql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jbarasa/jbmdb/migrations/internal/naming"
+)
+
+// CreateBatchMigration creates a migration file with a batch skeleton for data
+// changes that must be applied atomically.
+func CreateBatchMigration(name string) error {
+	up := `-- BEGIN BATCH is a logged batch: either all statements are applied or none are
+BEGIN BATCH
+    -- TODO INSERT INTO ... (...) VALUES (...);
+    -- TODO UPDATE ... SET ... WHERE ...;
+APPLY BATCH;`
+	down := `-- TODO reverse the batch changes
+-- BEGIN BATCH
+--     DELETE FROM ... WHERE ...;
+-- APPLY BATCH;`
+
+	return writeMigrationFile(strings.ToLower(name), up, down)
+}
+
+// keyColumn is a primary key column parsed from a "name:type" pair
+type keyColumn struct {
+	name, typ string
+}
+
+// parseKeyColumns parses "name:type" pairs, e.g. from --partition-key user_id:uuid
+func parseKeyColumns(flag string, pairs []string) ([]keyColumn, error) {
+	columns := make([]keyColumn, 0, len(pairs))
+	for _, pair := range pairs {
+		name, typ, ok := strings.Cut(pair, ":")
+		name, typ = strings.TrimSpace(name), strings.TrimSpace(typ)
+		if !ok || typ == "" || !identifierPattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid %s column %q, expected <name:type>", flag, pair)
+		}
+		columns = append(columns, keyColumn{name: strings.ToLower(name), typ: typ})
+	}
+	return columns, nil
+}
+
+// referenceColumns returns the uuid column lines for --references tables, each preceded
+// by a comment naming the table since CQL cannot enforce the reference.
+func referenceColumns(tables []string) string {
+	var columns strings.Builder
+	for _, table := range tables {
+		table = strings.ToLower(strings.TrimPrefix(table, tablePrefix))
+		fmt.Fprintf(&columns, "    -- Logical reference to %s\n    %s uuid,\n", prefixedTable(table), naming.ReferenceColumn(table))
+	}
+	return columns.String()
+}
+
+// compactionClasses maps the --compaction-strategy abbreviations to their class names
+var compactionClasses = map[string]string{
+	"lcs":  "LeveledCompactionStrategy",
+	"stcs": "SizeTieredCompactionStrategy",
+	"twcs": "TimeWindowCompactionStrategy",
+}
+
+// compactionOption returns the compaction table option for opts, or "" to keep the
+// cluster default.
+func compactionOption(opts TableOptions) (string, error) {
+	strategy := strings.ToLower(opts.CompactionStrategy)
+	if strategy != "twcs" && (opts.TWCSWindowUnit != "" || opts.TWCSWindowSize != 0) {
+		return "", fmt.Errorf("--twcs-window-unit and --twcs-window-size require --compaction-strategy twcs")
+	}
+	if strategy == "" {
+		return "", nil
+	}
+
+	class, ok := compactionClasses[strategy]
+	if !ok {
+		return "", fmt.Errorf("invalid compaction strategy %q: must be lcs, stcs or twcs", opts.CompactionStrategy)
+	}
+
+	option := fmt.Sprintf("'class': '%s'", class)
+	if unit := strings.ToUpper(opts.TWCSWindowUnit); unit != "" {
+		if unit != "HOURS" && unit != "DAYS" {
+			return "", fmt.Errorf("invalid TWCS window unit %q: must be hours or days", opts.TWCSWindowUnit)
+		}
+		option += fmt.Sprintf(", 'compaction_window_unit': '%s'", unit)
+	}
+	if opts.TWCSWindowSize < 0 {
+		return "", fmt.Errorf("invalid TWCS window size %d: must be positive", opts.TWCSWindowSize)
+	}
+	if opts.TWCSWindowSize > 0 {
+		option += fmt.Sprintf(", 'compaction_window_size': '%d'", opts.TWCSWindowSize)
+	}
+	return "compaction = {" + option + "}", nil
+}
+
+// createTableWithKeys returns a CREATE TABLE statement with the given partition and
+// clustering keys in place of the default id column.
+func createTableWithKeys(table string, opts TableOptions) (string, error) {
+	partition, err := parseKeyColumns("partition key", opts.PartitionKey)
+	if err != nil {
+		return "", err
+	}
+	clustering, err := parseKeyColumns("clustering key", opts.ClusteringKey)
+	if err != nil {
+		return "", err
+	}
+	if len(partition) == 0 {
+		return "", fmt.Errorf("--clustering-key and --clustering-order require --partition-key")
+	}
+
+	var columns, partitionNames, clusteringNames []string
+	for _, column := range partition {
+		columns = append(columns, fmt.Sprintf("    %s %s,", column.name, column.typ))
+		partitionNames = append(partitionNames, column.name)
+	}
+	for _, column := range clustering {
+		columns = append(columns, fmt.Sprintf("    %s %s,", column.name, column.typ))
+		clusteringNames = append(clusteringNames, column.name)
+	}
+
+	// A composite partition key needs its own parentheses
+	primaryKey := strings.Join(partitionNames, ", ")
+	if len(partition) > 1 {
+		primaryKey = "(" + primaryKey + ")"
+	}
+	if len(clustering) > 0 {
+		primaryKey += ", " + strings.Join(clusteringNames, ", ")
+	}
+
+	var orders []string
+	for _, pair := range opts.ClusteringOrder {
+		name, direction, _ := strings.Cut(pair, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		direction = strings.ToUpper(strings.TrimSpace(direction))
+		if !slices.Contains(clusteringNames, name) {
+			return "", fmt.Errorf("clustering order column %q is not a clustering key column", name)
+		}
+		if direction != "ASC" && direction != "DESC" {
+			return "", fmt.Errorf("invalid clustering order %q, expected <name:asc|desc>", pair)
+		}
+		orders = append(orders, name+" "+direction)
+	}
+
+	stmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+%s
+%s    created_at timestamp,
+    updated_at timestamp,
+    PRIMARY KEY (%s)
+)`, table, strings.Join(columns, "\n"), referenceColumns(opts.References), primaryKey)
+	if len(orders) > 0 {
+		stmt += fmt.Sprintf(" WITH CLUSTERING ORDER BY (%s)", strings.Join(orders, ", "))
+	}
+	return stmt + ";", nil
+}
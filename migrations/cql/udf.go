@@ -0,0 +1,82 @@
+package cql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// udfLanguages are the languages ScyllaDB accepts for user-defined functions
+var udfLanguages = []string{"lua", "xwasm"}
+
+// CreateUDFMigration creates a migration file for a user-defined function in language,
+// lua or xwasm. The argument list and body are left to be filled in.
+func CreateUDFMigration(name, language string) error {
+	name, language = strings.ToLower(name), strings.ToLower(language)
+	if !identifierPattern.MatchString(name) {
+		return fmt.Errorf("invalid function name %q", name)
+	}
+	if !slices.Contains(udfLanguages, language) {
+		return fmt.Errorf("invalid function language %q: must be lua or xwasm", language)
+	}
+	// Prefixed like tables, so fresh migrations drop it
+	name = prefixedTable(name)
+
+	fmt.Printf("%s[WARNING]%s User-defined functions need ScyllaDB with enable_user_defined_functions: true and\n"+
+		"          experimental_features: [udf] in scylla.yaml; xwasm needs a release with WebAssembly support\n",
+		ColorYellow, ColorReset)
+
+	up := fmt.Sprintf(`-- Requires enable_user_defined_functions: true and experimental_features: [udf]
+-- Statements are split on semicolons, keep them out of the function body
+CREATE FUNCTION IF NOT EXISTS %s(input text)
+    CALLED ON NULL INPUT
+    RETURNS text
+    LANGUAGE %s
+    AS '-- TODO';`, name, language)
+	down := fmt.Sprintf(`DROP FUNCTION IF EXISTS %s;`, name)
+
+	return writeMigrationFile("create_"+name+"_function", up, down)
+}
+
+// releaseVersion returns the release_version the connected node reports, or "unknown"
+func releaseVersion(session *gocql.Session) string {
+	var version string
+	if err := session.Query(`SELECT release_version FROM system.local`).Scan(&version); err != nil {
+		return "unknown"
+	}
+	return version
+}
+
+// dropAllFunctions drops the user-defined functions of the keyspace. Servers without
+// system_schema.functions have no user-defined functions, so they only get a warning.
+func dropAllFunctions(session *gocql.Session, keyspace string) error {
+	iter := session.Query(`SELECT function_name, argument_types FROM system_schema.functions
+			 WHERE keyspace_name = ?`, keyspace).Iter()
+
+	var name string
+	var argumentTypes []string
+	var functions []string
+	for iter.Scan(&name, &argumentTypes) {
+		if strings.HasPrefix(name, tablePrefix) {
+			// Overloads are told apart by their argument types
+			functions = append(functions, fmt.Sprintf("%s(%s)", name, strings.Join(argumentTypes, ", ")))
+		}
+	}
+	if err := iter.Close(); err != nil {
+		fmt.Printf("%s[WARNING]%s Could not list user-defined functions (release %s): %v\n",
+			ColorYellow, ColorReset, releaseVersion(session), err)
+		return nil
+	}
+
+	for _, function := range functions {
+		fmt.Printf("%s[DROP]%s Dropping function %s%s%s...", ColorYellow, ColorReset, ColorCyan, function, ColorReset)
+		if err := session.Query("DROP FUNCTION IF EXISTS " + function).Exec(); err != nil {
+			fmt.Printf(" %sFAILED%s\n", ColorRed, ColorReset)
+			return fmt.Errorf("failed to drop function %s: %w", function, err)
+		}
+		fmt.Printf(" %sDONE%s\n", ColorGreen, ColorReset)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Package checksum detects migration files that were edited after they were applied,
+// the same way for every database driver
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jbarasa/jbmdb/migrations/internal/report"
+)
+
+// Record is the checksum recorded for an applied migration
+type Record struct {
+	Version  int64
+	Name     string
+	Checksum string
+}
+
+// File returns the hex encoded SHA-256 of the migration file at path
+func File(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migration file %s: %w", filepath.Base(path), err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify compares each record, in the order given, with the checksum of the current
+// migration file that sum returns, and fails with mismatch when any file changed.
+// Migrations whose file is gone aren't checked.
+func Verify(records []Record, sum func(version int64, name string) (string, error), mismatch error) error {
+	mismatches := 0
+	for _, r := range records {
+		checksum, err := sum(r.Version, r.Name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if checksum == r.Checksum {
+			continue
+		}
+
+		fmt.Printf("%s[CHECKSUM MISMATCH]%s %s%d_%s%s changed after it was applied\n",
+			report.ColorRed, report.ColorReset, report.ColorCyan, r.Version, r.Name, report.ColorReset)
+		fmt.Printf("    applied: %s\n    current: %s\n", r.Checksum, checksum)
+		mismatches++
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("%w: %d applied migration files were modified", mismatch, mismatches)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package graph provides a small dependency graph used to order database objects
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrCycle is returned by TopologicalSort when the graph contains a cycle
+var ErrCycle = errors.New("dependency cycle")
+
+// Graph is a directed graph of named nodes where an edge from a to b means
+// a depends on b.
+type Graph struct {
+	deps map[string]map[string]bool
+}
+
+// New creates an empty graph
+func New() *Graph {
+	return &Graph{deps: make(map[string]map[string]bool)}
+}
+
+// AddNode adds a node without dependencies. Adding an existing node is a no-op.
+func (g *Graph) AddNode(name string) {
+	if _, ok := g.deps[name]; !ok {
+		g.deps[name] = make(map[string]bool)
+	}
+}
+
+// AddEdge records that from depends on to, adding both nodes if needed.
+// Self references are ignored since they never affect the order.
+func (g *Graph) AddEdge(from, to string) {
+	g.AddNode(from)
+	g.AddNode(to)
+	if from != to {
+		g.deps[from][to] = true
+	}
+}
+
+// TopologicalSort returns the nodes ordered so that every node comes after the
+// nodes it depends on. Nodes without an order between them are sorted by name so
+// the result is deterministic. A cycle is reported as an ErrCycle error listing the
+// nodes that could not be ordered.
+func (g *Graph) TopologicalSort() ([]string, error) {
+	// Count unresolved dependencies and index the reverse edges
+	pending := make(map[string]int, len(g.deps))
+	dependents := make(map[string][]string)
+	for node, deps := range g.deps {
+		pending[node] = len(deps)
+		for dep := range deps {
+			dependents[dep] = append(dependents[dep], node)
+		}
+	}
+
+	var ready []string
+	for node, count := range pending {
+		if count == 0 {
+			ready = append(ready, node)
+		}
+	}
+
+	order := make([]string, 0, len(g.deps))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		node := ready[0]
+		ready = ready[1:]
+		order = append(order, node)
+
+		for _, dependent := range dependents[node] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) < len(g.deps) {
+		var cycle []string
+		for node, count := range pending {
+			if count > 0 {
+				cycle = append(cycle, node)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("%w involving: %s", ErrCycle, strings.Join(cycle, ", "))
+	}
+
+	return order, nil
+}
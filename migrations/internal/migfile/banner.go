@@ -0,0 +1,16 @@
+package migfile
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sectionBannerPattern matches the "Write your ... migration here" banner lines jbmdb
+// adds below the section headers.
+var sectionBannerPattern = regexp.MustCompile(`(?m)^-+ Write your (up|down) migration here -+\n?`)
+
+// StripBanners returns the SQL or CQL of a migration section without the banner lines
+// and surrounding whitespace
+func StripBanners(sql string) string {
+	return strings.TrimSpace(sectionBannerPattern.ReplaceAllString(sql, ""))
+}
@@ -0,0 +1,53 @@
+package migfile
+
+import (
+	"fmt"
+	"path"
+	"slices"
+)
+
+// Exclude returns the migrations whose name matches none of the glob patterns (as
+// accepted by path.Match). name returns the name of a migration.
+func Exclude[M any](migrations []M, name func(M) string, patterns []string) ([]M, error) {
+	if len(patterns) == 0 {
+		return migrations, nil
+	}
+
+	filtered := make([]M, 0, len(migrations))
+	for _, migration := range migrations {
+		excluded := false
+		for _, pattern := range patterns {
+			matched, err := path.Match(pattern, name(migration))
+			if err != nil {
+				return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+			}
+			if matched {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			filtered = append(filtered, migration)
+		}
+	}
+	return filtered, nil
+}
+
+// Order returns the migrations in the given order, "asc" (or empty) for oldest first
+// and "desc" for newest first, keeping at most limit of them when limit is above 0.
+// migrations must be in version order.
+func Order[M any](migrations []M, order string, limit int) ([]M, error) {
+	ordered := slices.Clone(migrations)
+	switch order {
+	case "", "asc":
+	case "desc":
+		slices.Reverse(ordered)
+	default:
+		return nil, fmt.Errorf("invalid order %q: must be asc or desc", order)
+	}
+
+	if limit > 0 && limit < len(ordered) {
+		ordered = ordered[:limit]
+	}
+	return ordered, nil
+}
@@ -0,0 +1,24 @@
+package migfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFlywayConf writes the flyway.conf of a Flyway export. It holds the password, so
+// it is only readable by the owner.
+func WriteFlywayConf(dstPath, url, user, password string) error {
+	content := fmt.Sprintf(`# Generated by jbmdb. Undo (U) migrations require Flyway Teams.
+flyway.url=%s
+flyway.user=%s
+flyway.password=%s
+flyway.locations=filesystem:%s
+`, url, user, password, dstPath)
+
+	path := filepath.Join(dstPath, "flyway.conf")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
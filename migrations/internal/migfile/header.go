@@ -0,0 +1,43 @@
+// Package migfile reads and writes the parts of migration files that are the same for
+// every database driver
+package migfile
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// CommentHeader returns the description header of a new migration file, or "" when
+// no comment was given.
+func CommentHeader(comment string, now time.Time) string {
+	if comment == "" {
+		return ""
+	}
+	return fmt.Sprintf("-- Description: %s\n-- Author: %s\n-- Date: %s\n\n",
+		comment, CurrentUser(), now.Format("2006-01-02"))
+}
+
+// CurrentUser returns the name of the user creating a migration, for its header
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// ParseCommentHeader returns the description and author of the header above the
+// Up section of a migration file.
+func ParseCommentHeader(header string) (description, author string) {
+	for _, line := range strings.Split(header, "\n") {
+		line = strings.TrimSpace(line)
+		if value, ok := strings.CutPrefix(line, "-- Description:"); ok {
+			description = strings.TrimSpace(value)
+		} else if value, ok := strings.CutPrefix(line, "-- Author:"); ok {
+			author = strings.TrimSpace(value)
+		}
+	}
+	return description, author
+}
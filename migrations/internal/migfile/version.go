@@ -0,0 +1,29 @@
+package migfile
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Number of later seconds FreeTimestamp tries when the current timestamp is taken
+const maxTimestampRetries = 60
+
+// FreeTimestamp returns the first version timestamp from now on, in one second steps,
+// that no file in dir starts with, so migrations created within the same second
+// (e.g. by two developers) still get distinct versions. It fails with taken when every
+// timestamp it tries is taken.
+func FreeTimestamp(dir string, now time.Time, taken error) (string, error) {
+	for i := 0; i <= maxTimestampRetries; i++ {
+		timestamp := now.Add(time.Duration(i) * time.Second).Format("20060102150405")
+		matches, err := filepath.Glob(filepath.Join(dir, timestamp+"_*"))
+		if err != nil {
+			return "", err
+		}
+		if len(matches) == 0 {
+			return timestamp, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no free timestamp within %d seconds of %s",
+		taken, maxTimestampRetries, now.Format("20060102150405"))
+}
@@ -0,0 +1,109 @@
+// Package naming parses the migration name conventions shared by the database drivers
+package naming
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// alterPrefix starts the name of a migration that changes an existing table
+const alterPrefix = "alter_"
+
+// tableSeparator separates the table from the description of an alter migration whose
+// table isn't known from the migrations, e.g. alter_user_profiles__set_default
+const tableSeparator = "__"
+
+// Alter is a parsed alter_<table>_<description> migration name
+type Alter struct {
+	Table       string // Table to alter
+	Description string // Rest of the name, e.g. add_email
+	Action      string // "add" or "drop" for add_<column> and drop_<column> descriptions
+	Column      string // Column added or dropped, empty for other descriptions
+}
+
+// IsAlter reports whether name follows the alter_<table>_<description> convention
+func IsAlter(name string) bool {
+	return strings.HasPrefix(name, alterPrefix)
+}
+
+// ParseAlter splits an alter_<table>_<description> migration name. The table is the
+// longest leading part of the rest that is in tables, the tables the migrations
+// create. A table no migration creates is separated from the description with a
+// double underscore, or by the add_ or drop_ of a column description. Names that can't
+// be split that way are rejected, since the table would be a guess.
+func ParseAlter(name string, tables map[string]bool) (Alter, error) {
+	rest, ok := strings.CutPrefix(name, alterPrefix)
+	if !ok {
+		return Alter{}, fmt.Errorf("migration name %s does not start with %s", name, alterPrefix)
+	}
+
+	var alter Alter
+	if table, description, ok := strings.Cut(rest, tableSeparator); ok {
+		alter.Table, alter.Description = table, description
+	} else if table, description, ok := knownTable(rest, tables); ok {
+		alter.Table, alter.Description = table, description
+	} else if table, description, ok := columnDescription(rest); ok {
+		alter.Table, alter.Description = table, description
+	} else {
+		return Alter{}, fmt.Errorf("can't tell the table from the description in %s: no migration creates a table it starts with, "+
+			"separate them with a double underscore, e.g. alter_user_profiles__set_default", name)
+	}
+	if alter.Table == "" || alter.Description == "" {
+		return Alter{}, fmt.Errorf("migration name %s needs both a table and a description: alter_<table>_<description>", name)
+	}
+
+	for _, action := range []string{"add", "drop"} {
+		if column, ok := strings.CutPrefix(alter.Description, action+"_"); ok && column != "" {
+			alter.Action, alter.Column = action, column
+		}
+	}
+	return alter, nil
+}
+
+// knownTable splits rest after the longest run of leading words that is in tables
+func knownTable(rest string, tables map[string]bool) (string, string, bool) {
+	words := strings.Split(rest, "_")
+	for i := len(words) - 1; i > 0; i-- {
+		if table := strings.Join(words[:i], "_"); tables[table] {
+			return table, strings.Join(words[i:], "_"), true
+		}
+	}
+	return "", "", false
+}
+
+// columnDescription splits rest before the first _add_ or _drop_, whichever comes first
+func columnDescription(rest string) (string, string, bool) {
+	cut := -1
+	for _, marker := range []string{"_add_", "_drop_"} {
+		if i := strings.Index(rest, marker); i > 0 && (cut < 0 || i < cut) {
+			cut = i
+		}
+	}
+	if cut < 0 {
+		return "", "", false
+	}
+	return rest[:cut], rest[cut+1:], true
+}
+
+// ReferenceColumn returns the column referencing a table, using the singular of the
+// table name, e.g. user_id for users.
+func ReferenceColumn(table string) string {
+	table = strings.ToLower(table)
+	switch {
+	case strings.HasSuffix(table, "ies"):
+		table = strings.TrimSuffix(table, "ies") + "y"
+	case strings.HasSuffix(table, "s") && !strings.HasSuffix(table, "ss"):
+		table = strings.TrimSuffix(table, "s")
+	}
+	return table + "_id"
+}
+
+// tablePrefixPattern matches valid table prefixes
+var tablePrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_]*$`)
+
+// ValidTablePrefix reports whether prefix may be used as a table prefix. Prefixes are
+// inlined into SQL and CQL, so only letters, digits and underscores are allowed.
+func ValidTablePrefix(prefix string) bool {
+	return tablePrefixPattern.MatchString(prefix)
+}
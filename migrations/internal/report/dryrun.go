@@ -0,0 +1,45 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/jbarasa/jbmdb/migrations/internal/migfile"
+)
+
+// DryRun prints the SQL or CQL a migration would run, headed by its name and note
+func DryRun(migration fmt.Stringer, sql, note string) {
+	fmt.Printf("%s[DRY RUN]%s %s%s%s%s\n", ColorYellow, ColorReset, ColorCyan, migration, ColorReset, note)
+	fmt.Println(migfile.StripBanners(sql))
+	fmt.Println()
+}
+
+// DryRunMigrate prints the Up SQL or CQL of the migrations that aren't applied yet
+// instead of applying them, and reports applied ones as skipped. applied reports
+// whether a migration is applied, and is nil when no migration is. up returns the Up
+// SQL of a migration and the note to print with it.
+func DryRunMigrate[M fmt.Stringer](migrations []M, applied func(M) (bool, error), up func(M) (string, string)) (Result[M], error) {
+	var result Result[M]
+
+	pending := 0
+	for _, migration := range migrations {
+		if applied != nil {
+			ok, err := applied(migration)
+			if err != nil {
+				return result, err
+			}
+			if ok {
+				Skipped(migration)
+				result.Skipped++
+				continue
+			}
+		}
+
+		sql, note := up(migration)
+		DryRun(migration, sql, note)
+		pending++
+	}
+
+	fmt.Printf("%s[DRY RUN]%s %d migrations would be applied, %d skipped\n",
+		ColorYellow, ColorReset, pending, result.Skipped)
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// isTemplateHeader reports whether the line is part of the header written above the
+// Up section of a generated migration file
+func isTemplateHeader(line string) bool {
+	return strings.HasPrefix(line, "-- Migration:") || line == "-- Up Migration" ||
+		strings.HasPrefix(line, "-----")
+}
+
+// SQLPreview prints the first maxLines non-empty lines of the Up SQL or CQL of a
+// migration, indented
+func SQLPreview(up string, maxLines int) {
+	var lines []string
+	for _, line := range strings.Split(up, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !isTemplateHeader(trimmed) {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+
+	shown := lines
+	if len(shown) > maxLines {
+		shown = shown[:maxLines]
+	}
+	for _, line := range shown {
+		fmt.Printf("    %s%s%s\n", ColorCyan, line, ColorReset)
+	}
+	if len(lines) > len(shown) {
+		fmt.Printf("    ... (%d more lines)\n", len(lines)-len(shown))
+	}
+}
+
+// CommentHeader prints the author and description of a listed migration, if any
+func CommentHeader(author, description string) {
+	if author != "" {
+		fmt.Printf("    Author: %s\n", author)
+	}
+	if description != "" {
+		fmt.Printf("    Description: %s\n", description)
+	}
+}
+
+// ListFilter holds the filters of the list commands
+type ListFilter struct {
+	OnlyPending bool // List only the migrations that aren't applied yet
+	CountOnly   bool // Print just the number of listed migrations as a bare integer
+}
+
+// FilterList returns the migrations the filter lets through, applied reporting whether
+// a migration is applied. With f.CountOnly it prints their number instead and reports
+// that the listing is done.
+func FilterList[M any](f ListFilter, migrations []M, applied func(M) bool) ([]M, bool) {
+	listed := migrations
+	if f.OnlyPending {
+		listed = nil
+		for _, m := range migrations {
+			if !applied(m) {
+				listed = append(listed, m)
+			}
+		}
+	}
+	if f.CountOnly {
+		fmt.Println(len(listed))
+		return nil, true
+	}
+	return listed, false
+}
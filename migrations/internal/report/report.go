@@ -0,0 +1,54 @@
+// Package report prints the progress and outcome of migration runs the same way for
+// every database driver.
+package report
+
+import (
+	"fmt"
+	"time"
+)
+
+// Color constants for terminal output, the same as those of the driver packages
+const (
+	ColorRed    = "\033[31m"
+	ColorGreen  = "\033[32m"
+	ColorBlue   = "\033[34m"
+	ColorCyan   = "\033[36m"
+	ColorReset  = "\033[0m"
+	ColorYellow = "\033[33m"
+)
+
+// Result summarizes a migration run. M is the migration type of the driver, whose
+// String method returns the <version>_<name> of a migration.
+type Result[M fmt.Stringer] struct {
+	Applied          int           // Number of migrations applied in this run
+	Skipped          int           // Number of migrations that were already applied
+	TotalDuration    time.Duration // Wall time of the whole run
+	SlowestMigration M             // The applied migration that took the longest
+	SlowestDuration  time.Duration // How long SlowestMigration took to apply
+}
+
+// Record counts a migration that was applied in the given time
+func (r *Result[M]) Record(migration M, duration time.Duration) {
+	r.Applied++
+	if duration > r.SlowestDuration {
+		r.SlowestMigration = migration
+		r.SlowestDuration = duration
+	}
+}
+
+// Print prints the footer summarizing the run
+func (r *Result[M]) Print() {
+	fmt.Printf("%s[DONE]%s Applied %d migrations in %.2fs.",
+		ColorGreen, ColorReset, r.Applied, r.TotalDuration.Seconds())
+	if r.Applied > 0 {
+		fmt.Printf(" Slowest: %s%s%s (%.2fs)",
+			ColorCyan, r.SlowestMigration, ColorReset, r.SlowestDuration.Seconds())
+	}
+	fmt.Println()
+}
+
+// Skipped prints that a migration is skipped because it's already applied
+func Skipped(migration fmt.Stringer) {
+	fmt.Printf("%s[SKIPPED]%s Migration %s%s%s already applied\n",
+		ColorYellow, ColorReset, ColorCyan, migration, ColorReset)
+}
@@ -7,20 +7,24 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/gocql/gocql"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jbarasa/jbmdb/migrations/config"
 	"github.com/jbarasa/jbmdb/migrations/cql"
 	"github.com/jbarasa/jbmdb/migrations/mysql"
 	"github.com/jbarasa/jbmdb/migrations/postgres"
 	"github.com/jbarasa/jbmdb/migrations/update"
+	"golang.org/x/term"
 )
 
 const (
@@ -42,6 +46,112 @@ const (
 // Version is set during build time
 var Version = "dev"
 
+// Command flags. Flags may appear anywhere after the command,
+// e.g. jbmdb postgres-migration-publication orders_pub --tables orders,customers
+var (
+	tablesFlag    = flag.String("tables", "", "Comma-separated list of tables")
+	configFlag    = flag.String("config", "", "Path to the config file to use")
+	globalFlag    = flag.Bool("global", false, "Use the global config at ~/.jbmdb/config.json")
+	configDirFlag = flag.String("config-dir", "", "Directory of the .jbmdb.conf to use instead of searching parent directories")
+
+	noTransactionFlag = flag.Bool("no-transaction", false, "Run every migration without a transaction")
+
+	outputDirFlag = flag.String("output-dir", "", "Directory to write exported migrations to")
+	sourceFlag    = flag.String("source", "", "Directory to import migrations from")
+
+	onDeleteFlag = flag.String("on-delete", "CASCADE", "Foreign key ON DELETE action")
+	onUpdateFlag = flag.String("on-update", "CASCADE", "Foreign key ON UPDATE action")
+
+	timeoutFlag = flag.Int("timeout", 60, "Timeout in seconds")
+
+	locationFlag   = flag.String("location", "", "Directory for a new tablespace")
+	tablespaceFlag = flag.String("tablespace", "", "Tablespace to create the table in")
+	primaryKeyFlag = flag.String("primary-key", "bigserial", "Type of the id column of a new table: bigserial, uuid, int or none")
+
+	loginFlag     = flag.Bool("login", false, "Allow the new role to log in")
+	superuserFlag = flag.Bool("superuser", false, "Make the new role a superuser")
+	inheritFlag   = flag.Bool("inherit", true, "Let members of the new role use its privileges without SET ROLE")
+
+	fromEnvFlag = flag.Bool("from-env", false, "Import settings from JBMDB_<TYPE>_<FIELD> environment variables")
+	outputFlag  = flag.String("output", "", "Path of the file to write")
+
+	typeFlag        = flag.String("type", "", "Database type: postgres, mysql or cql")
+	showSecretsFlag = flag.Bool("show-secrets", false, "Show passwords instead of masking them")
+
+	parallelFlag = flag.Int("parallel", 1, "Number of independent migrations to apply concurrently")
+
+	noAutoUpdateFlag = flag.Bool("no-auto-update", false, "Don't install updates on startup even if auto_update_on_startup is set")
+
+	confirmFlag  = flag.Bool("confirm", false, "Skip the confirmation prompt of a fresh migration or table drop")
+	cascadeFlag  = flag.Bool("cascade", false, "Also drop objects that depend on the table")
+	untrackFlag  = flag.Bool("untrack", false, "Also remove the migration records of a dropped table")
+	preserveFlag = flag.String("preserve", "", "Comma-separated tables a fresh migration keeps")
+	snapshotFlag = flag.Bool("snapshot", false, "Snapshot the keyspace with nodetool before a CQL fresh migration")
+
+	tablePrefixFlag = flag.String("table-prefix", "", "Prefix of the tables this application owns, overrides table_prefix from the config")
+
+	orderFlag = flag.String("order", "asc", "Order of listed migrations: asc or desc")
+	limitFlag = flag.Int("limit", 0, "Show at most this many migrations, 0 for all")
+
+	showSQLFlag     = flag.Bool("show-sql", false, "Preview the Up SQL of each listed migration")
+	sqlMaxLinesFlag = flag.Int("sql-max-lines", 5, "Number of lines shown by --show-sql")
+	verboseFlag     = flag.Bool("verbose", false, "Show the author and description of each listed migration")
+	formatFlag      = flag.String("format", "", "Output format of postgres-list: table draws borders around the columns")
+	onlyPendingFlag = flag.Bool("only-pending", false, "List only the migrations that aren't applied yet")
+	countFlag       = flag.Bool("count", false, "Print only the number of listed migrations")
+
+	commentFlag = flag.String("comment", "", "Description written into the header of a new migration file")
+
+	dryRunFlag = flag.Bool("dry-run", false, "Print the SQL/CQL migrate, rollback and fresh would run without executing it")
+
+	skipChecksumFlag = flag.Bool("skip-checksum", false, "Migrate even if applied migration files changed since they were applied")
+
+	templateFlag = flag.String("template", "", "Template of the postgres template_dir to generate the migration from")
+
+	kindFlag = flag.String("kind", "", "Comma-separated statistics kinds: ndistinct, dependencies, mcv (default all)")
+
+	withVirtualColumnFlag = flag.String("with-virtual-column", "", "JSON path a new MySQL JSON column gets a virtual column for, followed by its name")
+
+	versionFlag = flag.String("version", "", "Migration version a checksum recompute is limited to")
+	allFlag     = flag.Bool("all", false, "Recompute the checksums of all applied migrations")
+
+	languageFlag = flag.String("language", "english", "Text search configuration of a PostgreSQL search_vector column")
+
+	parserFlag = flag.String("parser", "default", "Full-text parser: ngram, mecab or default")
+	modeFlag   = flag.String("mode", "natural", "Full-text search mode: boolean, natural or query-expansion")
+
+	sslCAFlag   = flag.String("ssl-ca", "", "CA certificate for MySQL TLS, overrides tls_ca_file from the config")
+	sslCertFlag = flag.String("ssl-cert", "", "Client certificate for MySQL TLS, overrides tls_cert_file from the config")
+	sslKeyFlag  = flag.String("ssl-key", "", "Client key for MySQL TLS, overrides tls_key_file from the config")
+
+	partitionKeyFlag    = flag.String("partition-key", "", "Comma-separated <name:type> partition key columns of a new CQL table")
+	clusteringKeyFlag   = flag.String("clustering-key", "", "Comma-separated <name:type> clustering columns of a new CQL table")
+	clusteringOrderFlag = flag.String("clustering-order", "", "Comma-separated <name:asc|desc> clustering order of a new CQL table")
+
+	compactionStrategyFlag = flag.String("compaction-strategy", "", "Compaction strategy of a new CQL table: lcs, stcs or twcs")
+	twcsWindowUnitFlag     = flag.String("twcs-window-unit", "", "Time window unit of twcs compaction: hours or days")
+	twcsWindowSizeFlag     = flag.Int("twcs-window-size", 0, "Number of units per twcs compaction window")
+
+	protoVersionFlag           = flag.Int("proto-version", 0, "CQL native protocol version, overrides proto_version from the config")
+	waitForSchemaAgreementFlag = flag.Bool("wait-for-schema-agreement", false, "Wait for schema agreement after each CQL DDL statement")
+
+	interactiveFlag bool
+	excludeFlag     stringList
+	referencesFlag  stringList
+	rangeColumnFlag stringList
+)
+
+func init() {
+	flag.BoolVar(&interactiveFlag, "interactive", false, "Choose which pending migrations to apply")
+	flag.BoolVar(&interactiveFlag, "i", false, "Shorthand for --interactive")
+	flag.Var(&excludeFlag, "exclude", "Glob pattern of migration names to skip (repeatable)")
+	flag.Var(&referencesFlag, "references", "Table a new table gets a <table>_id foreign key to (repeatable)")
+	flag.Var(&rangeColumnFlag, "range-column", "column:range_type a new PostgreSQL table gets with a GiST index (repeatable)")
+}
+
+// args holds the positional command-line arguments, with the command at index 0.
+var args []string
+
 func main() {
 	// Load environment variables
 	// godotenv.Load()
@@ -52,24 +162,40 @@ func main() {
 	}
 
 	// Parse command-line flags
-	flag.Parse()
-	command := flag.Arg(0)
+	parseArgs()
+	command := arg(0)
+
+	// Select the config file before any config is loaded
+	if *configFlag != "" {
+		config.SetConfigPath(*configFlag)
+	} else if *globalFlag {
+		globalPath, err := config.GlobalConfigPath()
+		if err != nil {
+			log.Fatalf("%s%v%s\n", colorRed, err, colorReset)
+		}
+		config.SetConfigPath(globalPath)
+	} else if *configDirFlag != "" {
+		config.SetConfigDir(*configDirFlag)
+	}
 
 	// Handle special commands first
 	switch command {
 	case "config":
-		initConfig()
+		handleConfig()
 		return
 	case "update":
 		handleUpdate()
 		return
+	case "self-uninstall":
+		handleSelfUninstall()
+		return
 	case "version":
 		fmt.Printf("jbmdb version %s\n", Version)
 		return
 	}
 
-	// Split command into db type and action
-	parts := strings.Split(command, "-")
+	// Split command into db type and action; actions may themselves contain dashes
+	parts := strings.SplitN(command, "-", 2)
 	if len(parts) != 2 {
 		showUsage()
 		os.Exit(1)
@@ -92,6 +218,71 @@ func main() {
 	}
 }
 
+// updateCheckTimeout bounds the background update check so an unreachable GitHub
+// never holds up a command.
+const updateCheckTimeout = 2 * time.Second
+
+// autoUpdateTimeout bounds auto_update_on_startup, from the release lookup to the end
+// of the download, which runs before the command.
+const autoUpdateTimeout = 10 * time.Second
+
+// startUpdateCheck checks for a new release in the background when auto_check_updates
+// is enabled in tool, the tool settings of the config the command loaded. It returns a function that prints a notice to stderr
+// after the command, waiting for the check for whatever is left of updateCheckTimeout. With
+// auto_update_on_startup, the update is instead installed right away, unless
+// --no-auto-update is passed. Neither happens with --count, whose output is meant for
+// scripts.
+func startUpdateCheck(tool *config.ToolConfig) func() {
+	noNotice := func() {}
+	if *countFlag || tool == nil || !tool.AutoCheckUpdates || Version == "dev" {
+		return noNotice
+	}
+
+	if tool.AutoUpdateOnStartup && !*noAutoUpdateFlag {
+		autoUpdate()
+		return noNotice
+	}
+
+	start := time.Now()
+	result := make(chan *update.Release, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+		defer cancel()
+
+		// Failures are silent: the check is only a courtesy
+		release, _ := update.NewerRelease(ctx, Version)
+		result <- release
+	}()
+
+	return func() {
+		select {
+		case release := <-result:
+			if release != nil {
+				fmt.Fprintf(os.Stderr, "%s[UPDATE]%s New version %s available. Run \"jbmdb update\" to install\n",
+					colorCyan, colorReset, release.TagName)
+			}
+		case <-time.After(updateCheckTimeout - time.Since(start)):
+		}
+	}
+}
+
+// autoUpdate installs a newer release before running the command. The running
+// command still uses the current binary; the new one is used from the next run.
+func autoUpdate() {
+	ctx, cancel := context.WithTimeout(context.Background(), autoUpdateTimeout)
+	defer cancel()
+
+	release, err := update.NewerRelease(ctx, Version)
+	if err != nil || release == nil {
+		return
+	}
+
+	fmt.Printf("%s[UPDATE]%s Installing %s...\n", colorCyan, colorReset, release.TagName)
+	if err := update.DownloadUpdate(ctx, release); err != nil {
+		fmt.Printf("%s[WARNING]%s Auto-update failed: %v\n", colorYellow, colorReset, err)
+	}
+}
+
 func handlePostgres(action string) {
 	pgConfig, err := config.LoadConfig[config.PostgresConfig]("postgres")
 	if err != nil {
@@ -99,19 +290,43 @@ func handlePostgres(action string) {
 			postgres.ColorRed, err, postgres.ColorReset)
 	}
 
+	printUpdateNotice := startUpdateCheck(config.LoadedToolConfig())
+	defer printUpdateNotice()
+
 	// Set migration path
 	postgres.SetMigrationPath(pgConfig.MigrationPath)
+	if err := postgres.SetTablePrefix(tablePrefix(pgConfig.TablePrefix)); err != nil {
+		log.Fatalf("%sError: %v%s\n", colorRed, err, colorReset)
+	}
+	postgres.SetMigrationComment(*commentFlag)
+	postgres.SetTemplateDir(pgConfig.TemplateDir)
+	postgres.SetTemplate(*templateFlag)
+	if *templateFlag != "" && action != "migration" && action != "migration-materialized-view" {
+		log.Fatalf("%sError: --template only applies to postgres-migration and postgres-migration-materialized-view%s\n",
+			postgres.ColorRed, postgres.ColorReset)
+	}
 
 	// Handle different actions
 	switch {
 	case action == "init":
 		initPostgresConfig()
 		return
+	case action == "template-validate":
+		if err := postgres.ValidateTemplates(); err != nil {
+			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
 	case action == "create-db":
 		if err := postgres.CreateDatabase(pgConfig); err != nil {
 			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
 		}
 		return
+	case action == "drop-db":
+		confirmDropByName("database", pgConfig.DBName)
+		if err := postgres.DropDatabase(pgConfig); err != nil {
+			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
 	case strings.HasPrefix(action, "create-user"):
 		parts := strings.Split(action, ":")
 		if len(parts) != 2 {
@@ -125,11 +340,131 @@ func handlePostgres(action string) {
 	case strings.HasPrefix(action, "rollback"):
 		handlePostgresRollback(action, pgConfig)
 		return
+	case action == "create-tablespace", action == "drop-tablespace":
+		name := requireArg(1, "Tablespace name")
+		if action == "create-tablespace" && *locationFlag == "" {
+			log.Fatalf("%sError: --location is required%s\n", postgres.ColorRed, postgres.ColorReset)
+		}
+
+		conn := connectPostgresSuperuser(pgConfig)
+		defer conn.Close(context.Background())
+
+		if action == "create-tablespace" {
+			err = postgres.CreateTablespace(conn, name, *locationFlag)
+		} else {
+			err = postgres.DropTablespace(conn, name)
+		}
+		if err != nil {
+			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
+	case action == "copy-schema-to":
+		targetDB := requireArg(1, "Target database")
+		if targetDB == pgConfig.DBName {
+			log.Fatalf("%sError: target database must differ from %s%s\n",
+				postgres.ColorRed, pgConfig.DBName, postgres.ColorReset)
+		}
+
+		conn := connectPostgresSuperuser(pgConfig)
+		defer conn.Close(context.Background())
+
+		if err := postgres.CloneSchema(conn, targetDB, pgConfig.User); err != nil {
+			log.Fatalf("%sFailed to copy schema: %v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
+	case action == "create-role":
+		name := requireArg(1, "Role name")
+
+		conn := connectPostgresSuperuser(pgConfig)
+		defer conn.Close(context.Background())
+
+		opts := postgres.RoleOptions{
+			CanLogin:          *loginFlag,
+			Superuser:         *superuserFlag,
+			InheritPrivileges: *inheritFlag,
+		}
+		if err := postgres.CreateRole(conn, name, opts); err != nil {
+			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
+	case action == "grant-role":
+		role := requireArg(1, "Role name")
+		user := requireArg(2, "User name")
+
+		conn := connectPostgresSuperuser(pgConfig)
+		defer conn.Close(context.Background())
+
+		if err := postgres.GrantRole(conn, role, user); err != nil {
+			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
+	case action == "grant-table", action == "revoke-table":
+		table := requireArg(1, "Table name")
+		user := requireArg(2, "User name")
+		privilege := requireArg(3, "Privilege")
+
+		conn := connectPostgresSuperuser(pgConfig)
+		defer conn.Close(context.Background())
+
+		if action == "grant-table" {
+			err = postgres.GrantTablePrivilege(conn, table, user, privilege)
+		} else {
+			err = postgres.RevokeTablePrivilege(conn, table, user, privilege)
+		}
+		if err != nil {
+			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
+	case action == "export-golang-migrate":
+		if *outputDirFlag == "" {
+			log.Fatalf("%sError: --output-dir is required%s\n", postgres.ColorRed, postgres.ColorReset)
+		}
+		srcPath := filepath.Join(pgConfig.MigrationPath, "sql")
+		if err := postgres.ExportToGoMigrate(srcPath, *outputDirFlag); err != nil {
+			log.Fatalf("%sFailed to export migrations: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
+	case action == "export-flyway":
+		if *outputDirFlag == "" {
+			log.Fatalf("%sError: --output-dir is required%s\n", postgres.ColorRed, postgres.ColorReset)
+		}
+		srcPath := filepath.Join(pgConfig.MigrationPath, "sql")
+		if err := postgres.ExportToFlyway(srcPath, *outputDirFlag, pgConfig); err != nil {
+			log.Fatalf("%sFailed to export migrations: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
+	case action == "import-golang-migrate":
+		if *sourceFlag == "" {
+			log.Fatalf("%sError: --source is required%s\n", postgres.ColorRed, postgres.ColorReset)
+		}
+		dstPath := filepath.Join(pgConfig.MigrationPath, "sql")
+		if err := postgres.ImportFromGoMigrate(*sourceFlag, dstPath); err != nil {
+			log.Fatalf("%sFailed to import migrations: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
+	case action == "diff":
+		version1 := parseVersionArg(requireArg(1, "Migration version"))
+		var version2 int64
+		if arg(2) != "" {
+			version2 = parseVersionArg(arg(2))
+		}
+		if err := postgres.DiffMigrations(version1, version2); err != nil {
+			log.Fatalf("%sFailed to diff migrations: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+		return
 	}
 
 	// Connect to database
-	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		pgConfig.User, pgConfig.Password, pgConfig.Host, pgConfig.Port, pgConfig.DBName)
+	dbURL := postgres.ConnString(pgConfig, pgConfig.User, pgConfig.Password, pgConfig.DBName)
+	if *parallelFlag > 1 {
+		// Leave connections for the migration lock and the migrations table besides
+		// the concurrent migrations
+		dbURL += fmt.Sprintf("&pool_max_conns=%d", max(4, *parallelFlag+2))
+	}
 
 	db, err := pgxpool.New(context.Background(), dbURL)
 	if err != nil {
@@ -141,41 +476,313 @@ func handlePostgres(action string) {
 	// Handle other actions
 	switch action {
 	case "migration":
-		if flag.NArg() < 2 {
-			fmt.Printf("%sError: Migration name is required%s\n",
-				postgres.ColorRed, postgres.ColorReset)
-			os.Exit(1)
+		name := requireArg(1, "Migration name")
+		if !postgres.IsColumnMigrationName(name) && !postgres.IsAlterMigrationName(name) {
+			validateMigrationName(name)
+		}
+		opts := postgres.TableOptions{
+			Tablespace:   *tablespaceFlag,
+			PrimaryKey:   *primaryKeyFlag,
+			References:   referencesFlag,
+			RangeColumns: rangeColumnFlag,
+		}
+		if err := postgres.CreateMigration(name, opts); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-materialized-view":
+		name := requireArg(1, "View name")
+		if err := postgres.CreateMaterializedViewMigration(name); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-policy":
+		table := requireArg(1, "Table name")
+		name := requireArg(2, "Policy name")
+		if err := postgres.CreatePolicyMigration(table, name); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-publication":
+		name := requireArg(1, "Publication name")
+		if err := postgres.CreatePublicationMigration(name, splitList(*tablesFlag)); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-unlogged":
+		name := requireArg(1, "Table name")
+		if err := postgres.CreateUnloggedTableMigration(name); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-alter-enum":
+		typeName := requireArg(1, "Enum type name")
+		value := requireArg(2, "New value")
+		if err := postgres.CreateAlterEnumMigration(typeName, value); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-partition-by-range":
+		name := requireArg(1, "Table name")
+		column := requireArg(2, "Partition column")
+		boundaries := splitList(requireArg(3, "Partition boundaries"))
+		if err := postgres.CreateRangePartitionMigration(name, column, boundaries); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-constraint-exclusion":
+		parent := requireArg(1, "Parent table")
+		child := requireArg(2, "Child table")
+		column := requireArg(3, "Partition column")
+		from := requireArg(4, "Lower bound (inclusive)")
+		to := requireArg(5, "Upper bound (exclusive)")
+		if err := postgres.CreateConstraintExclusionMigration(parent, child, column, from, to); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-with-data":
+		name := requireArg(1, "Migration name")
+		if err := postgres.CreateDataMigration(name); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-hypertable":
+		name := requireArg(1, "Table name")
+		timeColumn := requireArg(2, "Time column")
+		if err := postgres.CreateHypertableMigration(name, timeColumn); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-domain":
+		name := requireArg(1, "Domain name")
+		baseType := requireArg(2, "Base type")
+		if err := postgres.CreateDomainMigration(name, baseType); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-range-type":
+		name := requireArg(1, "Range type name")
+		subtype := requireArg(2, "Subtype")
+		if err := postgres.CreateRangeTypeMigration(name, subtype); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-composite-type":
+		name := requireArg(1, "Type name")
+		if err := postgres.CreateCompositeTypeMigration(name); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-check":
+		table := requireArg(1, "Table name")
+		name := requireArg(2, "Constraint name")
+		if err := postgres.CreateCheckConstraintMigration(table, name); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-unique":
+		table := requireArg(1, "Table name")
+		columns := splitList(requireArg(2, "Columns"))
+		if err := postgres.CreateUniqueConstraintMigration(table, columns); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-generated":
+		table := requireArg(1, "Table name")
+		column := requireArg(2, "Column name")
+		expression := requireArg(3, "Expression")
+		columnType := requireArg(4, "Column type")
+		if err := postgres.CreateGeneratedColumnMigration(table, column, expression, columnType); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-textsearch":
+		table := requireArg(1, "Table name")
+		columns := splitList(requireArg(2, "Columns"))
+		if err := postgres.CreateTextSearchMigration(table, columns, *languageFlag); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-autoupdate":
+		table := requireArg(1, "Table name")
+		if err := postgres.CreateAutoUpdateMigration(table); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-remove-autoupdate":
+		table := requireArg(1, "Table name")
+		if err := postgres.CreateRemoveAutoUpdateMigration(table); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-statistics":
+		name := requireArg(1, "Statistics name")
+		table := requireArg(2, "Table name")
+		columns := splitList(requireArg(3, "Columns"))
+		if err := postgres.CreateStatisticsMigration(name, table, columns, splitList(*kindFlag)); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "migration-foreign-key":
+		fromTable := requireArg(1, "From table")
+		fromColumn := requireArg(2, "From column")
+		toTable := requireArg(3, "To table")
+		if err := postgres.CreateForeignKeyMigration(fromTable, fromColumn, toTable, *onDeleteFlag, *onUpdateFlag); err != nil {
+			log.Fatalf("%sFailed to create migration: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
 		}
-		name := flag.Arg(1)
-		validateMigrationName(name)
-		if err := postgres.CreateMigration(name); err != nil {
+
+	case "migration-subscription":
+		name := requireArg(1, "Subscription name")
+		if err := postgres.CreateSubscriptionMigration(name); err != nil {
 			log.Fatalf("%sFailed to create migration: %v%s\n",
 				postgres.ColorRed, err, postgres.ColorReset)
 		}
 
+	case "drop-table":
+		table := requireArg(1, "Table name")
+		if !*confirmFlag {
+			confirmDropByName("table", table)
+		}
+		if err := postgres.DropTable(db, table, *cascadeFlag); err != nil {
+			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "list-tablespaces":
+		if err := postgres.ListTablespaces(db); err != nil {
+			log.Fatalf("%sFailed to list tablespaces: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "list-roles":
+		if err := postgres.ListRoles(db); err != nil {
+			log.Fatalf("%sFailed to list roles: %v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "list-policies":
+		if err := postgres.ListPolicies(db); err != nil {
+			log.Fatalf("%sFailed to list policies: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "refresh-view":
+		name := requireArg(1, "View name")
+		if err := postgres.RefreshMaterializedView(db, name); err != nil {
+			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+		fmt.Printf("%sMaterialized view '%s' refreshed successfully%s\n",
+			postgres.ColorGreen, name, postgres.ColorReset)
+
 	case "migrate":
-		if err := postgres.Migrate(db); err != nil {
+		if *noTransactionFlag {
+			fmt.Printf("%s[WARN]%s Running all migrations without transactions — partial failures will not be automatically rolled back\n",
+				postgres.ColorYellow, postgres.ColorReset)
+			postgres.SetNoTransaction(true)
+		}
+		postgres.SetParallelism(*parallelFlag)
+		postgres.SetExcludePatterns(excludeFlag)
+		postgres.SetSkipChecksum(*skipChecksumFlag)
+		postgres.SetCaptureWALPosition(pgConfig.CaptureWALPosition)
+		if interactiveFlag && !*dryRunFlag {
+			migrateInteractive(db)
+			return
+		}
+		if _, err := postgres.Migrate(db, *dryRunFlag); err != nil {
 			log.Fatalf("%sFailed to run migrations: %v%s\n",
 				postgres.ColorRed, err, postgres.ColorReset)
 		}
-		fmt.Printf("%sMigrations completed successfully%s\n",
-			postgres.ColorGreen, postgres.ColorReset)
+		if !*dryRunFlag {
+			fmt.Printf("%sMigrations completed successfully%s\n",
+				postgres.ColorGreen, postgres.ColorReset)
+		}
 
 	case "fresh":
-		confirmFreshMigration()
-		if err := postgres.MigrateFresh(db); err != nil {
+		if !*dryRunFlag {
+			confirmFreshMigration()
+		}
+		postgres.SetBackupConfig(pgConfig)
+		postgres.SetPreservedTables(splitList(*preserveFlag))
+		if err := postgres.MigrateFresh(db, *dryRunFlag); err != nil {
 			log.Fatalf("%sFailed to run fresh migrations: %v%s\n",
 				postgres.ColorRed, err, postgres.ColorReset)
 		}
-		fmt.Printf("%sFresh migration completed successfully%s\n",
-			postgres.ColorGreen, postgres.ColorReset)
+		if !*dryRunFlag {
+			fmt.Printf("%sFresh migration completed successfully%s\n",
+				postgres.ColorGreen, postgres.ColorReset)
+		}
+
+	case "squash":
+		confirmOperation("Squash", "This will delete all migration files and record a single baseline migration instead.")
+		if err := postgres.Squash(db); err != nil {
+			log.Fatalf("%sFailed to squash migrations: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "recompute-checksums":
+		var versions []int64
+		switch {
+		case *versionFlag != "":
+			versions = append(versions, parseVersionArg(*versionFlag))
+		case !*allFlag:
+			log.Fatalf("%sSpecify --version <v> or --all to recompute checksums%s\n",
+				postgres.ColorRed, postgres.ColorReset)
+		}
+		if err := postgres.RecomputeChecksums(db, versions); err != nil {
+			log.Fatalf("%sFailed to recompute checksums: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
 
 	case "list":
-		if err := postgres.ListMigrations(db); err != nil {
+		postgres.SetDisplayLocation(displayLocation(pgConfig.DisplayTimezone))
+		postgres.SetSQLPreview(sqlPreviewLines())
+		postgres.SetVerbose(*verboseFlag)
+		postgres.SetListFilter(*onlyPendingFlag, *countFlag)
+		if err := postgres.SetListFormat(*formatFlag); err != nil {
+			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+		if err := postgres.ListMigrations(db, *orderFlag, *limitFlag); err != nil {
 			log.Fatalf("%sFailed to list migrations: %v%s\n",
 				postgres.ColorRed, err, postgres.ColorReset)
 		}
 
+	case "status":
+		if err := postgres.Status(db); err != nil {
+			log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "analyze-slowest":
+		if err := postgres.AnalyzeSlowest(db, requirePositiveArg(1, "N")); err != nil {
+			log.Fatalf("%sFailed to analyze migrations: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
+	case "analyze-timeline":
+		postgres.SetDisplayLocation(displayLocation(pgConfig.DisplayTimezone))
+		if err := postgres.AnalyzeTimeline(db); err != nil {
+			log.Fatalf("%sFailed to analyze migrations: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
+
 	default:
 		fmt.Printf("%sError: Unknown command: %s%s\n",
 			postgres.ColorRed, action, postgres.ColorReset)
@@ -183,6 +790,61 @@ func handlePostgres(action string) {
 	}
 }
 
+// migrateInteractive lets the user pick which pending PostgreSQL migrations to apply.
+func migrateInteractive(db *pgxpool.Pool) {
+	pending, err := postgres.PendingMigrations(db)
+	if err != nil {
+		log.Fatalf("%sFailed to load pending migrations: %v%s\n",
+			postgres.ColorRed, err, postgres.ColorReset)
+	}
+	if len(pending) == 0 {
+		fmt.Printf("%sNo pending migrations%s\n", postgres.ColorYellow, postgres.ColorReset)
+		return
+	}
+
+	items := make([]string, len(pending))
+	for i, m := range pending {
+		items[i] = fmt.Sprintf("%d_%s", m.Version, m.Name)
+	}
+
+	selected, err := selectItems("Select migrations to apply", items)
+	if err == errSelectionCancelled {
+		fmt.Printf("%sOperation cancelled%s\n", postgres.ColorYellow, postgres.ColorReset)
+		return
+	}
+	if err != nil {
+		log.Fatalf("%s%v%s\n", postgres.ColorRed, err, postgres.ColorReset)
+	}
+	if len(selected) == 0 {
+		fmt.Printf("%sNo migrations selected%s\n", postgres.ColorYellow, postgres.ColorReset)
+		return
+	}
+
+	versions := make([]int64, len(selected))
+	for i, index := range selected {
+		versions[i] = pending[index].Version
+	}
+
+	if err := postgres.MigrateVersions(db, versions); err != nil {
+		log.Fatalf("%sFailed to run migrations: %v%s\n",
+			postgres.ColorRed, err, postgres.ColorReset)
+	}
+	fmt.Printf("%sMigrations completed successfully%s\n",
+		postgres.ColorGreen, postgres.ColorReset)
+}
+
+// connectPostgresSuperuser opens a connection to the configured database as the superuser.
+func connectPostgresSuperuser(pgConfig *config.PostgresConfig) *pgx.Conn {
+	dbURL := postgres.ConnString(pgConfig, pgConfig.SuperUser, pgConfig.SuperPass, pgConfig.DBName)
+
+	conn, err := pgx.Connect(context.Background(), dbURL)
+	if err != nil {
+		log.Fatalf("%sUnable to connect to PostgreSQL as superuser: %v%s\n",
+			postgres.ColorRed, err, postgres.ColorReset)
+	}
+	return conn
+}
+
 func handlePostgresRollback(action string, pgConfig *config.PostgresConfig) {
 	// Parse rollback steps
 	parts := strings.Split(action, ":")
@@ -202,8 +864,7 @@ func handlePostgresRollback(action string, pgConfig *config.PostgresConfig) {
 	}
 
 	// Connect to database
-	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		pgConfig.User, pgConfig.Password, pgConfig.Host, pgConfig.Port, pgConfig.DBName)
+	dbURL := postgres.ConnString(pgConfig, pgConfig.User, pgConfig.Password, pgConfig.DBName)
 
 	db, err := pgxpool.New(context.Background(), dbURL)
 	if err != nil {
@@ -213,11 +874,14 @@ func handlePostgresRollback(action string, pgConfig *config.PostgresConfig) {
 	defer db.Close()
 
 	// Handle rollback
-	if err := postgres.RollbackSteps(db, steps); err != nil {
+	if err := postgres.RollbackSteps(db, steps, *dryRunFlag); err != nil {
 		log.Fatalf("%sFailed to rollback migrations: %v%s\n",
 			postgres.ColorRed, err, postgres.ColorReset)
 	}
 
+	if *dryRunFlag {
+		return
+	}
 	if steps == -1 {
 		fmt.Printf("%sRolled back all migrations successfully%s\n",
 			postgres.ColorGreen, postgres.ColorReset)
@@ -234,10 +898,37 @@ func handleScylla(action string) {
 			postgres.ColorRed, err, postgres.ColorReset)
 	}
 
+	printUpdateNotice := startUpdateCheck(config.LoadedToolConfig())
+	defer printUpdateNotice()
+	cql.SetMigrationPath(scyllaConfig.MigrationPath)
+	if err := cql.SetTablePrefix(tablePrefix(scyllaConfig.TablePrefix)); err != nil {
+		log.Fatalf("%sError: %v%s\n", colorRed, err, colorReset)
+	}
+	cql.SetMigrationComment(*commentFlag)
+	if *protoVersionFlag != 0 {
+		scyllaConfig.ProtoVersion = *protoVersionFlag
+	}
+
 	switch {
 	case action == "init":
 		initScyllaConfig()
 		return
+	case action == "drop-keyspace":
+		confirmDropByName("keyspace", scyllaConfig.Keyspace)
+		if err := cql.DropKeyspace(scyllaConfig); err != nil {
+			log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
+		}
+		return
+	case action == "migration-batch":
+		if err := cql.CreateBatchMigration(requireArg(1, "Migration name")); err != nil {
+			log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
+		}
+		return
+	case action == "migration-udf":
+		if err := cql.CreateUDFMigration(requireArg(1, "Function name"), requireArg(2, "Language (lua or xwasm)")); err != nil {
+			log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
+		}
+		return
 	case strings.HasPrefix(action, "create-keyspace"):
 		parts := strings.Split(action, ":")
 		if len(parts) != 3 {
@@ -270,15 +961,9 @@ func handleScylla(action string) {
 	}
 
 	// Create CQL session
-	cluster := gocql.NewCluster(scyllaConfig.Hosts...)
-	cluster.Keyspace = scyllaConfig.Keyspace
-	cluster.Consistency = gocql.Quorum
-	cluster.ProtoVersion = 4
-	if scyllaConfig.User != "" {
-		cluster.Authenticator = gocql.PasswordAuthenticator{
-			Username: scyllaConfig.User,
-			Password: scyllaConfig.Password,
-		}
+	cluster, err := cql.NewCluster(scyllaConfig)
+	if err != nil {
+		log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
 	}
 
 	session, err := cluster.CreateSession()
@@ -291,40 +976,106 @@ func handleScylla(action string) {
 	// Handle commands
 	switch action {
 	case "migration":
-		if flag.NArg() < 2 {
-			fmt.Printf("%sError: Migration name is required%s\n",
-				postgres.ColorRed, postgres.ColorReset)
-			os.Exit(1)
+		name := requireArg(1, "Migration name")
+		if !cql.IsAlterMigrationName(name) {
+			validateMigrationName(name)
+		}
+		opts := cql.TableOptions{
+			PartitionKey:    splitList(*partitionKeyFlag),
+			ClusteringKey:   splitList(*clusteringKeyFlag),
+			ClusteringOrder: splitList(*clusteringOrderFlag),
+			References:      referencesFlag,
+
+			CompactionStrategy: *compactionStrategyFlag,
+			TWCSWindowUnit:     *twcsWindowUnitFlag,
+			TWCSWindowSize:     *twcsWindowSizeFlag,
 		}
-		name := flag.Arg(1)
-		validateMigrationName(name)
-		if err := cql.CreateMigration(name); err != nil {
+		if err := cql.CreateMigration(name, opts); err != nil {
 			log.Fatalf("%sFailed to create migration: %v%s\n",
 				postgres.ColorRed, err, postgres.ColorReset)
 		}
 
 	case "migrate":
-		if err := cql.Migrate(session); err != nil {
+		cql.SetExcludePatterns(excludeFlag)
+		cql.SetSkipChecksum(*skipChecksumFlag)
+		cql.SetWaitForSchemaAgreement(*waitForSchemaAgreementFlag,
+			time.Duration(scyllaConfig.SchemaAgreementTimeout)*time.Second)
+		if _, err := cql.Migrate(session, *dryRunFlag); err != nil {
 			log.Fatalf("%sFailed to run migrations: %v%s\n",
 				postgres.ColorRed, err, postgres.ColorReset)
 		}
-		fmt.Printf("%sMigrations completed successfully%s\n",
-			postgres.ColorGreen, postgres.ColorReset)
+		if !*dryRunFlag {
+			fmt.Printf("%sMigrations completed successfully%s\n",
+				postgres.ColorGreen, postgres.ColorReset)
+		}
 
 	case "fresh":
-		confirmFreshMigration()
-		if err := cql.MigrateFresh(session); err != nil {
+		if !*dryRunFlag {
+			confirmFreshMigration()
+		}
+		if *snapshotFlag {
+			cql.SetSnapshotBeforeFresh("")
+		}
+		if err := cql.MigrateFresh(session, *dryRunFlag); err != nil {
 			log.Fatalf("%sFailed to run fresh migrations: %v%s\n",
 				postgres.ColorRed, err, postgres.ColorReset)
 		}
-		fmt.Printf("%sFresh migration completed successfully%s\n",
-			postgres.ColorGreen, postgres.ColorReset)
+		if !*dryRunFlag {
+			fmt.Printf("%sFresh migration completed successfully%s\n",
+				postgres.ColorGreen, postgres.ColorReset)
+		}
+
+	case "create-snapshot":
+		if err := cql.CreateSnapshot(session, requireArg(1, "Snapshot tag")); err != nil {
+			log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
+		}
+
+	case "list-snapshots":
+		if err := cql.ListSnapshots(session); err != nil {
+			log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
+		}
+
+	case "clear-snapshot":
+		if err := cql.ClearSnapshot(requireArg(1, "Snapshot tag")); err != nil {
+			log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
+		}
+
+	case "drop-table":
+		table := requireArg(1, "Keyspace") + "." + requireArg(2, "Table name")
+		if !*confirmFlag {
+			confirmDropByName("table", table)
+		}
+		if err := cql.DropTable(session, table, false); err != nil {
+			log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
+		}
+		if *untrackFlag {
+			removed, err := cql.UntrackTable(session, arg(2))
+			if err != nil {
+				log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
+			}
+			fmt.Printf("%sRemoved %d migration record(s) for '%s'%s\n",
+				cql.ColorGreen, removed, arg(2), cql.ColorReset)
+		}
+
+	case "schema-repair":
+		if err := cql.WaitForSchemaAgreement(session, time.Duration(*timeoutFlag)*time.Second); err != nil {
+			log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
+		}
 
 	case "list":
-		if err := cql.ListMigrations(session); err != nil {
+		cql.SetDisplayLocation(displayLocation(scyllaConfig.DisplayTimezone))
+		cql.SetSQLPreview(sqlPreviewLines())
+		cql.SetVerbose(*verboseFlag)
+		cql.SetListFilter(*onlyPendingFlag, *countFlag)
+		if err := cql.ListMigrations(session, *orderFlag, *limitFlag); err != nil {
 			log.Fatalf("%sFailed to list migrations: %v%s\n",
 				postgres.ColorRed, err, postgres.ColorReset)
 		}
+	case "analyze-slowest":
+		if err := cql.AnalyzeSlowest(session, requirePositiveArg(1, "N")); err != nil {
+			log.Fatalf("%sFailed to analyze migrations: %v%s\n",
+				postgres.ColorRed, err, postgres.ColorReset)
+		}
 
 	default:
 		fmt.Printf("%sError: Unknown command: %s%s\n",
@@ -352,15 +1103,9 @@ func handleScyllaRollback(action string, scyllaConfig *config.ScyllaConfig) {
 	}
 
 	// Create CQL session
-	cluster := gocql.NewCluster(scyllaConfig.Hosts...)
-	cluster.Keyspace = scyllaConfig.Keyspace
-	cluster.Consistency = gocql.Quorum
-	cluster.ProtoVersion = 4
-	if scyllaConfig.User != "" {
-		cluster.Authenticator = gocql.PasswordAuthenticator{
-			Username: scyllaConfig.User,
-			Password: scyllaConfig.Password,
-		}
+	cluster, err := cql.NewCluster(scyllaConfig)
+	if err != nil {
+		log.Fatalf("%s%v%s\n", cql.ColorRed, err, cql.ColorReset)
 	}
 
 	session, err := cluster.CreateSession()
@@ -371,11 +1116,14 @@ func handleScyllaRollback(action string, scyllaConfig *config.ScyllaConfig) {
 	defer session.Close()
 
 	// Handle rollback
-	if err := cql.RollbackSteps(session, steps); err != nil {
+	if err := cql.RollbackSteps(session, steps, *dryRunFlag); err != nil {
 		log.Fatalf("%sFailed to rollback migrations: %v%s\n",
 			postgres.ColorRed, err, postgres.ColorReset)
 	}
 
+	if *dryRunFlag {
+		return
+	}
 	if steps == -1 {
 		fmt.Printf("%sRolled back all migrations successfully%s\n",
 			postgres.ColorGreen, postgres.ColorReset)
@@ -392,6 +1140,30 @@ func handleMySQL(action string) {
 			mysql.ColorRed, err, mysql.ColorReset)
 	}
 
+	printUpdateNotice := startUpdateCheck(config.LoadedToolConfig())
+	defer printUpdateNotice()
+
+	// SSL flags override the config for this run only
+	if *sslCAFlag != "" {
+		myConfig.TLSCAFile = *sslCAFlag
+	}
+	if *sslCertFlag != "" {
+		myConfig.TLSCertFile = *sslCertFlag
+	}
+	if *sslKeyFlag != "" {
+		myConfig.TLSKeyFile = *sslKeyFlag
+	}
+
+	// Set migration path
+	mysql.SetMigrationPath(myConfig.MigrationPath)
+	if err := mysql.SetTablePrefix(tablePrefix(myConfig.TablePrefix)); err != nil {
+		log.Fatalf("%sError: %v%s\n", colorRed, err, colorReset)
+	}
+	mysql.SetMigrationComment(*commentFlag)
+	mysql.SetPtOSCConfig(myConfig)
+	mysql.SetCharsetConfig(myConfig)
+	mysql.SetLockConfig(myConfig)
+
 	switch {
 	case action == "init":
 		initMySQLConfig()
@@ -401,6 +1173,12 @@ func handleMySQL(action string) {
 			log.Fatalf("%s%v%s\n", mysql.ColorRed, err, mysql.ColorReset)
 		}
 		return
+	case action == "drop-db":
+		confirmDropByName("database", myConfig.DBName)
+		if err := mysql.DropDatabase(myConfig); err != nil {
+			log.Fatalf("%s%v%s\n", mysql.ColorRed, err, mysql.ColorReset)
+		}
+		return
 	case strings.HasPrefix(action, "create-user"):
 		parts := strings.Split(action, ":")
 		if len(parts) != 2 {
@@ -414,11 +1192,19 @@ func handleMySQL(action string) {
 	case strings.HasPrefix(action, "rollback"):
 		handleMySQLRollback(action, myConfig)
 		return
+	case action == "export-flyway":
+		if *outputDirFlag == "" {
+			log.Fatalf("%sError: --output-dir is required%s\n", mysql.ColorRed, mysql.ColorReset)
+		}
+		srcPath := filepath.Join(myConfig.MigrationPath, "sql")
+		if err := mysql.ExportToFlyway(srcPath, *outputDirFlag, myConfig); err != nil {
+			log.Fatalf("%sFailed to export migrations: %v%s\n", mysql.ColorRed, err, mysql.ColorReset)
+		}
+		return
 	}
 
 	// Connect to database
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?multiStatements=true&parseTime=true",
-		myConfig.User, myConfig.Password, myConfig.Host, myConfig.Port, myConfig.DBName)
+	dsn := mysqlDSN(myConfig)
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
@@ -430,18 +1216,72 @@ func handleMySQL(action string) {
 	// Handle different actions
 	switch action {
 	case "migrate":
-		err = mysql.Migrate(db)
+		mysql.SetCaptureBinlogPosition(myConfig.CaptureBinlogPosition)
+		mysql.SetExcludePatterns(excludeFlag)
+		mysql.SetSkipChecksum(*skipChecksumFlag)
+		_, err = mysql.Migrate(db, *dryRunFlag)
 	case "fresh":
-		err = mysql.MigrateFresh(db)
+		if !*dryRunFlag {
+			confirmFreshMigration()
+		}
+		mysql.SetBackupConfig(myConfig)
+		mysql.SetPreservedTables(splitList(*preserveFlag))
+		err = mysql.MigrateFresh(db, *dryRunFlag)
+	case "squash":
+		confirmOperation("Squash", "This will archive all migration files and record a single baseline migration instead.")
+		err = mysql.Squash(db)
 	case "list":
-		err = mysql.ListMigrations(db)
+		mysql.SetDisplayLocation(displayLocation(myConfig.DisplayTimezone))
+		mysql.SetSQLPreview(sqlPreviewLines())
+		mysql.SetVerbose(*verboseFlag)
+		mysql.SetListFilter(*onlyPendingFlag, *countFlag)
+		err = mysql.ListMigrations(db, *orderFlag, *limitFlag)
+	case "analyze-slowest":
+		err = mysql.AnalyzeSlowest(db, requirePositiveArg(1, "N"))
+	case "list-charsets":
+		err = mysql.ListCharsets(db)
+	case "list-views":
+		err = mysql.ListViews(db)
+	case "drop-table":
+		table := requireArg(1, "Table name")
+		if !*confirmFlag {
+			confirmDropByName("table", table)
+		}
+		err = mysql.DropTable(db, table, false)
 	case "create":
-		name := flag.Arg(1)
+		name := arg(1)
 		if name == "" {
 			log.Fatalf("%sError: Migration name is required%s\n",
 				mysql.ColorRed, mysql.ColorReset)
 		}
-		err = mysql.CreateMigration(name)
+		err = mysql.CreateMigration(name, mysql.TableOptions{References: referencesFlag})
+	case "migration-event":
+		err = mysql.CreateEventMigration(requireArg(1, "Event name"))
+	case "migration-view":
+		err = mysql.CreateViewMigration(requireArg(1, "View name"))
+	case "migration-generated":
+		table := requireArg(1, "Table name")
+		column := requireArg(2, "Column name")
+		expression := requireArg(3, "Expression")
+		columnType := requireArg(4, "Column type")
+		err = mysql.CreateGeneratedColumnMigration(table, column, expression, columnType)
+	case "migration-json":
+		table := requireArg(1, "Table name")
+		column := requireArg(2, "Column name")
+		opts := mysql.JSONColumnOptions{VirtualPath: *withVirtualColumnFlag}
+		if opts.VirtualPath != "" {
+			opts.VirtualAlias = requireArg(3, "Virtual column name")
+		}
+		err = mysql.CreateJSONColumnMigration(table, column, opts)
+	case "migration-foreign-key":
+		fromTable := requireArg(1, "From table")
+		fromColumn := requireArg(2, "From column")
+		toTable := requireArg(3, "To table")
+		err = mysql.CreateForeignKeyMigration(fromTable, fromColumn, toTable, *onDeleteFlag, *onUpdateFlag)
+	case "migration-fulltext":
+		table := requireArg(1, "Table name")
+		opts := mysql.FulltextOptions{Parser: *parserFlag, Mode: *modeFlag}
+		err = mysql.CreateFulltextMigration(table, splitList(requireArg(2, "Columns")), opts)
 	default:
 		showUsage()
 		os.Exit(1)
@@ -452,9 +1292,18 @@ func handleMySQL(action string) {
 	}
 }
 
+// mysqlDSN returns the DSN of the configured database, registering the TLS
+// settings with the driver when the config has any.
+func mysqlDSN(myConfig *config.MySQLConfig) string {
+	dsn, err := mysql.DSN(myConfig)
+	if err != nil {
+		log.Fatalf("%s%v%s\n", mysql.ColorRed, err, mysql.ColorReset)
+	}
+	return dsn
+}
+
 func handleMySQLRollback(action string, myConfig *config.MySQLConfig) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?multiStatements=true&parseTime=true",
-		myConfig.User, myConfig.Password, myConfig.Host, myConfig.Port, myConfig.DBName)
+	dsn := mysqlDSN(myConfig)
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		log.Fatalf("%sError connecting to MySQL: %v%s\n",
@@ -462,15 +1311,19 @@ func handleMySQLRollback(action string, myConfig *config.MySQLConfig) {
 	}
 	defer db.Close()
 
-	if action == "rollback" {
+	steps, ok := mysqlRollbackSteps(action)
+	if !ok {
+		log.Fatalf("%sError: Invalid rollback steps%s\n",
+			mysql.ColorRed, mysql.ColorReset)
+	}
+
+	switch {
+	case action == "rollback" && !*dryRunFlag:
 		err = mysql.RollbackLast(db)
-	} else {
-		steps, err := strconv.Atoi(action[9:])
-		if err != nil {
-			log.Fatalf("%sError: Invalid rollback steps%s\n",
-				mysql.ColorRed, mysql.ColorReset)
-		}
-		err = mysql.RollbackSteps(db, steps)
+	case steps < 0:
+		err = mysql.RollbackAll(db, *dryRunFlag)
+	default:
+		err = mysql.RollbackSteps(db, steps, *dryRunFlag)
 	}
 
 	if err != nil {
@@ -478,6 +1331,23 @@ func handleMySQLRollback(action string, myConfig *config.MySQLConfig) {
 	}
 }
 
+// mysqlRollbackSteps parses the number of migrations a mysql-rollback action rolls back:
+// 1 for "rollback", -1 (all) for "rollback:all" and n for "rollback:<n>".
+func mysqlRollbackSteps(action string) (int, bool) {
+	switch action {
+	case "rollback":
+		return 1, true
+	case "rollback:all":
+		return -1, true
+	}
+
+	steps, err := strconv.Atoi(strings.TrimPrefix(action, "rollback:"))
+	if err != nil || steps < 1 || !strings.HasPrefix(action, "rollback:") {
+		return 0, false
+	}
+	return steps, true
+}
+
 func initMySQLConfig() {
 	myConfig := getMySQLConfig()
 	if err := config.SaveConfig(myConfig, "mysql"); err != nil {
@@ -497,31 +1367,36 @@ func getMySQLConfig() config.MySQLConfig {
 		User:          "root",
 		Password:      "",
 		DBName:        "mysql",
+		Charset:       "utf8mb4",
+		Collation:     "utf8mb4_unicode_ci",
 	}
 
-	existingConfig, err := config.LoadConfig[config.MySQLConfig]("mysql")
+	existingConfig, err := config.LoadFileConfig[config.MySQLConfig]("mysql")
 	if err == nil && existingConfig != nil {
 		defaultConfig = *existingConfig
 	}
 
-	printQuestion(fmt.Sprintf("Host [%s]: ", defaultConfig.Host))
+	printQuestion(fmt.Sprintf("Host [%s]%s: ", defaultConfig.Host, fromEnv("mysql", "host")))
 	host := readInput(defaultConfig.Host)
 
-	printQuestion(fmt.Sprintf("Port [%s]: ", defaultConfig.Port))
+	printQuestion(fmt.Sprintf("Port [%s]%s: ", defaultConfig.Port, fromEnv("mysql", "port")))
 	port := readInput(defaultConfig.Port)
 
-	printQuestion(fmt.Sprintf("Database [%s]: ", defaultConfig.DBName))
+	printQuestion(fmt.Sprintf("Database [%s]%s: ", defaultConfig.DBName, fromEnv("mysql", "dbname")))
 	dbname := readInput(defaultConfig.DBName)
 
-	printQuestion(fmt.Sprintf("User [%s]: ", defaultConfig.User))
+	printQuestion(fmt.Sprintf("User [%s]%s: ", defaultConfig.User, fromEnv("mysql", "user")))
 	user := readInput(defaultConfig.User)
 
-	printQuestion(fmt.Sprintf("Password [%s]: ", maskPassword(defaultConfig.Password)))
+	printQuestion(fmt.Sprintf("Password [%s]%s: ", maskPassword(defaultConfig.Password), fromEnv("mysql", "password")))
 	password := readInput(defaultConfig.Password)
 
-	printQuestion(fmt.Sprintf("Migration Path [%s]: ", defaultConfig.MigrationPath))
+	printQuestion(fmt.Sprintf("Migration Path [%s]%s: ", defaultConfig.MigrationPath, fromEnv("mysql", "migration_path")))
 	migrationPath := readInput(defaultConfig.MigrationPath)
 
+	printQuestion(fmt.Sprintf("TLS Mode (true, skip-verify, preferred, false) [%s]%s: ", defaultString(defaultConfig.TLSMode, "<none>"), fromEnv("mysql", "tls_mode")))
+	tlsMode := readInput(defaultConfig.TLSMode)
+
 	config := defaultConfig
 	config.MigrationPath = migrationPath
 	config.Host = host
@@ -529,6 +1404,20 @@ func getMySQLConfig() config.MySQLConfig {
 	config.User = user
 	config.Password = password
 	config.DBName = dbname
+	config.TLSMode = tlsMode
+
+	if tlsMode == "true" || tlsMode == "skip-verify" {
+		printQuestion(fmt.Sprintf("TLS CA File [%s]%s: ", defaultString(defaultConfig.TLSCAFile, "<none>"), fromEnv("mysql", "tls_ca_file")))
+		config.TLSCAFile = readInput(defaultConfig.TLSCAFile)
+
+		printQuestion(fmt.Sprintf("TLS Client Certificate [%s]%s: ", defaultString(defaultConfig.TLSCertFile, "<none>"), fromEnv("mysql", "tls_cert_file")))
+		config.TLSCertFile = readInput(defaultConfig.TLSCertFile)
+
+		if config.TLSCertFile != "" {
+			printQuestion(fmt.Sprintf("TLS Client Key [%s]%s: ", defaultString(defaultConfig.TLSKeyFile, "<none>"), fromEnv("mysql", "tls_key_file")))
+			config.TLSKeyFile = readInput(defaultConfig.TLSKeyFile)
+		}
+	}
 
 	return config
 }
@@ -544,29 +1433,33 @@ func getPostgresConfig() config.PostgresConfig {
 		DBName:        "postgres",
 	}
 
-	existingConfig, err := config.LoadConfig[config.PostgresConfig]("postgres")
+	existingConfig, err := config.LoadFileConfig[config.PostgresConfig]("postgres")
 	if err == nil && existingConfig != nil {
 		defaultConfig = *existingConfig
 	}
 
-	printQuestion(fmt.Sprintf("Host [%s]: ", defaultConfig.Host))
+	printQuestion(fmt.Sprintf("Host [%s]%s: ", defaultConfig.Host, fromEnv("postgres", "host")))
 	host := readInput(defaultConfig.Host)
 
-	printQuestion(fmt.Sprintf("Port [%s]: ", defaultConfig.Port))
+	printQuestion(fmt.Sprintf("Port [%s]%s: ", defaultConfig.Port, fromEnv("postgres", "port")))
 	port := readInput(defaultConfig.Port)
 
-	printQuestion(fmt.Sprintf("Database [%s]: ", defaultConfig.DBName))
+	printQuestion(fmt.Sprintf("Database [%s]%s: ", defaultConfig.DBName, fromEnv("postgres", "dbname")))
 	dbname := readInput(defaultConfig.DBName)
 
-	printQuestion(fmt.Sprintf("User [%s]: ", defaultConfig.User))
+	printQuestion(fmt.Sprintf("User [%s]%s: ", defaultConfig.User, fromEnv("postgres", "user")))
 	user := readInput(defaultConfig.User)
 
-	printQuestion(fmt.Sprintf("Password [%s]: ", maskPassword(defaultConfig.Password)))
+	printQuestion(fmt.Sprintf("Password [%s]%s: ", maskPassword(defaultConfig.Password), fromEnv("postgres", "password")))
 	password := readInput(defaultConfig.Password)
 
-	printQuestion(fmt.Sprintf("Migration Path [%s]: ", defaultConfig.MigrationPath))
+	printQuestion(fmt.Sprintf("Migration Path [%s]%s: ", defaultConfig.MigrationPath, fromEnv("postgres", "migration_path")))
 	migrationPath := readInput(defaultConfig.MigrationPath)
 
+	sslMode := defaultString(defaultConfig.SSLMode, "disable")
+	printQuestion(fmt.Sprintf("SSL Mode (disable, require, verify-ca, verify-full) [%s]%s: ", sslMode, fromEnv("postgres", "ssl_mode")))
+	sslMode = readInput(sslMode)
+
 	config := defaultConfig
 	config.MigrationPath = migrationPath
 	config.Host = host
@@ -574,6 +1467,22 @@ func getPostgresConfig() config.PostgresConfig {
 	config.User = user
 	config.Password = password
 	config.DBName = dbname
+	config.SSLMode = sslMode
+
+	if sslMode != "disable" {
+		if strings.HasPrefix(sslMode, "verify-") {
+			printQuestion(fmt.Sprintf("SSL Root Certificate [%s]%s: ", defaultString(defaultConfig.SSLRootCert, "<none>"), fromEnv("postgres", "ssl_root_cert")))
+			config.SSLRootCert = readInput(defaultConfig.SSLRootCert)
+		}
+
+		printQuestion(fmt.Sprintf("SSL Client Certificate [%s]%s: ", defaultString(defaultConfig.SSLCert, "<none>"), fromEnv("postgres", "ssl_cert")))
+		config.SSLCert = readInput(defaultConfig.SSLCert)
+
+		if config.SSLCert != "" {
+			printQuestion(fmt.Sprintf("SSL Client Key [%s]%s: ", defaultString(defaultConfig.SSLKey, "<none>"), fromEnv("postgres", "ssl_key")))
+			config.SSLKey = readInput(defaultConfig.SSLKey)
+		}
+	}
 
 	return config
 }
@@ -588,29 +1497,44 @@ func getScyllaConfig() config.ScyllaConfig {
 		Keyspace:      "system",
 	}
 
-	existingConfig, err := config.LoadConfig[config.ScyllaConfig]("cql")
+	existingConfig, err := config.LoadFileConfig[config.ScyllaConfig]("cql")
 	if err == nil && existingConfig != nil {
 		defaultConfig = *existingConfig
 	}
 
-	printQuestion(fmt.Sprintf("Hosts (comma-separated) [%s]: ", strings.Join(defaultConfig.Hosts, ",")))
+	printQuestion(fmt.Sprintf("Hosts (comma-separated) [%s]%s: ", strings.Join(defaultConfig.Hosts, ","), fromEnv("cql", "hosts")))
 	hostsStr := readInput(strings.Join(defaultConfig.Hosts, ","))
 	hosts := strings.Split(hostsStr, ",")
 
-	printQuestion(fmt.Sprintf("Keyspace [%s]: ", defaultConfig.Keyspace))
+	printQuestion(fmt.Sprintf("Keyspace [%s]%s: ", defaultConfig.Keyspace, fromEnv("cql", "keyspace")))
 	keyspace := readInput(defaultConfig.Keyspace)
 
-	printQuestion(fmt.Sprintf("User [%s]: ", defaultString(defaultConfig.User, "<none>")))
+	printQuestion(fmt.Sprintf("User [%s]%s: ", defaultString(defaultConfig.User, "<none>"), fromEnv("cql", "user")))
 	user := readInput(defaultConfig.User)
 
-	printQuestion(fmt.Sprintf("Password [%s]: ", maskPassword(defaultConfig.Password)))
+	printQuestion(fmt.Sprintf("Password [%s]%s: ", maskPassword(defaultConfig.Password), fromEnv("cql", "password")))
 	password := readInput(defaultConfig.Password)
 
-	printQuestion(fmt.Sprintf("Migration Path [%s]: ", defaultConfig.MigrationPath))
+	printQuestion(fmt.Sprintf("Migration Path [%s]%s: ", defaultConfig.MigrationPath, fromEnv("cql", "migration_path")))
 	migrationPath := readInput(defaultConfig.MigrationPath)
 
+	pool := defaultConfig.PoolConfig
+	if pool.NumConns == 0 {
+		pool.NumConns = 2
+	}
+	printQuestion(fmt.Sprintf("Connections per host [%d]: ", pool.NumConns))
+	if numConns, err := strconv.Atoi(readInput(strconv.Itoa(pool.NumConns))); err == nil && numConns > 0 {
+		pool.NumConns = numConns
+	} else {
+		fmt.Printf("%sInvalid number of connections, keeping %d%s\n", colorYellow, pool.NumConns, colorReset)
+	}
+
+	printQuestion(fmt.Sprintf("Only connect to datacenter [%s]: ", defaultString(pool.HostFilter, "<all>")))
+	pool.HostFilter = readInput(pool.HostFilter)
+
 	config := defaultConfig
 	config.MigrationPath = migrationPath
+	config.PoolConfig = pool
 	config.Hosts = hosts
 	config.User = user
 	config.Password = password
@@ -619,6 +1543,15 @@ func getScyllaConfig() config.ScyllaConfig {
 	return config
 }
 
+// fromEnv returns " (overridden by env)" for a wizard prompt whose setting a
+// JBMDB_<TYPE>_<FIELD> environment variable overrides at runtime
+func fromEnv(configType, key string) string {
+	if config.FromEnv(configType, key) {
+		return " (overridden by env)"
+	}
+	return ""
+}
+
 // Helper function to mask password in display
 func maskPassword(password string) string {
 	if password == "" {
@@ -685,17 +1618,87 @@ func handleUpdate() {
 	}
 
 	fmt.Printf("Downloading and installing update...\n")
-	if err := update.DownloadUpdate(release); err != nil {
+	if err := update.DownloadUpdate(context.Background(), release); err != nil {
 		fmt.Printf("%sError installing update: %v%s\n", postgres.ColorRed, err, postgres.ColorReset)
 		os.Exit(1)
 	}
 	fmt.Printf("%sUpdate successful! Please restart jbmdb to use the new version if it doesn't start automatically`%s\n", postgres.ColorGreen, postgres.ColorReset)
 }
 
+// handleSelfUninstall removes the jbmdb binary and, if the user agrees, the global
+// config directory. Local .jbmdb.conf files are only listed, since they usually live
+// in project repositories.
+func handleSelfUninstall() {
+	executable, err := os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	if err != nil {
+		fmt.Printf("%sError locating the jbmdb binary: %v%s\n", colorRed, err, colorReset)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s[WARNING]%s This will remove %s\n", colorRed, colorReset, executable)
+	fmt.Print("Are you sure you want to uninstall jbmdb? (y/N): ")
+	var response string
+	fmt.Scanln(&response)
+	if strings.ToLower(response) != "y" {
+		fmt.Printf("%sUninstall cancelled%s\n", colorYellow, colorReset)
+		return
+	}
+
+	if err := os.Remove(executable); err != nil {
+		fmt.Printf("%sFailed to remove %s: %v%s\n", colorRed, executable, err, colorReset)
+		fmt.Printf("Remove it manually, e.g. with: sudo rm %s\n", executable)
+	} else {
+		fmt.Printf("%sRemoved %s%s\n", colorGreen, executable, colorReset)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		globalDir := filepath.Join(home, ".jbmdb")
+		if _, err := os.Stat(globalDir); err == nil {
+			fmt.Printf("Also remove the global config directory %s? (y/N): ", globalDir)
+			response = ""
+			fmt.Scanln(&response)
+			if strings.ToLower(response) == "y" {
+				if err := os.RemoveAll(globalDir); err != nil {
+					fmt.Printf("%sFailed to remove %s: %v%s\n", colorRed, globalDir, err, colorReset)
+				} else {
+					fmt.Printf("%sRemoved %s%s\n", colorGreen, globalDir, colorReset)
+				}
+			}
+		}
+	}
+
+	// Local configs can hold credentials, so point out the ones jbmdb would have used
+	var localConfigs []string
+	if dir, err := os.Getwd(); err == nil {
+		for {
+			path := filepath.Join(dir, ".jbmdb.conf")
+			if _, err := os.Stat(path); err == nil {
+				localConfigs = append(localConfigs, path)
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	if len(localConfigs) > 0 {
+		fmt.Printf("%s[NOTE]%s Remove these local config files manually if no longer needed:\n", colorYellow, colorReset)
+		for _, path := range localConfigs {
+			fmt.Printf("    %s\n", path)
+		}
+	}
+}
+
 func validateMigrationName(name string) {
 	if !strings.HasPrefix(name, "create_") || !strings.HasSuffix(name, "_table") {
 		fmt.Printf("%sError: Migration name must follow format: create_<name>_table\n", postgres.ColorRed)
-		fmt.Printf("Example: create_users_table, create_post_comments_table%s\n", postgres.ColorReset)
+		fmt.Printf("Example: create_users_table, create_post_comments_table\n")
+		fmt.Printf("Existing tables are changed with alter_<table>_<description>, e.g. alter_users_add_email\n")
+		fmt.Printf("PostgreSQL also accepts add_<column>_to_<table> and remove_<column>_from_<table>%s\n", postgres.ColorReset)
 		os.Exit(1)
 	}
 
@@ -716,8 +1719,130 @@ func validateMigrationName(name string) {
 	}
 }
 
+// parseArgs parses the command-line flags, which may be interleaved with
+// positional arguments, and collects the positional arguments into args.
+func parseArgs() {
+	rest := os.Args[1:]
+	for {
+		flag.CommandLine.Parse(rest)
+		rest = flag.Args()
+		if len(rest) == 0 {
+			return
+		}
+		args = append(args, rest[0])
+		rest = rest[1:]
+	}
+}
+
+// arg returns the positional argument at index i, or an empty string if absent.
+func arg(i int) string {
+	if i >= len(args) {
+		return ""
+	}
+	return args[i]
+}
+
+// requireArg returns the positional argument at index i, exiting with an error
+// naming the missing value when it was not provided.
+func requireArg(i int, name string) string {
+	if arg(i) == "" {
+		fmt.Printf("%sError: %s is required%s\n", postgres.ColorRed, name, postgres.ColorReset)
+		os.Exit(1)
+	}
+	return arg(i)
+}
+
+// requirePositiveArg returns the positional argument at index i as a positive number,
+// exiting with an error if it is missing or invalid.
+func requirePositiveArg(i int, name string) int {
+	n, err := strconv.Atoi(requireArg(i, name))
+	if err != nil || n < 1 {
+		fmt.Printf("%sError: %s must be a positive number%s\n", postgres.ColorRed, name, postgres.ColorReset)
+		os.Exit(1)
+	}
+	return n
+}
+
+// displayLocation resolves the configured display timezone, exiting on invalid names.
+func displayLocation(timezone string) *time.Location {
+	loc, err := config.DisplayLocation(timezone)
+	if err != nil {
+		log.Fatalf("%s%v%s\n", colorRed, err, colorReset)
+	}
+	return loc
+}
+
+// parseVersionArg parses a migration version argument, exiting on invalid input.
+func parseVersionArg(value string) int64 {
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Fatalf("%sInvalid migration version: %s%s\n",
+			postgres.ColorRed, value, postgres.ColorReset)
+	}
+	return version
+}
+
+// sqlPreviewLines returns the number of Up SQL lines the list commands preview,
+// 0 unless --show-sql is given.
+func sqlPreviewLines() int {
+	if !*showSQLFlag {
+		return 0
+	}
+	if *sqlMaxLinesFlag < 1 {
+		log.Fatalf("%s--sql-max-lines must be at least 1%s\n", colorRed, colorReset)
+	}
+	return *sqlMaxLinesFlag
+}
+
+// stringList is a flag that may be given several times, collecting every value
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// tablePrefix returns --table-prefix if given, else the configured prefix
+func tablePrefix(configured string) string {
+	if *tablePrefixFlag != "" {
+		return *tablePrefixFlag
+	}
+	return configured
+}
+
+// splitList splits a comma-separated flag value into trimmed, non-empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// confirmFreshMigration asks before a fresh migration drops all tables. --confirm skips
+// the prompt; without it, a non-interactive stdin is refused rather than read.
 func confirmFreshMigration() {
-	fmt.Printf("%s[WARNING]%s This will drop all tables and reapply all migrations.\n", postgres.ColorRed, postgres.ColorReset)
+	confirmOperation("Fresh migration", "This will drop all tables and reapply all migrations.")
+}
+
+// confirmOperation asks before operation does what warning says, see confirmFreshMigration
+func confirmOperation(operation, warning string) {
+	if *confirmFlag {
+		return
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Printf("%s[ERROR]%s %s requires confirmation. Use --confirm flag in non-interactive mode\n",
+			postgres.ColorRed, postgres.ColorReset, operation)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s[WARNING]%s %s\n", postgres.ColorRed, postgres.ColorReset, warning)
 	fmt.Printf("Are you sure you want to continue? (y/N): ")
 
 	var response string
@@ -729,6 +1854,29 @@ func confirmFreshMigration() {
 	}
 }
 
+// confirmDropByName asks the user to type the name of the database or keyspace
+// about to be dropped and exits unless it matches. Without a terminal to type it in,
+// it exits with an error instead.
+func confirmDropByName(kind, name string) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Printf("%s[ERROR]%s Dropping the %s '%s' requires typing its name. Refusing in non-interactive mode\n",
+			postgres.ColorRed, postgres.ColorReset, kind, name)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s[WARNING]%s This will permanently drop the %s '%s' and all of its data.\n",
+		postgres.ColorRed, postgres.ColorReset, kind, name)
+	fmt.Printf("Type the %s name to confirm deletion: ", kind)
+
+	var response string
+	fmt.Scanln(&response)
+
+	if response != name {
+		fmt.Printf("%sOperation cancelled%s\n", postgres.ColorYellow, postgres.ColorReset)
+		os.Exit(0)
+	}
+}
+
 func showUsage() {
 	fmt.Printf(`
 JBMDB Database Migration Tool
@@ -737,44 +1885,168 @@ Usage: jbmdb <command>
 
 Commands:
     config                Initialize configuration
+    config show           Show the current configuration (passwords masked)
+    config export [--type postgres|mysql|cql] [--show-secrets]  Print settings as shell export statements
+                          (e.g. source <(jbmdb config export --show-secrets))
+    config migrate-format  Upgrade the config file to the current format version
+    config import --from-env [--output path]  Write JBMDB_<TYPE>_<FIELD> environment variables into the config file
     update                Update jbmdb to latest version
+    self-uninstall        Remove the jbmdb binary and optionally ~/.jbmdb
     version               Show version information
 
+Global Flags:
+    --config <path>       Use the given config file instead of .jbmdb.conf
+    --global              Use the global config at ~/.jbmdb/config.json
+                          (e.g. jbmdb config init --global, jbmdb config show --global)
+    --config-dir <path>   Use the .jbmdb.conf in this directory instead of searching
+                          the current and parent directories up to the repository root
+    --table-prefix <p>    Only manage tables named <p>_*, overrides table_prefix from the config
+    --exclude <glob>      Skip migrations whose name matches the pattern when migrating;
+                          may be repeated (e.g. --exclude '*_seed*')
+    --skip-checksum       Migrate even if applied migration files changed since they
+                          were applied (checked against their SHA-256 by default)
+
 PostgreSQL Commands:
     postgres-migration <n>   Create a new PostgreSQL migration
+    postgres-migration <n> --tablespace <name>  Create the table in a specific tablespace
+    postgres-migration <n> --primary-key <bigserial|uuid|int|none>  Type of the id column, none to declare your own key
+    <db>-migration <n> --references <table>  Add a <table>_id foreign key column (repeatable; logical reference in CQL)
+    <db>-migration* <n> --comment <text>  Start the file with a Description/Author/Date header
+    postgres-migration <n> --range-column <col:range_type>  Add a range column with a GiST index (repeatable)
+    postgres-migration add_<column>_to_<table>       Add a column to an existing table
+    postgres-migration remove_<column>_from_<table>  Drop a column from an existing table
+    <db>-migration alter_<table>_<description>  ALTER TABLE migration, alter_<table>_add_<col> and alter_<table>_drop_<col> fill in the column
     postgres-migrate       Run all pending PostgreSQL migrations
+    postgres-migrate --no-transaction  Run every migration outside of a transaction
+    postgres-migrate --parallel N  Apply up to N migrations that share no tables concurrently
+    postgres-migrate --interactive (-i)  Choose which pending PostgreSQL migrations to apply
+    postgres-migrate --dry-run  Print the SQL of pending migrations without running it (also rollback and fresh)
     postgres-rollback      Rollback the last PostgreSQL migration
     postgres-rollback:all  Rollback all PostgreSQL migrations
     postgres-rollback:<n>  Rollback n PostgreSQL migrations
     postgres-fresh         Drop all tables and reapply PostgreSQL migrations
+    postgres-fresh --confirm  Skip the confirmation prompt (required when stdin is not a terminal)
+    postgres-fresh --preserve <t1,t2>  Keep these tables and their data
+    postgres-squash [--confirm]  Replace all applied migrations with a single baseline migration of the current schema
     postgres-list          List all PostgreSQL migrations
+    postgres-list --order desc --limit N  List the N most recent migrations (also for mysql-list and cql-list)
+    postgres-list --show-sql [--sql-max-lines N]  Preview the first N (default 5) lines of each Up migration
+    postgres-list --verbose  Show the author and description from each migration's header
+    postgres-list --format table  Draw box borders around the columns
+    postgres-list --only-pending [--count]  List only unapplied migrations, or print just their number
+    postgres-status        Report tables without a migration, missing tables and missing files; exits 1 on drift
+    postgres-analyze-slowest <n>  Show the n migrations that took longest to apply
+    postgres-analyze-timeline  Chart the time spent applying migrations per month
     postgres-init          Initialize PostgreSQL configuration
     postgres-create-db     Create database if not exists
     postgres-create-user:[read|write|all|admin]  Create user with specified privileges
+    postgres-create-role <name> [--login] [--superuser] [--inherit=false]  Create a role
+    postgres-grant-role <role> <user>  Grant a role to a user
+    postgres-list-roles    List roles with their attributes and memberships
+    postgres-drop-db       Drop the database (asks for the name to confirm)
+    postgres-copy-schema-to <target_db>  Create target_db with the same schema (no data) as the configured database
+    postgres-grant-table <table> <user> <privilege>   Grant a table privilege (SELECT, INSERT, UPDATE, DELETE, TRUNCATE, REFERENCES, TRIGGER, ALL)
+    postgres-revoke-table <table> <user> <privilege>  Revoke a table privilege
+    postgres-create-tablespace <name> --location <path>  Create a tablespace (superuser)
+    postgres-drop-tablespace <name>  Drop an empty tablespace (superuser)
+    postgres-list-tablespaces  List tablespaces with owner and location
+    postgres-drop-table <table> [--cascade] [--confirm]  Drop a single table (asks for the name unless --confirm)
+    postgres-migration-materialized-view <name>  Create a materialized view migration
+    postgres-refresh-view <name>  Refresh a materialized view concurrently
+    postgres-migration-policy <table> <name>  Create a row-level security policy migration
+    postgres-list-policies  List row-level security policies
+    postgres-migration-publication <name> [--tables t1,t2]  Create a logical replication publication migration
+    postgres-migration-subscription <name>  Create a logical replication subscription migration
+    postgres-migration-unlogged <name>  Create an unlogged table migration (no WAL, truncated on crash)
+    postgres-migration-composite-type <name>  Create a composite type migration
+    postgres-migration <name> --template <name>  Generate the migration from <name>.tmpl of template_dir
+    postgres-template-validate  Parse the templates of template_dir and report syntax errors
+    postgres-migration-with-data <name>  Create a migration with schema change, data migration and cleanup sections
+    postgres-migration-hypertable <name> <time_column>  Create a TimescaleDB hypertable migration
+    postgres-migration-domain <name> <base_type>  Create a domain (constrained type) migration
+    postgres-migration-range-type <name> <subtype>  Create a range type migration (e.g. floatrange float8)
+    postgres-migration-partition-by-range <name> <column> <b1,b2,...>  Create a range partitioned table
+                          with a partition between each pair of boundaries (e.g. 2023-01-01,2024-01-01,2025-01-01)
+    postgres-migration-constraint-exclusion <parent> <child> <column> <from> <to>
+                          Create an INHERITS child table with a CHECK constraint and an insert routing trigger (PostgreSQL < 10)
+    postgres-migration-alter-enum <type> <value>  Add a value to an enum type (runs without a transaction, cannot be rolled back)
+    postgres-migration-check <table> <constraint>  Create a CHECK constraint migration
+    postgres-migration-unique <table> <col1,col2>  Create a UNIQUE constraint migration
+    postgres-migration-generated <table> <column> "<expression>" <type>  Add a stored generated column (PostgreSQL 12+)
+    postgres-migration-textsearch <table> <col1,col2> [--language english]
+                          Add a generated search_vector column with a GIN index (PostgreSQL 12+)
+    postgres-migration-autoupdate <table>  Create a trigger migration keeping updated_at current on UPDATE
+    postgres-migration-remove-autoupdate <table>  Create a migration removing that trigger
+    postgres-migration-statistics <name> <table> <col1,col2> [--kind ndistinct,dependencies,mcv]
+                          Create extended statistics on correlated columns
+    postgres-migration-foreign-key <from_table> <from_column> <to_table> [--on-delete action] [--on-update action]
+                          Create a foreign key migration (actions default to CASCADE)
+    postgres-export-golang-migrate --output-dir <path>  Export migrations as golang-migrate up/down files
+    postgres-export-flyway --output-dir <path>  Export migrations as Flyway V/U files with a flyway.conf
+    postgres-import-golang-migrate --source <path>      Import golang-migrate up/down files as migrations
+    postgres-recompute-checksums --version <v> | --all  Store the SHA-256 of edited migration files as their checksum
+    postgres-diff <v1> [v2]  Show the Up SQL diff between two migrations (v1 against its predecessor if v2 is omitted)
 
 MySQL Commands:
     mysql-migration <n>     Create a new MySQL migration
     mysql-migrate         Run all pending MySQL migrations
+    mysql-migrate --dry-run  Print the SQL of pending migrations without running it (also rollback and fresh)
+    mysql-squash [--confirm]  Replace all applied migrations with a baseline of SHOW CREATE TABLE, archiving the old files
+    mysql-analyze-slowest <n>  Show the n migrations that took longest to apply
+    mysql-list-charsets   List the character sets the server supports
+    mysql-list-views      List the views with the start of their definition
+    mysql-export-flyway --output-dir <path>  Export migrations as Flyway V/U files with a flyway.conf
+    mysql-<command> --ssl-ca <path> [--ssl-cert <path> --ssl-key <path>]  Connect over TLS with these files for this run
     mysql-rollback        Rollback the last MySQL migration
     mysql-rollback:all    Rollback all MySQL migrations
     mysql-rollback:<n>    Rollback n MySQL migrations
     mysql-fresh           Drop all tables and reapply MySQL migrations
+    mysql-fresh --preserve <t1,t2>  Keep these tables and their data
+    mysql-drop-table <table> [--confirm]  Drop a single table (asks for the name unless --confirm)
     mysql-list            List all MySQL migrations
     mysql-init            Initialize MySQL configuration
     mysql-create-db       Create database if not exists
     mysql-create-user:[read|write|all|admin]    Create user with specified privileges
+    mysql-drop-db         Drop the database (asks for the name to confirm)
+    mysql-migration-event <name>  Create an Event Scheduler job migration
+    mysql-migration-view <name>  Create a view migration (create_<name>_view)
+    mysql-migration-fulltext <table> <col1,col2> [--parser ngram|mecab|default] [--mode boolean|natural|query-expansion]
+                          Create a FULLTEXT index migration
+    mysql-migration-foreign-key <from_table> <from_column> <to_table> [--on-delete action] [--on-update action]
+                          Create a foreign key migration (actions default to CASCADE)
+    mysql-migration-generated <table> <column> "<expression>" <type>  Add a stored generated column
+    mysql-migration-json <table> <column> [--with-virtual-column <path> <alias>]
+                          Add a validated JSON column, optionally with a virtual column for a path (MySQL 5.7.8+)
 
 CQL Commands (Cassandra/ScyllaDB):
     cql-migration <n>     Create a new CQL migration
+    cql-migration <n> --partition-key <col:type,...> [--clustering-key <col:type,...>] [--clustering-order <col:asc|desc,...>]
+                        Generate the table with these primary key columns instead of id uuid
+    cql-migration <n> --compaction-strategy <lcs|stcs|twcs> [--twcs-window-unit <hours|days> --twcs-window-size <n>]
+                        Set the compaction strategy of the generated table
     cql-migrate         Run all pending CQL migrations
+    cql-migrate --dry-run  Print the CQL of pending migrations without running it (also rollback and fresh)
     cql-rollback        Rollback the last CQL migration
     cql-rollback:all    Rollback all CQL migrations
     cql-rollback:<n>    Rollback n CQL migrations
     cql-fresh           Drop all tables and reapply CQL migrations
+    cql-fresh --confirm Skip the confirmation prompt (required when stdin is not a terminal)
+    cql-fresh --snapshot  Snapshot the keyspace with nodetool first (jbmdb_fresh_<timestamp>)
     cql-list            List all CQL migrations
+    cql-analyze-slowest <n>  Show the n migrations that took longest to apply
     cql-init            Initialize CQL configuration
     cql-create-keyspace:[strategy]:[rf]  Create keyspace with replication
+    cql-drop-keyspace   Drop the keyspace (asks for the name to confirm)
+    cql-create-snapshot <tag>  Snapshot the keyspace with nodetool (prints instructions without nodetool)
+    cql-list-snapshots  List snapshots from system.snapshots (ScyllaDB)
+    cql-clear-snapshot <tag>  Remove a snapshot with nodetool clearsnapshot
+    cql-drop-table <keyspace> <table> [--untrack] [--confirm]  Drop a single table, --untrack also removes its migration records
+    cql-<command> --proto-version N  Use CQL native protocol version N for this run (default from config, 4)
+    cql-migrate --wait-for-schema-agreement  Wait for all nodes to agree on the schema after each DDL statement
+    cql-schema-repair [--timeout N]  Detect schema disagreement and wait up to N seconds (default 60) for it to converge
     cql-create-user:[read|write|all|admin]  Create user with specified privileges
+    cql-migration-batch <name>  Create a migration with a BEGIN BATCH ... APPLY BATCH skeleton
+    cql-migration-udf <name> <lua|xwasm>  Create a migration for a ScyllaDB user-defined function
 
 Current Configuration:
   PostgreSQL migrations: migrations/postgres
@@ -794,6 +2066,118 @@ Replication Strategies (Cassandra/ScyllaDB):
 `)
 }
 
+func handleConfig() {
+	switch arg(1) {
+	case "", "init":
+		if err := initConfig(); err != nil {
+			fmt.Printf("%sError: %v%s\n", colorRed, err, colorReset)
+			os.Exit(1)
+		}
+		fmt.Printf("\n%sConfiguration saved to %s%s\n", colorGreen, config.ConfigPath(), colorReset)
+	case "show":
+		showConfig()
+	case "import":
+		importConfig()
+	case "export":
+		exportConfig()
+	case "migrate-format":
+		from, to, err := config.MigrateFormat()
+		if err != nil {
+			log.Fatalf("%sFailed to migrate config format: %v%s\n", colorRed, err, colorReset)
+		}
+		if from == to {
+			fmt.Printf("%s%s is already at format version %d%s\n", colorGreen, config.ConfigPath(), to, colorReset)
+			return
+		}
+		fmt.Printf("%sMigrated %s from format version %d to %d%s\n", colorGreen, config.ConfigPath(), from, to, colorReset)
+	default:
+		fmt.Printf("%sError: Unknown config command: %s%s\n", colorRed, arg(1), colorReset)
+		os.Exit(1)
+	}
+}
+
+// importConfig writes settings from environment variables into the config file
+func importConfig() {
+	if !*fromEnvFlag {
+		log.Fatalf("%sError: config import requires --from-env%s\n", colorRed, colorReset)
+	}
+	if *outputFlag != "" {
+		config.SetConfigPath(*outputFlag)
+	}
+
+	count, err := config.ImportFromEnv()
+	if err != nil {
+		log.Fatalf("%sFailed to import config: %v%s\n", colorRed, err, colorReset)
+	}
+	if count == 0 {
+		fmt.Printf("%sNo JBMDB_POSTGRES_*, JBMDB_MYSQL_* or JBMDB_CQL_* variables set; nothing imported%s\n",
+			colorYellow, colorReset)
+		return
+	}
+
+	fmt.Printf("%sImported %d settings from the environment into %s%s\n",
+		colorGreen, count, config.ConfigPath(), colorReset)
+}
+
+// exportConfig prints the effective configuration as shell export statements
+func exportConfig() {
+	cfg, err := config.LoadFullConfig()
+	if err != nil {
+		log.Fatalf("%s%v%s\n", colorRed, err, colorReset)
+	}
+
+	// Keep only the requested section
+	selected := *cfg
+	switch *typeFlag {
+	case "":
+	case "postgres":
+		selected = config.JBMDBConfig{Postgres: cfg.Postgres}
+	case "mysql":
+		selected = config.JBMDBConfig{MySQL: cfg.MySQL}
+	case "cql":
+		selected = config.JBMDBConfig{Scylla: cfg.Scylla}
+	default:
+		log.Fatalf("%sError: invalid --type %s (use postgres, mysql or cql)%s\n", colorRed, *typeFlag, colorReset)
+	}
+
+	if err := config.ExportAsEnv(&selected, os.Stdout, *showSecretsFlag); err != nil {
+		log.Fatalf("%sFailed to export config: %v%s\n", colorRed, err, colorReset)
+	}
+}
+
+// showConfig prints the effective configuration as JSON with passwords masked
+func showConfig() {
+	cfg, err := config.LoadFullConfig()
+	if err != nil {
+		log.Fatalf("%s%v%s\n", colorRed, err, colorReset)
+	}
+
+	masked := *cfg
+	if masked.Postgres != nil {
+		pg := *masked.Postgres
+		pg.Password, pg.SuperPass = maskPassword(pg.Password), maskPassword(pg.SuperPass)
+		masked.Postgres = &pg
+	}
+	if masked.MySQL != nil {
+		my := *masked.MySQL
+		my.Password, my.SuperPass = maskPassword(my.Password), maskPassword(my.SuperPass)
+		masked.MySQL = &my
+	}
+	if masked.Scylla != nil {
+		sc := *masked.Scylla
+		sc.Password, sc.SuperPass = maskPassword(sc.Password), maskPassword(sc.SuperPass)
+		masked.Scylla = &sc
+	}
+
+	data, err := json.MarshalIndent(masked, "", "  ")
+	if err != nil {
+		log.Fatalf("%sFailed to format config: %v%s\n", colorRed, err, colorReset)
+	}
+
+	printHeader("Current Configuration")
+	fmt.Println(string(data))
+}
+
 func initConfig() error {
 	printHeader("Database Configuration")
 
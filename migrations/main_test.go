@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestMySQLRollbackSteps(t *testing.T) {
+	tests := []struct {
+		action string
+		steps  int
+		ok     bool
+	}{
+		{action: "rollback", steps: 1, ok: true},
+		{action: "rollback:all", steps: -1, ok: true},
+		{action: "rollback:3", steps: 3, ok: true},
+		{action: "rollback:0"},
+		{action: "rollback:-2"},
+		{action: "rollback:some"},
+		{action: "rollback:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			steps, ok := mysqlRollbackSteps(tt.action)
+			if steps != tt.steps || ok != tt.ok {
+				t.Errorf("mysqlRollbackSteps(%q) = %d, %v, want %d, %v", tt.action, steps, ok, tt.steps, tt.ok)
+			}
+		})
+	}
+}
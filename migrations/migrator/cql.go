@@ -0,0 +1,91 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/jbarasa/jbmdb/migrations/config"
+	"github.com/jbarasa/jbmdb/migrations/cql"
+)
+
+// cqlMigrator implements Migrator for Cassandra and ScyllaDB
+type cqlMigrator struct {
+	cfg     *config.ScyllaConfig
+	session *gocql.Session
+}
+
+// NewCQLMigratorFromConfig connects to the cluster and keyspace described by cfg and
+// returns a Migrator for it. A deadline on ctx bounds the connection attempt.
+func NewCQLMigratorFromConfig(ctx context.Context, cfg *config.ScyllaConfig) (Migrator, error) {
+	if _, err := config.DisplayLocation(cfg.DisplayTimezone); err != nil {
+		return nil, err
+	}
+
+	cluster, err := cql.NewCluster(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		cluster.ConnectTimeout = time.Until(deadline)
+	}
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", cql.ErrConnectionFailed, err)
+	}
+
+	return &cqlMigrator{cfg: cfg, session: session}, nil
+}
+
+// apply sets the package-level cql settings from the config
+func (m *cqlMigrator) apply() error {
+	cql.SetMigrationPath(m.cfg.MigrationPath)
+	if err := cql.SetTablePrefix(m.cfg.TablePrefix); err != nil {
+		return err
+	}
+
+	// Library users have no --wait-for-schema-agreement flag, and a migration that
+	// uses a table created on a node that hasn't seen it yet fails, so always wait
+	cql.SetWaitForSchemaAgreement(true, time.Duration(m.cfg.SchemaAgreementTimeout)*time.Second)
+
+	// Validated by the constructor
+	loc, _ := config.DisplayLocation(m.cfg.DisplayTimezone)
+	cql.SetDisplayLocation(loc)
+	return nil
+}
+
+func (m *cqlMigrator) Migrate() (Result, error) {
+	if err := m.apply(); err != nil {
+		return Result{}, err
+	}
+	result, err := cql.Migrate(m.session, false)
+	return Result{Applied: result.Applied, Skipped: result.Skipped, TotalDuration: result.TotalDuration}, err
+}
+
+func (m *cqlMigrator) Rollback(steps int) error {
+	if err := m.apply(); err != nil {
+		return err
+	}
+	return cql.RollbackSteps(m.session, steps, false)
+}
+
+func (m *cqlMigrator) Fresh() error {
+	if err := m.apply(); err != nil {
+		return err
+	}
+	return cql.MigrateFresh(m.session, false)
+}
+
+func (m *cqlMigrator) List() error {
+	if err := m.apply(); err != nil {
+		return err
+	}
+	return cql.ListMigrations(m.session, "asc", 0)
+}
+
+func (m *cqlMigrator) Close() error {
+	m.session.Close()
+	return nil
+}
@@ -0,0 +1,36 @@
+// Package migrator is the library entry point of jbmdb. It opens a database from a
+// jbmdb config section and exposes the migration commands through one interface
+// for PostgreSQL, MySQL/MariaDB and CQL databases.
+//
+// The driver packages keep run-wide settings such as the migration path in package
+// state, so every Migrator method re-applies its config before running. Migrators
+// for the same database type must not be used concurrently.
+package migrator
+
+import "time"
+
+// Migrator runs migrations against a single database.
+type Migrator interface {
+	// Migrate applies all pending migrations in version order.
+	Migrate() (Result, error)
+
+	// Rollback rolls back the given number of most recently applied migrations.
+	Rollback(steps int) error
+
+	// Fresh drops all tables and reapplies every migration. Unlike the CLI, it
+	// does not ask for confirmation.
+	Fresh() error
+
+	// List prints every migration with its status.
+	List() error
+
+	// Close releases the database connection.
+	Close() error
+}
+
+// Result summarizes a Migrate run.
+type Result struct {
+	Applied       int           // Number of migrations applied in this run
+	Skipped       int           // Number of migrations that were already applied
+	TotalDuration time.Duration // Wall time of the whole run
+}
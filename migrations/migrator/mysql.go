@@ -0,0 +1,91 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jbarasa/jbmdb/migrations/config"
+	"github.com/jbarasa/jbmdb/migrations/mysql"
+)
+
+// mysqlMigrator implements Migrator for MySQL and MariaDB
+type mysqlMigrator struct {
+	cfg *config.MySQLConfig
+	db  *sql.DB
+}
+
+// NewMySQLMigratorFromConfig opens the database described by cfg and returns a
+// Migrator for it. The connection is checked before returning.
+func NewMySQLMigratorFromConfig(ctx context.Context, cfg *config.MySQLConfig) (Migrator, error) {
+	if _, err := config.DisplayLocation(cfg.DisplayTimezone); err != nil {
+		return nil, err
+	}
+
+	dsn, err := mysql.DSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", mysql.ErrConnectionFailed, err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%w: %w", mysql.ErrConnectionFailed, err)
+	}
+
+	return &mysqlMigrator{cfg: cfg, db: db}, nil
+}
+
+// apply sets the package-level mysql settings from the config
+func (m *mysqlMigrator) apply() error {
+	mysql.SetMigrationPath(m.cfg.MigrationPath)
+	if err := mysql.SetTablePrefix(m.cfg.TablePrefix); err != nil {
+		return err
+	}
+	mysql.SetPtOSCConfig(m.cfg)
+	mysql.SetCharsetConfig(m.cfg)
+	mysql.SetLockConfig(m.cfg)
+	mysql.SetBackupConfig(m.cfg)
+	mysql.SetCaptureBinlogPosition(m.cfg.CaptureBinlogPosition)
+
+	// Validated by the constructor
+	loc, _ := config.DisplayLocation(m.cfg.DisplayTimezone)
+	mysql.SetDisplayLocation(loc)
+	return nil
+}
+
+func (m *mysqlMigrator) Migrate() (Result, error) {
+	if err := m.apply(); err != nil {
+		return Result{}, err
+	}
+	result, err := mysql.Migrate(m.db, false)
+	return Result{Applied: result.Applied, Skipped: result.Skipped, TotalDuration: result.TotalDuration}, err
+}
+
+func (m *mysqlMigrator) Rollback(steps int) error {
+	if err := m.apply(); err != nil {
+		return err
+	}
+	return mysql.RollbackSteps(m.db, steps, false)
+}
+
+func (m *mysqlMigrator) Fresh() error {
+	if err := m.apply(); err != nil {
+		return err
+	}
+	return mysql.MigrateFresh(m.db, false)
+}
+
+func (m *mysqlMigrator) List() error {
+	if err := m.apply(); err != nil {
+		return err
+	}
+	return mysql.ListMigrations(m.db, "asc", 0)
+}
+
+func (m *mysqlMigrator) Close() error {
+	return m.db.Close()
+}
@@ -0,0 +1,86 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jbarasa/jbmdb/migrations/config"
+	"github.com/jbarasa/jbmdb/migrations/postgres"
+)
+
+// postgresMigrator implements Migrator for PostgreSQL
+type postgresMigrator struct {
+	cfg *config.PostgresConfig
+	db  *pgxpool.Pool
+}
+
+// NewPostgresMigratorFromConfig opens a connection pool to the database described by
+// cfg and returns a Migrator for it. The connection is checked before returning.
+func NewPostgresMigratorFromConfig(ctx context.Context, cfg *config.PostgresConfig) (Migrator, error) {
+	if _, err := config.DisplayLocation(cfg.DisplayTimezone); err != nil {
+		return nil, err
+	}
+
+	dbURL := postgres.ConnString(cfg, cfg.User, cfg.Password, cfg.DBName)
+
+	db, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", postgres.ErrConnectionFailed, err)
+	}
+	if err := db.Ping(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%w: %w", postgres.ErrConnectionFailed, err)
+	}
+
+	return &postgresMigrator{cfg: cfg, db: db}, nil
+}
+
+// apply sets the package-level postgres settings from the config
+func (m *postgresMigrator) apply() error {
+	postgres.SetMigrationPath(m.cfg.MigrationPath)
+	if err := postgres.SetTablePrefix(m.cfg.TablePrefix); err != nil {
+		return err
+	}
+	postgres.SetBackupConfig(m.cfg)
+	postgres.SetCaptureWALPosition(m.cfg.CaptureWALPosition)
+
+	// Validated by the constructor
+	loc, _ := config.DisplayLocation(m.cfg.DisplayTimezone)
+	postgres.SetDisplayLocation(loc)
+	return nil
+}
+
+func (m *postgresMigrator) Migrate() (Result, error) {
+	if err := m.apply(); err != nil {
+		return Result{}, err
+	}
+	result, err := postgres.Migrate(m.db, false)
+	return Result{Applied: result.Applied, Skipped: result.Skipped, TotalDuration: result.TotalDuration}, err
+}
+
+func (m *postgresMigrator) Rollback(steps int) error {
+	if err := m.apply(); err != nil {
+		return err
+	}
+	return postgres.RollbackSteps(m.db, steps, false)
+}
+
+func (m *postgresMigrator) Fresh() error {
+	if err := m.apply(); err != nil {
+		return err
+	}
+	return postgres.MigrateFresh(m.db, false)
+}
+
+func (m *postgresMigrator) List() error {
+	if err := m.apply(); err != nil {
+		return err
+	}
+	return postgres.ListMigrations(m.db, "asc", 0)
+}
+
+func (m *postgresMigrator) Close() error {
+	m.db.Close()
+	return nil
+}
@@ -0,0 +1,51 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// AnalyzeSlowest prints the n applied migrations that took the longest to apply
+func AnalyzeSlowest(db *sql.DB, n int) error {
+	hasDuration, err := hasDurationColumn(db)
+	if err != nil {
+		return fmt.Errorf("failed to check migrations table: %w", err)
+	}
+	if !hasDuration {
+		fmt.Printf("%sNo migration durations recorded yet, they are recorded from the next migrate on%s\n",
+			ColorYellow, ColorReset)
+		return nil
+	}
+
+	rows, err := db.Query(`
+		SELECT version, name, duration_ms FROM `+migrationsTable+`
+		WHERE duration_ms IS NOT NULL
+		ORDER BY duration_ms DESC, version
+		LIMIT ?
+	`, n)
+	if err != nil {
+		return fmt.Errorf("failed to query migrations table: %w", err)
+	}
+	defer rows.Close()
+
+	fmt.Printf("\n%sSlowest Migrations%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-20s %-45s %s\n", "Version", "Name", "Duration")
+	fmt.Println(strings.Repeat("-", 80))
+
+	for rows.Next() {
+		var version, durationMs int64
+		var name string
+		if err := rows.Scan(&version, &name, &durationMs); err != nil {
+			return fmt.Errorf("failed to scan migration row: %w", err)
+		}
+		fmt.Printf("%-20d %-45s %.3fs\n", version, name, float64(durationMs)/1000)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read migrations table: %w", err)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
+	return nil
+}
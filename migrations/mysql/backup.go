@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jbarasa/jbmdb/migrations/config"
+)
+
+// defaultBackupPath is used when BackupPath is not configured.
+const defaultBackupPath = "backups"
+
+// Configuration used to back up the database before a fresh migration.
+var backupConfig *config.MySQLConfig
+
+// SetBackupConfig sets the configuration MigrateFresh uses to back up the database.
+// The backup only runs when BackupBeforeFresh is enabled.
+func SetBackupConfig(cfg *config.MySQLConfig) {
+	backupConfig = cfg
+}
+
+// backupDatabase dumps the database with mysqldump into a timestamped file under
+// BackupPath. A missing mysqldump binary only prints a warning; a failed dump is
+// returned as an error so that nothing is dropped without a backup.
+func backupDatabase(cfg *config.MySQLConfig) error {
+	mysqldump, err := exec.LookPath("mysqldump")
+	if err != nil {
+		fmt.Printf("%s[WARNING]%s mysqldump not found in PATH, skipping backup\n", ColorYellow, ColorReset)
+		return nil
+	}
+
+	backupPath := cfg.BackupPath
+	if backupPath == "" {
+		backupPath = defaultBackupPath
+	}
+	if err := os.MkdirAll(backupPath, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	filename := filepath.Join(backupPath, fmt.Sprintf("backup_%s.sql", time.Now().Format("20060102_150405")))
+	fmt.Printf("%s[BACKUP]%s Dumping database %s to %s...\n", ColorBlue, ColorReset, cfg.DBName, filename)
+
+	cmd := exec.Command(mysqldump,
+		"--host="+cfg.Host,
+		"--port="+cfg.Port,
+		"--user="+cfg.User,
+		"--single-transaction",
+		"--routines",
+		"--events",
+		"--result-file="+filename,
+		cfg.DBName,
+	)
+	cmd.Env = append(os.Environ(), "MYSQL_PWD="+cfg.Password)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("mysqldump failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	fmt.Printf("%s[BACKUP]%s Database backed up to %s\n", ColorGreen, ColorReset, filename)
+	return nil
+}
@@ -0,0 +1,71 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Whether Migrate records the binary log position before and after the run.
+var captureBinlog bool
+
+// SetCaptureBinlogPosition makes Migrate print the binary log position before and
+// after applying migrations, for point-in-time recovery with binary log replay.
+func SetCaptureBinlogPosition(enabled bool) {
+	captureBinlog = enabled
+}
+
+// GetBinlogPosition returns the current binary log position as "file:position".
+// It returns an empty string when binary logging is disabled. The user needs the
+// REPLICATION CLIENT (or BINLOG MONITOR on MariaDB) privilege.
+func GetBinlogPosition(db *sql.DB) (string, error) {
+	// MySQL 8.4 removed SHOW MASTER STATUS in favour of SHOW BINARY LOG STATUS,
+	// which older servers and MariaDB don't know
+	rows, err := db.Query("SHOW BINARY LOG STATUS")
+	if err != nil {
+		if rows, err = db.Query("SHOW MASTER STATUS"); err != nil {
+			return "", fmt.Errorf("failed to read binary log status: %w", err)
+		}
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return "", rows.Err()
+	}
+
+	// The number of columns differs between versions; File and Position come first
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s:%s", values[0], values[1]), nil
+}
+
+// binlogPosition returns the current binary log position for a migration run,
+// printing a warning instead of failing the run when it can't be read.
+func binlogPosition(db *sql.DB) string {
+	position, err := GetBinlogPosition(db)
+	if err != nil {
+		fmt.Printf("%s[WARNING]%s %v\n", ColorYellow, ColorReset, err)
+		return ""
+	}
+	if position == "" {
+		fmt.Printf("%s[WARNING]%s Binary logging is disabled, no position to capture\n", ColorYellow, ColorReset)
+	}
+	return position
+}
+
+// printBinlogPositions captures the position after a run and prints both positions
+func printBinlogPositions(db *sql.DB, result *MigrateResult) {
+	result.BinlogAfter = binlogPosition(db)
+	fmt.Printf("%s[BINLOG]%s Before: %s, After: %s\n",
+		ColorCyan, ColorReset, result.BinlogBefore, result.BinlogAfter)
+}
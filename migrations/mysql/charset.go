@@ -0,0 +1,82 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jbarasa/jbmdb/migrations/config"
+)
+
+// Defaults for the charset and collation settings of the mysql config section
+const (
+	defaultCharset   = "utf8mb4"
+	defaultCollation = "utf8mb4_unicode_ci"
+)
+
+// Charset and collation written into new CREATE TABLE migrations, see SetCharsetConfig
+var (
+	tableCharset   = defaultCharset
+	tableCollation = defaultCollation
+)
+
+// SetCharsetConfig sets the charset and collation CreateMigration writes into new
+// tables. Empty settings keep utf8mb4 and utf8mb4_unicode_ci.
+func SetCharsetConfig(cfg *config.MySQLConfig) {
+	tableCharset, tableCollation = charsetOf(cfg)
+}
+
+// charsetOf returns the configured charset and collation, with defaults for empty values
+func charsetOf(cfg *config.MySQLConfig) (string, string) {
+	charset, collation := cfg.Charset, cfg.Collation
+	if charset == "" {
+		charset = defaultCharset
+	}
+	if collation == "" {
+		collation = defaultCollation
+	}
+	return charset, collation
+}
+
+// CharsetDSNParams returns the charset and collation DSN parameters for cfg, e.g.
+// charset=utf8mb4&collation=utf8mb4_unicode_ci
+func CharsetDSNParams(cfg *config.MySQLConfig) string {
+	charset, collation := charsetOf(cfg)
+	return "charset=" + url.QueryEscape(charset) + "&collation=" + url.QueryEscape(collation)
+}
+
+// ListCharsets prints the character sets the server supports, marking the configured one
+func ListCharsets(db *sql.DB) error {
+	rows, err := db.Query("SHOW CHARACTER SET")
+	if err != nil {
+		return fmt.Errorf("failed to query character sets: %w", err)
+	}
+	defer rows.Close()
+
+	// Print header
+	fmt.Printf("\n%sCharacter Sets%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-12s %-28s %-26s %s\n", "Charset", "Description", "Default Collation", "Max Len")
+	fmt.Println(strings.Repeat("-", 80))
+
+	for rows.Next() {
+		var charset, description, collation string
+		var maxLen int
+		if err := rows.Scan(&charset, &description, &collation, &maxLen); err != nil {
+			return fmt.Errorf("failed to scan character set row: %w", err)
+		}
+		color := ""
+		if charset == tableCharset {
+			color = ColorGreen
+		}
+		fmt.Printf("%s%-12s%s %-28s %-26s %d\n", color, charset, ColorReset, description, collation, maxLen)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating character sets: %w", err)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("Configured: %s%s%s (collation %s)\n", ColorGreen, tableCharset, ColorReset, tableCollation)
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jbarasa/jbmdb/migrations/internal/checksum"
+)
+
+// Whether Migrate skips comparing applied migration files with their checksums, see
+// SetSkipChecksum
+var skipChecksum bool
+
+// SetSkipChecksum makes Migrate go ahead even when applied migration files changed
+// since they were applied, for emergencies.
+func SetSkipChecksum(skip bool) {
+	skipChecksum = skip
+}
+
+// fileChecksum returns the hex encoded SHA-256 of the file of a migration
+func fileChecksum(version int64, name string) (string, error) {
+	return checksum.File(filepath.Join(migrationPath, "sql", fmt.Sprintf("%d_%s.sql", version, name)))
+}
+
+// verifyChecksums compares the checksum recorded for each applied migration with the
+// SHA-256 of its file and fails with ErrChecksumMismatch when any file changed. Records
+// from before checksums were recorded and migrations whose file is gone aren't checked.
+func verifyChecksums(db *sql.DB) error {
+	if skipChecksum {
+		fmt.Printf("%s[WARNING]%s Checksums of applied migrations are not verified\n", ColorYellow, ColorReset)
+		return nil
+	}
+
+	rows, err := db.Query("SELECT version, name, checksum FROM " + migrationsTable +
+		" WHERE checksum IS NOT NULL ORDER BY version")
+	if err != nil {
+		return fmt.Errorf("failed to read checksums: %w", err)
+	}
+	defer rows.Close()
+
+	var records []checksum.Record
+	for rows.Next() {
+		var r checksum.Record
+		if err := rows.Scan(&r.Version, &r.Name, &r.Checksum); err != nil {
+			return fmt.Errorf("failed to read checksums: %w", err)
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	return checksum.Verify(records, fileChecksum, ErrChecksumMismatch)
+}
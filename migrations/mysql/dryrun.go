@@ -0,0 +1,36 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jbarasa/jbmdb/migrations/internal/report"
+)
+
+// dryRunMigrate prints the Up SQL of the migrations that aren't applied yet instead of
+// applying them, and reports applied ones as skipped. Nothing is written, so a missing
+// migrations table means no migration is applied, as does fresh, for which the
+// migrations table would be dropped first.
+func dryRunMigrate(db *sql.DB, migrations []Migration, fresh bool) (MigrateResult, error) {
+	var result MigrateResult
+
+	exists := false
+	if !fresh {
+		if err := db.QueryRow(`SELECT COUNT(*) > 0 FROM information_schema.tables
+			WHERE table_schema = DATABASE() AND table_name = ?`, migrationsTable).Scan(&exists); err != nil {
+			return result, fmt.Errorf("failed to check migrations table: %w", err)
+		}
+	}
+
+	var applied func(Migration) (bool, error)
+	if exists {
+		applied = func(migration Migration) (bool, error) {
+			return isMigrationApplied(db, migration.Version)
+		}
+	}
+	var err error
+	result.Result, err = report.DryRunMigrate(migrations, applied, func(migration Migration) (string, string) {
+		return migration.UpSQL, ""
+	})
+	return result, err
+}
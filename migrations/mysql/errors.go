@@ -0,0 +1,25 @@
+package mysql
+
+import "errors"
+
+// Sentinel errors returned by the mysql package. They are wrapped with context, so
+// test for them with errors.Is, e.g. errors.Is(err, mysql.ErrMigrationNotFound).
+var (
+	// ErrMigrationNotFound is returned when a migration version has no file or record
+	ErrMigrationNotFound = errors.New("migration not found")
+
+	// ErrChecksumMismatch is returned when a migration file changed after it was applied
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+	// ErrDuplicateVersion is returned when two migration files share a version
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+
+	// ErrLockTimeout is returned when the migration lock can't be acquired in time
+	ErrLockTimeout = errors.New("timed out waiting for migration lock")
+
+	// ErrConnectionFailed is returned when the database can't be reached
+	ErrConnectionFailed = errors.New("unable to connect to MySQL")
+
+	// ErrInvalidTablePrefix is returned when a table prefix isn't a plain identifier
+	ErrInvalidTablePrefix = errors.New("invalid table prefix")
+)
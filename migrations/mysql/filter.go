@@ -0,0 +1,10 @@
+package mysql
+
+// Glob patterns of migration names skipped by loadMigrations, see SetExcludePatterns
+var excludePatterns []string
+
+// SetExcludePatterns sets the glob patterns (as accepted by path.Match) of migration
+// names to leave out, e.g. "*_seed*".
+func SetExcludePatterns(patterns []string) {
+	excludePatterns = patterns
+}
@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jbarasa/jbmdb/migrations/config"
+)
+
+// migrationLockName is the GET_LOCK name held while Migrate, RollbackLast, RollbackSteps,
+// MigrateFresh and Squash change the database. Named locks are server-wide, so databases on
+// the same server share it.
+const migrationLockName = "jbmdb_migration_lock"
+
+// defaultLockTimeout is the number of seconds to wait for the migration lock when the
+// mysql config section doesn't set lock_timeout
+const defaultLockTimeout = 30
+
+// Seconds lockMigrations waits for another process to release the lock, see SetLockConfig
+var lockTimeout = defaultLockTimeout
+
+// SetLockConfig sets how long migrations wait for the migration lock held by another
+// process. An unset lock_timeout keeps 30 seconds.
+func SetLockConfig(cfg *config.MySQLConfig) {
+	lockTimeout = defaultLockTimeout
+	if cfg.LockTimeout > 0 {
+		lockTimeout = cfg.LockTimeout
+	}
+}
+
+// lockMigrations takes the migration lock, waiting up to lockTimeout seconds for another
+// process to release it. GET_LOCK belongs to the session, so the lock is held on a
+// dedicated connection; call the returned function to release it.
+func lockMigrations(db *sql.DB) (func(), error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection for migration lock: %w", err)
+	}
+
+	// GET_LOCK returns 1 when the lock is taken, 0 on timeout and NULL on errors
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, lockTimeout).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		conn.Close()
+		return nil, fmt.Errorf("%w: another migration process has held %s for over %d seconds",
+			ErrLockTimeout, migrationLockName, lockTimeout)
+	}
+
+	return func() {
+		conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", migrationLockName)
+		conn.Close()
+	}, nil
+}
@@ -5,16 +5,22 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jbarasa/jbmdb/migrations/config"
+	"github.com/jbarasa/jbmdb/migrations/internal/graph"
+	"github.com/jbarasa/jbmdb/migrations/internal/migfile"
+	"github.com/jbarasa/jbmdb/migrations/internal/naming"
+	"github.com/jbarasa/jbmdb/migrations/internal/report"
 )
 
 // Color constants for terminal output
@@ -33,17 +39,21 @@ const (
 // CreateDatabase creates a new database if it doesn't exist
 func CreateDatabase(myConfig *config.MySQLConfig) error {
 	// Connect to MySQL server as super user
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/",
-		myConfig.SuperUser, myConfig.SuperPass, myConfig.Host, myConfig.Port)
+	dsn, err := superuserDSN(myConfig)
+	if err != nil {
+		return err
+	}
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		return fmt.Errorf("error connecting to MySQL: %v", err)
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
 	}
 	defer db.Close()
 
 	// Create database if not exists
-	_, err = db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", myConfig.DBName))
+	charset, collation := charsetOf(myConfig)
+	_, err = db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET %s COLLATE %s",
+		quoteIdentifier(myConfig.DBName), charset, collation))
 	if err != nil {
 		return fmt.Errorf("error creating database: %v", err)
 	}
@@ -54,15 +64,59 @@ func CreateDatabase(myConfig *config.MySQLConfig) error {
 	return nil
 }
 
+// DropDatabase drops the configured database
+func DropDatabase(myConfig *config.MySQLConfig) error {
+	// Connect to MySQL server as super user
+	dsn, err := superuserDSN(myConfig)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("DROP DATABASE IF EXISTS " + quoteIdentifier(myConfig.DBName))
+	if err != nil {
+		return fmt.Errorf("error dropping database: %v", err)
+	}
+
+	fmt.Printf("%sDatabase '%s' dropped successfully%s\n",
+		ColorGreen, myConfig.DBName, ColorReset)
+
+	return nil
+}
+
+// DropTable drops a single table outside of the migration flow. MySQL accepts
+// CASCADE for compatibility but ignores it, so referencing foreign keys must be
+// dropped first.
+func DropTable(db *sql.DB, table string, cascade bool) error {
+	stmt := "DROP TABLE IF EXISTS " + quoteIdentifier(table)
+	if cascade {
+		stmt += " CASCADE"
+	}
+
+	if _, err := db.Exec(stmt); err != nil {
+		return fmt.Errorf("error dropping table %s: %w", table, err)
+	}
+
+	fmt.Printf("%sTable '%s' dropped successfully%s\n", ColorGreen, table, ColorReset)
+	return nil
+}
+
 // CreateUser creates a new user if it doesn't exist and grants privileges
 func CreateUser(myConfig *config.MySQLConfig, privileges string) error {
 	// Connect to MySQL server as super user
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/",
-		myConfig.SuperUser, myConfig.SuperPass, myConfig.Host, myConfig.Port)
+	dsn, err := superuserDSN(myConfig)
+	if err != nil {
+		return err
+	}
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		return fmt.Errorf("error connecting to MySQL: %v", err)
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
 	}
 	defer db.Close()
 
@@ -82,16 +136,16 @@ func CreateUser(myConfig *config.MySQLConfig, privileges string) error {
 	switch privileges {
 	case "all":
 		grantCmd = fmt.Sprintf("GRANT ALL PRIVILEGES ON %s.* TO '%s'@'%%'",
-			myConfig.DBName, myConfig.User)
+			quoteIdentifier(myConfig.DBName), myConfig.User)
 	case "read":
 		grantCmd = fmt.Sprintf("GRANT SELECT ON %s.* TO '%s'@'%%'",
-			myConfig.DBName, myConfig.User)
+			quoteIdentifier(myConfig.DBName), myConfig.User)
 	case "write":
 		grantCmd = fmt.Sprintf("GRANT SELECT, INSERT, UPDATE, DELETE ON %s.* TO '%s'@'%%'",
-			myConfig.DBName, myConfig.User)
+			quoteIdentifier(myConfig.DBName), myConfig.User)
 	case "admin":
 		grantCmd = fmt.Sprintf("GRANT ALL PRIVILEGES ON %s.* TO '%s'@'%%' WITH GRANT OPTION",
-			myConfig.DBName, myConfig.User)
+			quoteIdentifier(myConfig.DBName), myConfig.User)
 	default:
 		return fmt.Errorf("invalid privilege level: %s", privileges)
 	}
@@ -120,6 +174,21 @@ type Migration struct {
 	Name    string // Name of the migration
 	UpSQL   string // SQL script for applying the migration
 	DownSQL string // SQL script for rolling back the migration
+
+	Description string // Description from the header of the migration file
+	Author      string // Author from the header of the migration file
+}
+
+// String returns the <version>_<name> of the migration
+func (m Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// MigrateResult summarizes a migration run.
+type MigrateResult struct {
+	report.Result[Migration]
+	BinlogBefore string // Binary log position before the run, when captured
+	BinlogAfter  string // Binary log position after the run, when captured
 }
 
 // Path to the migration files
@@ -130,6 +199,77 @@ func SetMigrationPath(path string) {
 	migrationPath = path
 }
 
+// Prefix of the tables this application owns, including the trailing underscore, and
+// the table applied migrations are recorded in. See SetTablePrefix.
+var (
+	tablePrefix     string
+	migrationsTable = "migrations"
+)
+
+// SetTablePrefix scopes jbmdb to the tables starting with prefix, for applications
+// sharing a database: new tables are created as <prefix>_<name>, fresh migrations only
+// drop prefixed tables and migrations are recorded in <prefix>_migrations. The prefix
+// is inlined into queries, so it fails with ErrInvalidTablePrefix unless the prefix has
+// only letters, digits and underscores.
+func SetTablePrefix(prefix string) error {
+	if !naming.ValidTablePrefix(prefix) {
+		return fmt.Errorf("%w %q: only letters, digits and underscores are allowed", ErrInvalidTablePrefix, prefix)
+	}
+
+	tablePrefix = ""
+	if prefix = strings.TrimSuffix(prefix, "_"); prefix != "" {
+		tablePrefix = prefix + "_"
+	}
+	migrationsTable = tablePrefix + "migrations"
+	return nil
+}
+
+// prefixedTable returns the table name with the table prefix, unless it already has it
+func prefixedTable(name string) string {
+	if strings.HasPrefix(name, tablePrefix) {
+		return name
+	}
+	return tablePrefix + name
+}
+
+// Location used to display timestamps in ListMigrations.
+var displayLocation = time.Local
+
+// SetDisplayLocation sets the timezone used to display applied_at timestamps
+func SetDisplayLocation(loc *time.Location) {
+	displayLocation = loc
+}
+
+// IsAlterMigrationName reports whether name follows the alter_<table>_<description>
+// naming convention.
+func IsAlterMigrationName(name string) bool {
+	return naming.IsAlter(name)
+}
+
+// migrationTables returns the tables the migrations create, named as in the migration
+// names, which alter migration names are matched against
+func migrationTables() (map[string]bool, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	tables := make(map[string]bool, len(migrations))
+	for _, migration := range migrations {
+		tables[strings.ToLower(extractTableName(migration.Name))] = true
+	}
+	return tables, nil
+}
+
+// parseAlterMigrationName parses an alter_<table>_<description> migration name against
+// the tables of the migrations, see naming.ParseAlter
+func parseAlterMigrationName(name string) (naming.Alter, error) {
+	tables, err := migrationTables()
+	if err != nil {
+		return naming.Alter{}, err
+	}
+	return naming.ParseAlter(name, tables)
+}
+
 // extractTableName extracts the table name from the migration name
 func extractTableName(name string) string {
 	name = strings.TrimPrefix(name, "create_")
@@ -163,12 +303,30 @@ func checkDuplicateTableName(newTableName string) error {
 			return fmt.Errorf("%stable name '%s' already exists in migration '%d_%s'%s",
 				ColorRed, newTableName, migration.Version, migration.Name, ColorReset)
 		}
+		if strings.EqualFold(extractViewName(migration.Name), newTableName) {
+			return fmt.Errorf("%sname '%s' is already used by a view in migration '%d_%s'%s",
+				ColorRed, newTableName, migration.Version, migration.Name, ColorReset)
+		}
 	}
 	return nil
 }
 
+// TableOptions customizes the CREATE TABLE statement generated by CreateMigration.
+type TableOptions struct {
+	References []string // Tables to add a <table>_id foreign key column for
+}
+
 // CreateMigration creates new migration file with the given name and current timestamp
-func CreateMigration(name string) error {
+func CreateMigration(name string, opts TableOptions) error {
+	// Alter migrations change an existing table instead of creating one
+	if IsAlterMigrationName(name) {
+		alter, err := parseAlterMigrationName(name)
+		if err != nil {
+			return err
+		}
+		return createAlterMigration(name, alter.Action, alter.Column, prefixedTable(alter.Table))
+	}
+
 	// Extract table name from migration name
 	tableName := extractTableName(name)
 
@@ -177,25 +335,73 @@ func CreateMigration(name string) error {
 		return err
 	}
 
-	timestamp := time.Now().Format("20060102150405")
+	// Foreign key columns follow the id, their constraints the timestamps
+	var columns, foreignKeys string
+	table := prefixedTable(strings.ToLower(tableName))
+	for _, referenced := range opts.References {
+		referenced = strings.ToLower(strings.TrimPrefix(referenced, tablePrefix))
+		column := naming.ReferenceColumn(referenced)
+		columns += fmt.Sprintf("    %s BIGINT UNSIGNED NOT NULL,\n", column)
+		foreignKeys += fmt.Sprintf(",\n    CONSTRAINT fk_%s_%s FOREIGN KEY (%s) REFERENCES %s(id) ON DELETE CASCADE",
+			table, column, column, prefixedTable(referenced))
+	}
+
+	up := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+%s    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP%s
+) ENGINE=InnoDB DEFAULT CHARSET=%s COLLATE=%s;`, table, columns, foreignKeys, tableCharset, tableCollation)
+	down := fmt.Sprintf("DROP TABLE IF EXISTS %s;", table)
+
+	return writeMigrationFile(name, up, down)
+}
+
+// createAlterMigration writes an ALTER TABLE migration for an alter_<table>_<description>
+// name. Adding or dropping a column defaults to a TEXT column, other descriptions get a
+// template to fill in.
+func createAlterMigration(name, action, column, table string) error {
+	addColumn := fmt.Sprintf("-- TODO: adjust the column type\nALTER TABLE %s ADD COLUMN %s TEXT;", table, column)
+	dropColumn := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, column)
+
+	switch action {
+	case "add":
+		return writeMigrationFile(name, addColumn, dropColumn)
+	case "drop":
+		return writeMigrationFile(name, dropColumn, addColumn)
+	}
+
+	// No semicolons in the comments, applyMigration splits statements on them
+	up := fmt.Sprintf("-- TODO: alter the table, e.g.\n-- ALTER TABLE %s MODIFY COLUMN name VARCHAR(255) NOT NULL", table)
+	down := fmt.Sprintf("-- TODO: undo the changes to %s", table)
+	return writeMigrationFile(name, up, down)
+}
+
+// writeMigrationFile writes a new timestamped migration file with the given up and down
+// SQL wrapped in the standard sections
+func writeMigrationFile(name, up, down string) error {
+	timestamp, err := migfile.FreeTimestamp(filepath.Join(migrationPath, "sql"), time.Now(), ErrDuplicateVersion)
+	if err != nil {
+		return err
+	}
+	return writeMigrationFileTo(timestamp, name, up, down)
+}
+
+// writeMigrationFileTo writes the migration file with the given version timestamp
+func writeMigrationFileTo(timestamp, name, up, down string) error {
 	filename := fmt.Sprintf("%s_%s.sql", timestamp, name)
 
-	content := fmt.Sprintf(`-- Migration: %s
+	content := migfile.CommentHeader(migrationComment, time.Now()) + fmt.Sprintf(`-- Migration: %s
 
 -- Up Migration
 ----------------------- Write your up migration here ----------------------------
 
-CREATE TABLE IF NOT EXISTS %s (
-    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+%s
 
 
 -- Down Migration
 ----------------------- Write your down migration here ----------------------------
 
-DROP TABLE IF EXISTS %s;`, name, strings.ToLower(tableName), strings.ToLower(tableName))
+%s`, name, up, down)
 
 	filePath := filepath.Join(migrationPath, "sql", filename)
 	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
@@ -213,9 +419,17 @@ DROP TABLE IF EXISTS %s;`, name, strings.ToLower(tableName), strings.ToLower(tab
 
 // loadMigrations loads all migration files from the migration directory
 func loadMigrations() ([]Migration, error) {
+	migrations, err := loadMigrationsFrom(filepath.Join(migrationPath, "sql"))
+	if err != nil {
+		return nil, err
+	}
+	return migfile.Exclude(migrations, func(m Migration) string { return m.Name }, excludePatterns)
+}
+
+// loadMigrationsFrom loads all migration files from sqlDir, sorted by version
+func loadMigrationsFrom(sqlDir string) ([]Migration, error) {
 	var migrations []Migration
 
-	sqlDir := filepath.Join(migrationPath, "sql")
 	files, err := os.ReadDir(sqlDir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -229,7 +443,14 @@ func loadMigrations() ([]Migration, error) {
 			continue
 		}
 
-		version := parseInt(file.Name()[:14])
+		// At least a 14-digit version, an underscore and a name
+		if len(file.Name()) < len("20060102150405_x.sql") {
+			return nil, fmt.Errorf("invalid migration file %s: expected <version>_<name>.sql", file.Name())
+		}
+		version, err := parseInt(file.Name()[:14])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file %s: %w", file.Name(), err)
+		}
 		name := strings.TrimSuffix(file.Name()[15:], ".sql")
 
 		content, err := os.ReadFile(filepath.Join(sqlDir, file.Name()))
@@ -242,14 +463,20 @@ func loadMigrations() ([]Migration, error) {
 			return nil, fmt.Errorf("invalid migration file format %s", file.Name())
 		}
 
-		upSQL := strings.Split(parts[0], "-- Up Migration")[1]
+		header, upSQL, found := strings.Cut(parts[0], "-- Up Migration")
+		if !found {
+			return nil, fmt.Errorf("invalid migration file format %s", file.Name())
+		}
 		downSQL := parts[1]
+		description, author := migfile.ParseCommentHeader(header)
 
 		migrations = append(migrations, Migration{
-			Version: version,
-			Name:    name,
-			UpSQL:   strings.TrimSpace(upSQL),
-			DownSQL: strings.TrimSpace(downSQL),
+			Version:     version,
+			Name:        name,
+			UpSQL:       strings.TrimSpace(upSQL),
+			DownSQL:     strings.TrimSpace(downSQL),
+			Description: description,
+			Author:      author,
 		})
 	}
 
@@ -260,42 +487,97 @@ func loadMigrations() ([]Migration, error) {
 	return migrations, nil
 }
 
-// Migrate applies all pending migrations to the database
-func Migrate(db *sql.DB) error {
+// Migrate applies all pending migrations to the database. With dryRun their SQL is
+// printed instead and the database is left untouched.
+func Migrate(db *sql.DB, dryRun bool) (MigrateResult, error) {
+	if dryRun {
+		migrations, err := loadMigrations()
+		if err != nil {
+			return MigrateResult{}, err
+		}
+		return dryRunMigrate(db, migrations, false)
+	}
+
+	// Make sure no other process applies the same migrations
+	release, err := lockMigrations(db)
+	if err != nil {
+		return MigrateResult{}, err
+	}
+	defer release()
+
+	return migrate(db)
+}
+
+// migrate applies all pending migrations, the caller holding the migration lock
+func migrate(db *sql.DB) (MigrateResult, error) {
+	var result MigrateResult
+	start := time.Now()
+
 	if err := createMigrationsTable(db); err != nil {
-		return err
+		return result, err
+	}
+
+	// Refuse to build on applied migrations whose files were edited since
+	if err := verifyChecksums(db); err != nil {
+		return result, err
 	}
 
 	migrations, err := loadMigrations()
 	if err != nil {
-		return err
+		return result, err
+	}
+
+	preflightCheck(db, migrations)
+
+	if captureBinlog {
+		result.BinlogBefore = binlogPosition(db)
 	}
 
 	for _, migration := range migrations {
 		applied, err := isMigrationApplied(db, migration.Version)
 		if err != nil {
-			return err
+			return result, err
 		}
 
-		if !applied {
-			fmt.Printf("%s[MIGRATE]%s Applying migration %s%d_%s%s... ",
-				ColorBlue, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset)
+		if applied {
+			result.Skipped++
+			continue
+		}
 
-			if err := applyMigration(db, migration); err != nil {
-				fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
-				return fmt.Errorf("failed to apply migration %d_%s: %w",
-					migration.Version, migration.Name, err)
-			}
+		fmt.Printf("%s[MIGRATE]%s Applying migration %s%d_%s%s... ",
+			ColorBlue, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset)
 
-			fmt.Printf("%sOK%s\n", ColorGreen, ColorReset)
+		migrationStart := time.Now()
+		if err := applyMigration(db, migration); err != nil {
+			fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
+			if captureBinlog {
+				printBinlogPositions(db, &result)
+			}
+			return result, fmt.Errorf("failed to apply migration %d_%s: %w",
+				migration.Version, migration.Name, err)
 		}
+		result.Record(migration, time.Since(migrationStart))
+
+		fmt.Printf("%sOK%s\n", ColorGreen, ColorReset)
 	}
 
-	return nil
+	result.TotalDuration = time.Since(start)
+	if captureBinlog {
+		printBinlogPositions(db, &result)
+	}
+	result.Print()
+
+	return result, nil
 }
 
 // RollbackLast rolls back the most recently applied migration
 func RollbackLast(db *sql.DB) error {
+	release, err := lockMigrations(db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	latestVersion, err := getLatestMigration(db)
 	if err != nil {
 		return err
@@ -327,21 +609,81 @@ func RollbackLast(db *sql.DB) error {
 		}
 	}
 
-	return fmt.Errorf("migration version %d not found", latestVersion)
+	return fmt.Errorf("%w: version %d", ErrMigrationNotFound, latestVersion)
+}
+
+// RollbackSteps rolls back a specified number of migrations. With dryRun their Down SQL
+// is printed instead.
+func RollbackSteps(db *sql.DB, steps int, dryRun bool) error {
+	if !dryRun {
+		release, err := lockMigrations(db)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	return rollbackApplied(db, steps, dryRun)
 }
 
-// RollbackSteps rolls back a specified number of migrations
-func RollbackSteps(db *sql.DB, steps int) error {
+// RollbackAll rolls back every applied migration, newest first. With dryRun their Down
+// SQL is printed instead. The applied migrations are read under the same lock as they
+// are rolled back, so none applied in between is left behind.
+func RollbackAll(db *sql.DB, dryRun bool) error {
+	if !dryRun {
+		release, err := lockMigrations(db)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	return rollbackApplied(db, -1, dryRun)
+}
+
+// rollbackApplied rolls back the newest steps applied migrations, or all of them if
+// steps is negative. Unless dryRun, the caller holds the migration lock.
+func rollbackApplied(db *sql.DB, steps int, dryRun bool) error {
 	appliedMigrations, err := getAppliedMigrations(db)
 	if err != nil {
 		return err
 	}
 
+	if dryRun {
+		return rollbackSteps(appliedMigrations, steps, func(migration Migration) error {
+			report.DryRun(migration, migration.DownSQL, " (rollback)")
+			return nil
+		})
+	}
+
+	return rollbackSteps(appliedMigrations, steps, func(migration Migration) error {
+		fmt.Printf("%s[ROLLBACK]%s Rolling back migration %s%d_%s%s... ",
+			ColorBlue, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset)
+
+		if err := rollbackMigration(db, migration); err != nil {
+			fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
+			return fmt.Errorf("failed to rollback migration %d_%s: %w",
+				migration.Version, migration.Name, err)
+		}
+
+		fmt.Printf("%sOK%s\n", ColorGreen, ColorReset)
+		return nil
+	})
+}
+
+// rollbackSteps calls rollback for the first steps of appliedMigrations, which are
+// ordered newest first, or for all of them if steps is negative. It stops at the first
+// error.
+func rollbackSteps(appliedMigrations []Migration, steps int, rollback func(Migration) error) error {
 	if len(appliedMigrations) == 0 {
 		fmt.Printf("%sNo migrations to rollback%s\n", ColorYellow, ColorReset)
 		return nil
 	}
 
+	if steps < 0 {
+		steps = len(appliedMigrations)
+	}
+
 	// Limit steps to available migrations
 	if steps > len(appliedMigrations) {
 		steps = len(appliedMigrations)
@@ -350,34 +692,106 @@ func RollbackSteps(db *sql.DB, steps int) error {
 	}
 
 	// Rollback migrations in reverse order
-	for i := 0; i < steps; i++ {
-		migration := appliedMigrations[i]
-		fmt.Printf("%s[ROLLBACK]%s Rolling back migration %s%d_%s%s... ",
-			ColorBlue, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset)
-
-		if err := rollbackMigration(db, migration); err != nil {
-			fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
-			return fmt.Errorf("failed to rollback migration %d_%s: %w",
-				migration.Version, migration.Name, err)
+	for _, migration := range appliedMigrations[:steps] {
+		if err := rollback(migration); err != nil {
+			return err
 		}
-
-		fmt.Printf("%sOK%s\n", ColorGreen, ColorReset)
 	}
 
 	return nil
 }
 
-// MigrateFresh drops all tables and reapplies all migrations
-func MigrateFresh(db *sql.DB) error {
-	if err := dropAllTables(db); err != nil {
+// Tables MigrateFresh keeps with their data, see SetPreservedTables
+var preservedTables []string
+
+// SetPreservedTables makes MigrateFresh keep the given tables, e.g. lookup tables that
+// are slow to repopulate. Their migrations must create them with IF NOT EXISTS.
+func SetPreservedTables(tables []string) {
+	preservedTables = tables
+}
+
+// MigrateFresh drops all tables and reapplies all migrations. With dryRun the SQL of all
+// migrations is printed instead and nothing is dropped.
+func MigrateFresh(db *sql.DB, dryRun bool) error {
+	if dryRun {
+		fmt.Printf("%s[DRY RUN]%s All tables would be dropped", ColorYellow, ColorReset)
+		if len(preservedTables) > 0 {
+			fmt.Printf(", preserved: %s", strings.Join(preservedTables, ", "))
+		}
+		fmt.Println()
+
+		migrations, err := loadMigrations()
+		if err != nil {
+			return err
+		}
+		_, err = dryRunMigrate(db, migrations, true)
 		return err
 	}
 
-	return Migrate(db)
+	release, err := lockMigrations(db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if backupConfig != nil && backupConfig.BackupBeforeFresh {
+		if err := backupDatabase(backupConfig); err != nil {
+			return err
+		}
+	}
+
+	if err := dropAllTables(db, preservedTables); err != nil {
+		return err
+	}
+	if len(preservedTables) > 0 {
+		fmt.Printf("%s[FRESH]%s Preserved tables: %s\n", ColorGreen, ColorReset, strings.Join(preservedTables, ", "))
+	}
+
+	// The migration lock is already held
+	_, err = migrate(db)
+	return err
+}
+
+// Number of Up SQL lines ListMigrations previews below each migration, see SetSQLPreview
+var sqlPreviewLines int
+
+// SetSQLPreview makes ListMigrations print the first maxLines non-empty lines of each
+// migration's Up SQL. 0 disables the preview.
+func SetSQLPreview(maxLines int) {
+	sqlPreviewLines = maxLines
+}
+
+// Description written into the header of new migration files, see SetMigrationComment
+var migrationComment string
+
+// SetMigrationComment makes new migration files start with a header holding comment
+// as the description, plus the current user and date. Empty writes no header.
+func SetMigrationComment(comment string) {
+	migrationComment = strings.Join(strings.Fields(comment), " ")
+}
+
+// Whether ListMigrations shows the author and description of each migration, see SetVerbose
+var verboseList bool
+
+// SetVerbose makes ListMigrations print the author and description from the header
+// of each migration below it.
+func SetVerbose(verbose bool) {
+	verboseList = verbose
+}
+
+// Filters of ListMigrations, see SetListFilter
+var listFilter report.ListFilter
+
+// SetListFilter makes ListMigrations show only the migrations that aren't applied yet,
+// and with countOnly print just the number of listed migrations as a bare integer, e.g.
+// for pending=$(jbmdb mysql-list --only-pending --count).
+func SetListFilter(onlyPending, countOnly bool) {
+	listFilter = report.ListFilter{OnlyPending: onlyPending, CountOnly: countOnly}
 }
 
 // ListMigrations retrieves and lists all migrations along with their status
-func ListMigrations(db *sql.DB) error {
+// order is "asc" or "desc" by version, and a limit above 0 shows only that many.
+func ListMigrations(db *sql.DB, order string, limit int) error {
 	migrations, err := loadMigrations()
 	if err != nil {
 		return err
@@ -388,56 +802,244 @@ func ListMigrations(db *sql.DB) error {
 		return nil
 	}
 
-	fmt.Println("\nMigration Status:")
-	fmt.Println("------------------")
+	// Durations are shown once the migrations table records them
+	hasDuration, err := hasDurationColumn(db)
+	if err != nil {
+		return fmt.Errorf("failed to check migrations table: %w", err)
+	}
+	durationColumn := "NULL"
+	if hasDuration {
+		durationColumn = "duration_ms"
+	}
 
-	for _, migration := range migrations {
-		applied, err := isMigrationApplied(db, migration.Version)
-		if err != nil {
-			return err
-		}
+	// Get all applied migrations from the database
+	rows, err := db.Query("SELECT version, applied_at, " + durationColumn + " FROM " + migrationsTable + " ORDER BY version")
+	if err != nil {
+		return fmt.Errorf("failed to query migrations table: %w", err)
+	}
+	defer rows.Close()
 
-		status := fmt.Sprintf("%s[PENDING]%s", ColorYellow, ColorReset)
-		if applied {
-			status = fmt.Sprintf("%s[APPLIED]%s", ColorGreen, ColorReset)
+	// Create a map of applied migrations
+	type appliedMigration struct {
+		appliedAt  time.Time
+		durationMs sql.NullInt64
+	}
+	appliedMigrations := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var version int64
+		var appliedAt sql.NullTime
+		var durationMs sql.NullInt64
+		if err := rows.Scan(&version, &appliedAt, &durationMs); err != nil {
+			return fmt.Errorf("failed to scan migration row: %w", err)
 		}
+		appliedMigrations[version] = appliedMigration{appliedAt: appliedAt.Time, durationMs: durationMs}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read migrations table: %w", err)
+	}
 
-		fmt.Printf("%s %s%d_%s%s\n",
-			status, ColorCyan, migration.Version, migration.Name, ColorReset)
+	// Filter before ordering, so a limit applies to the pending migrations
+	listed, done := report.FilterList(listFilter, migrations, func(m Migration) bool {
+		_, ok := appliedMigrations[m.Version]
+		return ok
+	})
+	if done {
+		return nil
+	}
+
+	shown, err := migfile.Order(listed, order, limit)
+	if err != nil {
+		return err
 	}
 
+	// Print header
+	fmt.Printf("\n%sMigration Status%s", ColorBold, ColorReset)
+	if limit > 0 {
+		position := "first"
+		if order == "desc" {
+			position = "last"
+		}
+		fmt.Printf(" (showing %s %d of %d)", position, len(shown), len(listed))
+	}
 	fmt.Println()
+	fmt.Println(strings.Repeat("-", 80))
+	if hasDuration {
+		fmt.Printf("%-20s %-30s %-15s %-25s %s\n", "Version", "Name", "Status", "Applied At", "Duration")
+	} else {
+		fmt.Printf("%-20s %-30s %-15s %s\n", "Version", "Name", "Status", "Applied At")
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
+	// Print each migration with its status
+	for _, m := range shown {
+		applied, isApplied := appliedMigrations[m.Version]
+		status := fmt.Sprintf("%sPending%s", ColorYellow, ColorReset)
+		appliedAtStr := "Not Applied"
+		if isApplied {
+			status = fmt.Sprintf("%sApplied%s", ColorGreen, ColorReset)
+			appliedAtStr = applied.appliedAt.In(displayLocation).Format("2006-01-02 15:04:05 MST")
+		}
+		if hasDuration {
+			// Migrations applied before durations were recorded have none
+			durationStr := "-"
+			if applied.durationMs.Valid {
+				durationStr = fmt.Sprintf("%.3fs", float64(applied.durationMs.Int64)/1000)
+			}
+			fmt.Printf("%-20d %-30s %-15s %-25s %s\n", m.Version, m.Name, status, appliedAtStr, durationStr)
+		} else {
+			fmt.Printf("%-20d %-30s %-15s %s\n", m.Version, m.Name, status, appliedAtStr)
+		}
+		if verboseList {
+			report.CommentHeader(m.Author, m.Description)
+		}
+		if sqlPreviewLines > 0 {
+			report.SQLPreview(m.UpSQL, sqlPreviewLines)
+		}
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
 	return nil
 }
 
+// preflightCheck warns about server settings that stop migrations from behaving as
+// expected. It never blocks the migration run.
+func preflightCheck(db *sql.DB, migrations []Migration) {
+	checkEventScheduler(db, migrations)
+	checkFulltextMinWordLength(db, migrations)
+}
+
+// checkEventScheduler warns when migrations create events while the event scheduler is off
+func checkEventScheduler(db *sql.DB, migrations []Migration) {
+	for _, migration := range migrations {
+		if !strings.Contains(strings.ToUpper(migration.UpSQL), "CREATE EVENT") {
+			continue
+		}
+
+		// Events are created regardless, but only fire while the scheduler is running
+		var name, value string
+		if err := db.QueryRow("SHOW VARIABLES LIKE 'event_scheduler'").Scan(&name, &value); err != nil {
+			fmt.Printf("%s[WARNING]%s Unable to check event_scheduler: %v\n", ColorYellow, ColorReset, err)
+			return
+		}
+		if strings.EqualFold(value, "OFF") {
+			fmt.Printf("%s[WARNING]%s event_scheduler is OFF: events will be created but will not fire "+
+				"until it is enabled (SET GLOBAL event_scheduler = ON)\n", ColorYellow, ColorReset)
+		}
+		return
+	}
+}
+
+// checkFulltextMinWordLength warns when migrations add FULLTEXT indexes while
+// ft_min_word_len silently leaves words shorter than 4 characters out of them.
+func checkFulltextMinWordLength(db *sql.DB, migrations []Migration) {
+	for _, migration := range migrations {
+		if !strings.Contains(strings.ToUpper(migration.UpSQL), "FULLTEXT") {
+			continue
+		}
+
+		var name, value string
+		if err := db.QueryRow("SHOW VARIABLES LIKE 'ft_min_word_len'").Scan(&name, &value); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				fmt.Printf("%s[WARNING]%s Unable to check ft_min_word_len: %v\n", ColorYellow, ColorReset, err)
+			}
+			return
+		}
+		if minLen, err := strconv.Atoi(value); err == nil && minLen > 3 {
+			fmt.Printf("%s[WARNING]%s ft_min_word_len is %d: words shorter than that are left out of "+
+				"FULLTEXT indexes (InnoDB uses innodb_ft_min_token_size instead)\n", ColorYellow, ColorReset, minLen)
+		}
+		return
+	}
+}
+
 // createMigrationsTable creates the migrations table if it doesn't exist
 func createMigrationsTable(db *sql.DB) error {
 	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS migrations (
+		CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (
 			version BIGINT UNSIGNED PRIMARY KEY,
 			name VARCHAR(255) NOT NULL,
-			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			duration_ms BIGINT UNSIGNED NULL,
+			checksum TEXT NULL
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 	`)
+	if err != nil {
+		return err
+	}
+
+	// Tables created by older versions lack the duration_ms and checksum columns
+	hasDuration, err := hasDurationColumn(db)
+	if err != nil {
+		return err
+	}
+	if !hasDuration {
+		if _, err := db.Exec("ALTER TABLE " + migrationsTable + " ADD COLUMN duration_ms BIGINT UNSIGNED NULL"); err != nil {
+			return err
+		}
+	}
+	hasChecksum, err := hasMigrationsColumn(db, "checksum")
+	if err != nil || hasChecksum {
+		return err
+	}
+	_, err = db.Exec("ALTER TABLE " + migrationsTable + " ADD COLUMN checksum TEXT NULL")
 	return err
 }
 
+// hasDurationColumn reports whether the migrations table records migration durations
+func hasDurationColumn(db *sql.DB) (bool, error) {
+	return hasMigrationsColumn(db, "duration_ms")
+}
+
+// hasMigrationsColumn reports whether the migrations table has the column
+func hasMigrationsColumn(db *sql.DB, column string) (bool, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = '`+migrationsTable+`' AND COLUMN_NAME = ?
+	`, column).Scan(&count)
+	return count > 0, err
+}
+
 // applyMigration applies a single migration to the database
 func applyMigration(db *sql.DB, migration Migration) error {
-	tx, err := db.BeginTx(context.Background(), nil)
+	start := time.Now()
+
+	checksum, err := fileChecksum(migration.Version, migration.Name)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
 	// Split the up migration into individual statements
 	statements := strings.Split(migration.UpSQL, ";")
+
+	if usePtOSC() {
+		if err := checkPtOSC(statements); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	for _, stmt := range statements {
 		stmt = strings.TrimSpace(stmt)
 		if stmt == "" {
 			continue
 		}
 
+		// ALTER TABLE runs through pt-online-schema-change so large tables stay writable
+		if usePtOSC() {
+			if table, alterations, ok := parseAlterTable(stmt); ok {
+				if err := runPtOSC(table, alterations); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
 		if _, err := tx.Exec(stmt); err != nil {
 			return err
 		}
@@ -445,8 +1047,8 @@ func applyMigration(db *sql.DB, migration Migration) error {
 
 	// Record the migration
 	if _, err := tx.Exec(
-		"INSERT INTO migrations (version, name) VALUES (?, ?)",
-		migration.Version, migration.Name,
+		"INSERT INTO "+migrationsTable+" (version, name, duration_ms, checksum) VALUES (?, ?, ?, ?)",
+		migration.Version, migration.Name, time.Since(start).Milliseconds(), checksum,
 	); err != nil {
 		return err
 	}
@@ -477,7 +1079,7 @@ func rollbackMigration(db *sql.DB, migration Migration) error {
 
 	// Remove the migration record
 	if _, err := tx.Exec(
-		"DELETE FROM migrations WHERE version = ?",
+		"DELETE FROM "+migrationsTable+" WHERE version = ?",
 		migration.Version,
 	); err != nil {
 		return err
@@ -490,7 +1092,7 @@ func rollbackMigration(db *sql.DB, migration Migration) error {
 func getAppliedMigrations(db *sql.DB) ([]Migration, error) {
 	var migrations []Migration
 
-	rows, err := db.Query("SELECT version, name FROM migrations ORDER BY version DESC")
+	rows, err := db.Query("SELECT version, name FROM " + migrationsTable + " ORDER BY version DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -531,7 +1133,7 @@ func getAppliedMigrations(db *sql.DB) ([]Migration, error) {
 func isMigrationApplied(db *sql.DB, version int64) (bool, error) {
 	var exists bool
 	err := db.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM migrations WHERE version = ?)",
+		"SELECT EXISTS(SELECT 1 FROM "+migrationsTable+" WHERE version = ?)",
 		version,
 	).Scan(&exists)
 	return exists, err
@@ -541,55 +1143,179 @@ func isMigrationApplied(db *sql.DB, version int64) (bool, error) {
 func getLatestMigration(db *sql.DB) (int64, error) {
 	var version int64
 	err := db.QueryRow(
-		"SELECT COALESCE(MAX(version), 0) FROM migrations",
+		"SELECT COALESCE(MAX(version), 0) FROM " + migrationsTable,
 	).Scan(&version)
 	return version, err
 }
 
-// parseInt converts a string to an integer
-func parseInt(s string) int64 {
-	var version int64
-	fmt.Sscanf(s, "%d", &version)
-	return version
+// parseInt converts a migration version to an integer, failing on anything that
+// isn't a number instead of returning 0
+func parseInt(s string) (int64, error) {
+	version, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid migration version %q: %w", s, err)
+	}
+	return version, nil
 }
 
-// dropAllTables drops all user-created tables in the database
-func dropAllTables(db *sql.DB) error {
+// dropAllTables drops all user-created views and tables in the database, or all starting
+// with the table prefix, except excludeTables.
+// Tables are dropped in reverse foreign key dependency order with foreign key checks
+// left on, so referencing tables go before the tables they reference. Circular
+// references, and preserved tables referencing dropped ones, fall back to disabling
+// foreign key checks.
+func dropAllTables(db *sql.DB, excludeTables []string) error {
 	fmt.Printf("%s[WARNING]%s Dropping all tables... ", ColorYellow, ColorReset)
 
-	// Disable foreign key checks temporarily
-	if _, err := db.Exec("SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+	if err := dropAllViews(db, excludeTables); err != nil {
 		return err
 	}
-	defer db.Exec("SET FOREIGN_KEY_CHECKS = 1")
 
-	// Get all table names
-	rows, err := db.Query(`
-		SELECT table_name 
-		FROM information_schema.tables 
-		WHERE table_schema = DATABASE()
-	`)
+	order, referencedByExcluded, err := tableDependencyOrder(db, excludeTables)
+	if err == nil && referencedByExcluded {
+		fmt.Printf("\n%s[WARNING]%s Preserved tables reference dropped tables, dropping with FOREIGN_KEY_CHECKS = 0... ",
+			ColorYellow, ColorReset)
+		if err := dropTablesWithoutForeignKeyChecks(db, order); err != nil {
+			return err
+		}
+		fmt.Printf("%sOK%s\n", ColorGreen, ColorReset)
+		return nil
+	}
+	if errors.Is(err, graph.ErrCycle) {
+		fmt.Printf("\n%s[WARNING]%s Circular foreign keys (%v), dropping with FOREIGN_KEY_CHECKS = 0... ",
+			ColorYellow, ColorReset, err)
+		if err := dropTablesWithoutForeignKeyChecks(db, order); err != nil {
+			return err
+		}
+		fmt.Printf("%sOK%s\n", ColorGreen, ColorReset)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
+
+	// Most depended-on tables come first in order, so drop from the end
+	for i := len(order) - 1; i >= 0; i-- {
+		if _, err := db.Exec("DROP TABLE IF EXISTS " + quoteIdentifier(order[i])); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", order[i], err)
+		}
+	}
+
+	fmt.Printf("%sOK%s\n", ColorGreen, ColorReset)
+	return nil
+}
+
+// tableDependencyOrder returns the tables of the current database, except
+// excludeTables, topologically sorted by their foreign keys, referenced tables first.
+// It also reports whether an excluded table references one of the returned tables.
+// When the foreign keys form a cycle, the unsorted table list is returned along with
+// a graph.ErrCycle error.
+func tableDependencyOrder(db *sql.DB, excludeTables []string) ([]string, bool, error) {
+	g := graph.New()
+	var tables []string
+
+	query := `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE()
+		  AND table_type = 'BASE TABLE'`
+	var args []any
+	if tablePrefix != "" {
+		// Only the application's own tables when sharing the database
+		query += " AND table_name LIKE ?"
+		args = append(args, strings.ReplaceAll(tablePrefix, "_", `\_`)+"%")
+	}
+	if len(excludeTables) > 0 {
+		query += " AND table_name NOT IN (?" + strings.Repeat(", ?", len(excludeTables)-1) + ")"
+		for _, table := range excludeTables {
+			args = append(args, table)
+		}
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
 	defer rows.Close()
 
-	// Drop each table
+	included := make(map[string]bool)
 	for rows.Next() {
 		var tableName string
 		if err := rows.Scan(&tableName); err != nil {
-			return err
+			return nil, false, err
 		}
+		g.AddNode(tableName)
+		tables = append(tables, tableName)
+		included[tableName] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
 
-		if _, err := db.Exec("DROP TABLE IF EXISTS " + tableName); err != nil {
-			return err
+	fkRows, err := db.Query(`
+		SELECT table_name, referenced_table_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = DATABASE()
+		  AND referenced_table_schema = DATABASE()
+		  AND referenced_table_name IS NOT NULL
+	`)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	defer fkRows.Close()
+
+	referencedByExcluded := false
+	for fkRows.Next() {
+		var table, referenced string
+		if err := fkRows.Scan(&table, &referenced); err != nil {
+			return nil, false, err
+		}
+		if !included[table] {
+			if included[referenced] {
+				referencedByExcluded = true
+			}
+			continue
 		}
+		if !included[referenced] {
+			continue
+		}
+		g.AddEdge(table, referenced)
+	}
+	if err := fkRows.Err(); err != nil {
+		return nil, false, err
 	}
 
-	if err := rows.Err(); err != nil {
+	order, err := g.TopologicalSort()
+	if err != nil {
+		return tables, referencedByExcluded, err
+	}
+	return order, referencedByExcluded, nil
+}
+
+// dropTablesWithoutForeignKeyChecks drops the given tables on a single connection with
+// foreign key checks disabled, since the setting only applies to its own session.
+func dropTablesWithoutForeignKeyChecks(db *sql.DB, tables []string) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
 		return err
 	}
+	defer conn.Close()
 
-	fmt.Printf("%sOK%s\n", ColorGreen, ColorReset)
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1")
+
+	for _, table := range tables {
+		if _, err := conn.ExecContext(ctx, "DROP TABLE IF EXISTS "+quoteIdentifier(table)); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", table, err)
+		}
+	}
 	return nil
 }
+
+// quoteIdentifier quotes a table or database name with backticks for use in a statement
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,168 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testDB connects to the database in JBMDB_TEST_MYSQL_DSN, e.g.
+// "root:secret@tcp(localhost:3306)/jbmdb_test", skipping the test when it isn't set.
+// Tests scope their tables with a table prefix and drop them when they're done.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("JBMDB_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("JBMDB_TEST_MYSQL_DSN is not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatalf("failed to connect to %s: %v", dsn, err)
+	}
+	return db
+}
+
+// writeMigration writes a migration file with the given Up and Down SQL to dir/sql
+func writeMigration(t *testing.T, dir string, version int64, name, up, down string) {
+	t.Helper()
+
+	content := fmt.Sprintf("-- Up Migration\n%s\n\n-- Down Migration\n%s\n", up, down)
+	filename := filepath.Join(dir, "sql", fmt.Sprintf("%d_%s.sql", version, name))
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMigrationsRejectsNonNumericVersion(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sql"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	SetMigrationPath(dir)
+	t.Cleanup(func() { SetMigrationPath("") })
+
+	content := "-- Up Migration\nCREATE TABLE users (id BIGINT);\n\n-- Down Migration\nDROP TABLE users;\n"
+	for _, filename := range []string{"notanumber_create_users_table.sql", "1.sql"} {
+		t.Run(filename, func(t *testing.T) {
+			path := filepath.Join(dir, "sql", filename)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.Remove(path) })
+
+			migrations, err := loadMigrations()
+			if err == nil {
+				t.Fatalf("loadMigrations() = %v, want an error for the invalid version", migrations)
+			}
+			if !strings.Contains(err.Error(), filename) {
+				t.Errorf("error %q doesn't name the migration file", err)
+			}
+		})
+	}
+}
+
+func TestRollbackStepsAll(t *testing.T) {
+	// Applied migrations come newest first, like getAppliedMigrations returns them
+	var applied []Migration
+	for version := int64(5); version >= 1; version-- {
+		applied = append(applied, Migration{Version: version, Name: fmt.Sprintf("migration_%d", version)})
+	}
+
+	tests := []struct {
+		steps int
+		want  []int64
+	}{
+		{steps: -1, want: []int64{5, 4, 3, 2, 1}},
+		{steps: 2, want: []int64{5, 4}},
+		{steps: 10, want: []int64{5, 4, 3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.steps), func(t *testing.T) {
+			var rolledBack []int64
+			err := rollbackSteps(applied, tt.steps, func(migration Migration) error {
+				rolledBack = append(rolledBack, migration.Version)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(rolledBack) != fmt.Sprint(tt.want) {
+				t.Errorf("rollbackSteps(%d) rolled back %v, want %v", tt.steps, rolledBack, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollbackAll(t *testing.T) {
+	db := testDB(t)
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sql"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	SetMigrationPath(dir)
+	if err := SetTablePrefix("jbmdb_test"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		SetMigrationPath("")
+		SetTablePrefix("")
+	})
+
+	tables := []string{"users", "posts", "comments", "tags", "likes"}
+	for i, table := range tables {
+		name := "create_" + table + "_table"
+		writeMigration(t, dir, int64(i+1), name,
+			"CREATE TABLE jbmdb_test_"+table+" (id BIGINT PRIMARY KEY);",
+			"DROP TABLE IF EXISTS jbmdb_test_"+table+";")
+	}
+	t.Cleanup(func() {
+		for _, table := range tables {
+			db.Exec("DROP TABLE IF EXISTS jbmdb_test_" + table)
+		}
+		db.Exec("DROP TABLE IF EXISTS " + migrationsTable)
+	})
+
+	if _, err := Migrate(db, false); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	applied, err := getAppliedMigrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(tables) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(tables))
+	}
+
+	if err := RollbackAll(db, false); err != nil {
+		t.Fatalf("RollbackAll: %v", err)
+	}
+
+	applied, err = getAppliedMigrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("%d migrations still applied after RollbackAll, want 0", len(applied))
+	}
+	for _, table := range tables {
+		var count int
+		if err := db.QueryRow(
+			"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+			"jbmdb_test_"+table).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("table jbmdb_test_%s still exists after RollbackAll", table)
+		}
+	}
+}
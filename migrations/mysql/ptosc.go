@@ -0,0 +1,126 @@
+package mysql
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/jbarasa/jbmdb/migrations/config"
+)
+
+// ptOSCBinary is the Percona Toolkit tool used for online schema changes.
+const ptOSCBinary = "pt-online-schema-change"
+
+// Configuration used to run ALTER TABLE statements through pt-online-schema-change.
+var ptOSCConfig *config.MySQLConfig
+
+// SetPtOSCConfig sets the configuration applyMigration uses for online schema changes.
+// ALTER TABLE statements only go through pt-online-schema-change when UsePtOSC is enabled.
+func SetPtOSCConfig(cfg *config.MySQLConfig) {
+	ptOSCConfig = cfg
+}
+
+// alterTablePattern matches an ALTER TABLE statement, capturing the table name and
+// the alterations.
+var alterTablePattern = regexp.MustCompile("(?is)^ALTER\\s+TABLE\\s+(?:`?[A-Za-z0-9_]+`?\\.)?`?([A-Za-z0-9_]+)`?\\s+(.+)$")
+
+// usePtOSC reports whether ALTER TABLE statements should go through pt-online-schema-change.
+func usePtOSC() bool {
+	return ptOSCConfig != nil && ptOSCConfig.UsePtOSC
+}
+
+// parseAlterTable splits an ALTER TABLE statement into its table name and alterations.
+// Leading comment lines are ignored. ok is false for any other statement.
+func parseAlterTable(stmt string) (table, alterations string, ok bool) {
+	match := alterTablePattern.FindStringSubmatch(stripLineComments(stmt))
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+// stripLineComments joins the lines of stmt that aren't empty or "--" comments
+func stripLineComments(stmt string) string {
+	var lines []string
+	for _, line := range strings.Split(stmt, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			lines = append(lines, trimmed)
+		}
+	}
+	return strings.Join(lines, " ")
+}
+
+// checkPtOSC returns an error if the migration contains ALTER TABLE statements but
+// pt-online-schema-change is not installed, rather than silently running them directly.
+// pt-online-schema-change runs outside the migration transaction, so a migration mixing
+// ALTER TABLE with other statements is rejected: a failing ALTER couldn't roll them back.
+func checkPtOSC(statements []string) error {
+	alters, others := 0, 0
+	for _, stmt := range statements {
+		if _, _, ok := parseAlterTable(stmt); ok {
+			alters++
+		} else if stripLineComments(stmt) != "" {
+			others++
+		}
+	}
+	if alters == 0 {
+		return nil
+	}
+
+	if _, err := exec.LookPath(ptOSCBinary); err != nil {
+		return fmt.Errorf("use_pt_osc is enabled but %s was not found in PATH", ptOSCBinary)
+	}
+	if others > 0 {
+		return fmt.Errorf("use_pt_osc is enabled but the migration mixes ALTER TABLE with %d other statements, "+
+			"which %s can't roll back: move them into a migration of their own", others, ptOSCBinary)
+	}
+	return nil
+}
+
+// runPtOSC applies the alterations to the table with pt-online-schema-change, which
+// copies the table in the background instead of blocking writes. Its output is printed
+// whether or not it succeeds.
+func runPtOSC(table, alterations string) error {
+	// Keep the password off the command line, where ps would show it
+	defaultsFile, err := writePtOSCDefaultsFile(ptOSCConfig.Password)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(defaultsFile)
+
+	dsn := fmt.Sprintf("h=%s,P=%s,u=%s,D=%s,t=%s",
+		ptOSCConfig.Host, ptOSCConfig.Port, ptOSCConfig.User, ptOSCConfig.DBName, table)
+
+	fmt.Printf("%s[PT-OSC]%s Altering table %s%s%s with %s\n",
+		ColorBlue, ColorReset, ColorCyan, table, ColorReset, ptOSCBinary)
+	cmd := exec.Command(ptOSCBinary, "--defaults-file", defaultsFile, "--alter", alterations, "--execute", dsn)
+	output, err := cmd.CombinedOutput()
+	if trimmed := strings.TrimSpace(string(output)); trimmed != "" {
+		fmt.Println(trimmed)
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed for table %s: %w", ptOSCBinary, table, err)
+	}
+
+	return nil
+}
+
+// writePtOSCDefaultsFile writes the password into a temporary option file readable only
+// by the current user and returns its path. The caller removes it.
+func writePtOSCDefaultsFile(password string) (string, error) {
+	file, err := os.CreateTemp("", "jbmdb-pt-osc-*.cnf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s defaults file: %w", ptOSCBinary, err)
+	}
+	defer file.Close()
+
+	// Option file values are quoted, with backslash escapes for quotes and backslashes
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(password)
+	if _, err := fmt.Fprintf(file, "[client]\npassword=\"%s\"\n", escaped); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write %s defaults file: %w", ptOSCBinary, err)
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,212 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jbarasa/jbmdb/migrations/internal/graph"
+	"github.com/jbarasa/jbmdb/migrations/internal/migfile"
+)
+
+// baselineName is the name of the migration Squash writes
+const baselineName = "baseline"
+
+// autoIncrementPattern matches the AUTO_INCREMENT counter SHOW CREATE TABLE adds to the
+// table options, which a fresh schema must not start from
+var autoIncrementPattern = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
+// Squash replaces all applied migrations with a single baseline migration: its Up SQL
+// is the SHOW CREATE TABLE of every table, referenced tables first, and its Down SQL
+// drops them in reverse. The old migration files are moved to
+// <migration_path>/archive/<baseline version> and the migrations table records only the
+// baseline. Pending migrations must be applied first. Views, routines, triggers and
+// events aren't part of the baseline.
+func Squash(db *sql.DB) error {
+	// No migration may be applied between the pending check and rewriting the records
+	release, err := lockMigrations(db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := createMigrationsTable(db); err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, migration := range migrations {
+		applied, err := isMigrationApplied(db, migration.Version)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d migrations are not applied yet, run mysql-migrate before squashing", pending)
+	}
+
+	var squashed int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + migrationsTable).Scan(&squashed); err != nil {
+		return fmt.Errorf("failed to count applied migrations: %w", err)
+	}
+	if squashed == 0 {
+		fmt.Printf("%sNo applied migrations to squash%s\n", ColorYellow, ColorReset)
+		return nil
+	}
+
+	up, down, err := baselineStatements(db)
+	if err != nil {
+		return err
+	}
+	warnUnsquashedObjects(db)
+
+	up = fmt.Sprintf("-- Baseline of %d squashed migrations, generated from SHOW CREATE TABLE\n%s", squashed, up)
+
+	sqlDir := filepath.Join(migrationPath, "sql")
+	timestamp, err := migfile.FreeTimestamp(sqlDir, time.Now(), ErrDuplicateVersion)
+	if err != nil {
+		return err
+	}
+	if err := writeMigrationFileTo(timestamp, baselineName, up, down); err != nil {
+		return err
+	}
+	baselineFile := fmt.Sprintf("%s_%s.sql", timestamp, baselineName)
+	version, err := parseInt(timestamp)
+	if err != nil {
+		return err
+	}
+
+	if err := recordBaseline(db, version); err != nil {
+		// Nothing changed yet, so don't leave a pending baseline behind
+		os.Remove(filepath.Join(sqlDir, baselineFile))
+		return err
+	}
+
+	// The records are replaced, so the old files can go
+	archiveDir := filepath.Join(migrationPath, "archive", timestamp)
+	archived, err := archiveMigrationFiles(sqlDir, archiveDir, baselineFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s[SQUASH]%s %d migrations squashed into %s%s%s, %d files archived in %s\n",
+		ColorGreen, ColorReset, squashed, ColorCyan, baselineFile, ColorReset, archived, archiveDir)
+	return nil
+}
+
+// baselineStatements returns the CREATE TABLE statements of all tables except the
+// migrations table in foreign key order, and the DROP TABLE statements undoing them.
+// Circular foreign keys can't be ordered, so both run with foreign key checks disabled.
+func baselineStatements(db *sql.DB) (string, string, error) {
+	order, _, err := tableDependencyOrder(db, []string{migrationsTable})
+	cyclic := errors.Is(err, graph.ErrCycle)
+	if err != nil && !cyclic {
+		return "", "", err
+	}
+
+	creates := make([]string, len(order))
+	drops := make([]string, len(order))
+	for i, table := range order {
+		var name, stmt string
+		if err := db.QueryRow("SHOW CREATE TABLE "+quoteIdentifier(table)).Scan(&name, &stmt); err != nil {
+			return "", "", fmt.Errorf("failed to read table %s: %w", table, err)
+		}
+		creates[i] = autoIncrementPattern.ReplaceAllString(stmt, "") + ";"
+		drops[len(order)-1-i] = "DROP TABLE IF EXISTS " + quoteIdentifier(table) + ";"
+	}
+
+	if cyclic {
+		fmt.Printf("%s[WARNING]%s Circular foreign keys (%v), the baseline disables FOREIGN_KEY_CHECKS\n",
+			ColorYellow, ColorReset, err)
+		creates = append(append([]string{"SET FOREIGN_KEY_CHECKS = 0;"}, creates...), "SET FOREIGN_KEY_CHECKS = 1;")
+		drops = append(append([]string{"SET FOREIGN_KEY_CHECKS = 0;"}, drops...), "SET FOREIGN_KEY_CHECKS = 1;")
+	}
+
+	return strings.Join(creates, "\n\n"), strings.Join(drops, "\n"), nil
+}
+
+// warnUnsquashedObjects warns about the views, routines, triggers and events of the
+// database, which the baseline doesn't recreate
+func warnUnsquashedObjects(db *sql.DB) {
+	var views, routines, triggers, events int
+	if err := db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM information_schema.views WHERE table_schema = DATABASE()),
+			(SELECT COUNT(*) FROM information_schema.routines WHERE routine_schema = DATABASE()),
+			(SELECT COUNT(*) FROM information_schema.triggers WHERE trigger_schema = DATABASE()),
+			(SELECT COUNT(*) FROM information_schema.events WHERE event_schema = DATABASE())
+	`).Scan(&views, &routines, &triggers, &events); err != nil {
+		fmt.Printf("%s[WARNING]%s Could not check for views, routines, triggers and events: %v\n",
+			ColorYellow, ColorReset, err)
+		return
+	}
+
+	if views+routines+triggers+events > 0 {
+		fmt.Printf("%s[WARNING]%s The baseline only recreates tables, add %d views, %d routines, %d triggers and %d events to it by hand\n",
+			ColorYellow, ColorReset, views, routines, triggers, events)
+	}
+}
+
+// recordBaseline replaces all records of the migrations table with the baseline in a
+// single transaction
+func recordBaseline(db *sql.DB, version int64) error {
+	checksum, err := fileChecksum(version, baselineName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM " + migrationsTable); err != nil {
+		return fmt.Errorf("failed to clear migrations table: %w", err)
+	}
+	if _, err := tx.Exec("INSERT INTO "+migrationsTable+" (version, name, checksum) VALUES (?, ?, ?)",
+		version, baselineName, checksum); err != nil {
+		return fmt.Errorf("failed to record baseline: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit baseline: %w", err)
+	}
+	return nil
+}
+
+// archiveMigrationFiles moves the migration files in sqlDir, except keep, into
+// archiveDir and returns how many it moved
+func archiveMigrationFiles(sqlDir, archiveDir, keep string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(sqlDir, "*.sql"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list migration files: %w", err)
+	}
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	archived := 0
+	for _, file := range files {
+		if filepath.Base(file) == keep {
+			continue
+		}
+		if err := os.Rename(file, filepath.Join(archiveDir, filepath.Base(file))); err != nil {
+			return archived, fmt.Errorf("failed to archive %s: %w", file, err)
+		}
+		archived++
+	}
+	return archived, nil
+}
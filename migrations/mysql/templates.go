@@ -0,0 +1,241 @@
+package mysql
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// CreateEventMigration creates a migration file for an Event Scheduler job.
+// Events only fire while the server's event_scheduler is ON.
+func CreateEventMigration(name string) error {
+	eventName := strings.ToLower(name)
+
+	up := fmt.Sprintf(`-- Migrations are split on semicolons: for a multi-statement body, move it into a
+-- stored procedure and CALL it from the event
+CREATE EVENT IF NOT EXISTS %s
+ON SCHEDULE EVERY 1 DAY
+DO BEGIN
+    -- TODO
+END;`, eventName)
+	down := fmt.Sprintf("DROP EVENT IF EXISTS %s;", eventName)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_event", eventName), up, down)
+}
+
+// FulltextOptions customizes the FULLTEXT index generated by CreateFulltextMigration.
+type FulltextOptions struct {
+	Parser string // Full-text parser: ngram, mecab or default (the built-in parser)
+	Mode   string // Search mode of the example query: boolean, natural or query-expansion
+}
+
+// fulltextSearchModes maps the --mode values to their MATCH ... AGAINST modifiers
+var fulltextSearchModes = map[string]string{
+	"natural":         "IN NATURAL LANGUAGE MODE",
+	"boolean":         "IN BOOLEAN MODE",
+	"query-expansion": "WITH QUERY EXPANSION",
+}
+
+// CreateFulltextMigration creates a migration file that adds a FULLTEXT index on the
+// given columns. Columns that the table's CREATE TABLE migration declares with a
+// non-text type are reported as warnings, since FULLTEXT only supports CHAR, VARCHAR
+// and TEXT columns.
+func CreateFulltextMigration(table string, columns []string, opts FulltextOptions) error {
+	table = strings.ToLower(table)
+	columns = slices.Clone(columns)
+	for i, column := range columns {
+		columns[i] = strings.ToLower(column)
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+
+	parser := strings.ToLower(opts.Parser)
+	withParser := ""
+	switch parser {
+	case "", "default":
+	case "ngram", "mecab":
+		withParser = " WITH PARSER " + parser
+	default:
+		return fmt.Errorf("invalid full-text parser %q: must be ngram, mecab or default", opts.Parser)
+	}
+
+	mode := strings.ToLower(opts.Mode)
+	if mode == "" {
+		mode = "natural"
+	}
+	searchModifier, ok := fulltextSearchModes[mode]
+	if !ok {
+		return fmt.Errorf("invalid full-text mode %q: must be boolean, natural or query-expansion", opts.Mode)
+	}
+
+	if err := checkFulltextColumns(table, columns); err != nil {
+		return err
+	}
+
+	indexName := fmt.Sprintf("ft_%s_%s", table, strings.Join(columns, "_"))
+	columnList := strings.Join(columns, ", ")
+
+	up := fmt.Sprintf(`-- InnoDB rebuilds FULLTEXT indexes lazily: after bulk inserts or large updates,
+-- run OPTIMIZE TABLE %s (with innodb_optimize_fulltext_only=ON) to merge the
+-- index and purge deleted entries. MyISAM tables use REPAIR TABLE %s QUICK instead.
+-- Query the index with the same column list, e.g.
+-- SELECT * FROM %s WHERE MATCH (%s) AGAINST ('search terms' %s)
+ALTER TABLE %s ADD FULLTEXT INDEX %s (%s)%s;`, table, table, table, columnList, searchModifier,
+		table, indexName, columnList, withParser)
+	down := fmt.Sprintf("ALTER TABLE %s DROP INDEX %s;", table, indexName)
+
+	return writeMigrationFile(fmt.Sprintf("add_%s_fulltext_index", indexName), up, down)
+}
+
+// textColumnTypes are the column types a FULLTEXT index can cover.
+var textColumnTypes = map[string]bool{
+	"char": true, "varchar": true,
+	"tinytext": true, "text": true, "mediumtext": true, "longtext": true,
+}
+
+// columnDefinitionPattern matches the name and type at the start of a column definition.
+var columnDefinitionPattern = regexp.MustCompile("^`?([A-Za-z_][A-Za-z0-9_]*)`?\\s+([A-Za-z]+)")
+
+// checkFulltextColumns looks up the CREATE TABLE migration for the table and warns about
+// requested columns with a non-text type. Nothing is checked if the table's migration
+// cannot be found.
+func checkFulltextColumns(table string, columns []string) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	createPattern := regexp.MustCompile("(?is)CREATE\\s+TABLE\\s+(IF\\s+NOT\\s+EXISTS\\s+)?`?" +
+		regexp.QuoteMeta(table) + "`?\\s*\\((.*)\\)")
+
+	for _, migration := range migrations {
+		match := createPattern.FindStringSubmatch(migration.UpSQL)
+		if match == nil {
+			continue
+		}
+
+		types := make(map[string]string)
+		for _, line := range strings.Split(match[2], "\n") {
+			definition := columnDefinitionPattern.FindStringSubmatch(strings.TrimSpace(line))
+			if definition != nil {
+				types[strings.ToLower(definition[1])] = strings.ToLower(definition[2])
+			}
+		}
+
+		for _, column := range columns {
+			columnType, ok := types[column]
+			if !ok {
+				fmt.Printf("%s[WARNING]%s Column %s not found in migration %d_%s\n",
+					ColorYellow, ColorReset, column, migration.Version, migration.Name)
+			} else if !textColumnTypes[columnType] {
+				fmt.Printf("%s[WARNING]%s Column %s is %s; FULLTEXT indexes only support CHAR, VARCHAR and TEXT columns\n",
+					ColorYellow, ColorReset, column, strings.ToUpper(columnType))
+			}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// referentialActions are the foreign key actions InnoDB supports. SET DEFAULT is
+// parsed by MySQL but rejected by InnoDB, so it is not offered.
+var referentialActions = []string{"CASCADE", "RESTRICT", "SET NULL", "NO ACTION"}
+
+// normalizeReferentialAction validates a foreign key action, accepting forms such as
+// "set-null" or "set_null" for SET NULL.
+func normalizeReferentialAction(action string) (string, error) {
+	normalized := strings.ToUpper(strings.NewReplacer("-", " ", "_", " ").Replace(action))
+	for _, valid := range referentialActions {
+		if normalized == valid {
+			return normalized, nil
+		}
+	}
+	return "", fmt.Errorf("invalid referential action: %s (valid: %s)", action, strings.Join(referentialActions, ", "))
+}
+
+// CreateForeignKeyMigration creates a migration file that adds a foreign key from
+// fromTable.fromColumn to toTable(id), named fk_<from_table>_<to_table>.
+func CreateForeignKeyMigration(fromTable, fromColumn, toTable, onDelete, onUpdate string) error {
+	fromTable = strings.ToLower(fromTable)
+	fromColumn = strings.ToLower(fromColumn)
+	toTable = strings.ToLower(toTable)
+
+	onDelete, err := normalizeReferentialAction(onDelete)
+	if err != nil {
+		return err
+	}
+	onUpdate, err = normalizeReferentialAction(onUpdate)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("fk_%s_%s", fromTable, toTable)
+
+	up := fmt.Sprintf(`-- The column must match the type of %s.id exactly, including UNSIGNED
+ALTER TABLE %s ADD CONSTRAINT %s
+    FOREIGN KEY (%s) REFERENCES %s(id)
+    ON DELETE %s ON UPDATE %s;`, toTable, fromTable, name, fromColumn, toTable, onDelete, onUpdate)
+	down := fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s;", fromTable, name)
+
+	return writeMigrationFile(fmt.Sprintf("add_%s_constraint", name), up, down)
+}
+
+// CreateGeneratedColumnMigration creates a migration file that adds a stored generated
+// column computed from expression. Requires MySQL 5.7+.
+func CreateGeneratedColumnMigration(table, column, expression, columnType string) error {
+	table = strings.ToLower(table)
+	column = strings.ToLower(column)
+	if strings.TrimSpace(expression) == "" {
+		return fmt.Errorf("the generated column expression must not be empty")
+	}
+
+	up := fmt.Sprintf(`-- The expression may only use deterministic functions and columns of the same row
+ALTER TABLE %s ADD COLUMN %s %s GENERATED ALWAYS AS (%s) STORED;`, table, column, columnType, expression)
+	down := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, column)
+
+	return writeMigrationFile(fmt.Sprintf("add_%s_%s_generated", table, column), up, down)
+}
+
+// JSONColumnOptions customizes the JSON column generated by CreateJSONColumnMigration.
+type JSONColumnOptions struct {
+	VirtualPath  string // JSON path of a generated column to extract, e.g. $.address.city
+	VirtualAlias string // Name of that generated column
+}
+
+// CreateJSONColumnMigration creates a migration file that adds a JSON column to table
+// with a JSON_VALID check, and optionally a virtual column extracting one path of it
+// that can be indexed. Requires MySQL 5.7.8+, and 8.0.16+ to enforce the check.
+func CreateJSONColumnMigration(table, column string, opts JSONColumnOptions) error {
+	table = strings.ToLower(table)
+	column = strings.ToLower(column)
+	check := fmt.Sprintf("chk_%s_%s_json", table, column)
+
+	additions := []string{
+		fmt.Sprintf("ADD COLUMN %s JSON NOT NULL", column),
+		fmt.Sprintf("ADD CONSTRAINT %s CHECK (JSON_VALID(%s))", check, column),
+	}
+	removals := []string{fmt.Sprintf("DROP CHECK %s", check)}
+
+	if opts.VirtualPath != "" {
+		if !strings.HasPrefix(opts.VirtualPath, "$") {
+			return fmt.Errorf("invalid JSON path %q: must start with $, e.g. $.address.city", opts.VirtualPath)
+		}
+		if opts.VirtualAlias == "" {
+			return fmt.Errorf("a column name is required for the virtual column of %s", opts.VirtualPath)
+		}
+		alias := strings.ToLower(opts.VirtualAlias)
+		additions = append(additions, fmt.Sprintf(
+			"ADD COLUMN %s VARCHAR(255) GENERATED ALWAYS AS (JSON_UNQUOTE(JSON_EXTRACT(%s, '%s'))) VIRTUAL",
+			alias, column, strings.ReplaceAll(opts.VirtualPath, "'", "''")))
+		removals = append(removals, fmt.Sprintf("DROP COLUMN %s", alias))
+	}
+	removals = append(removals, fmt.Sprintf("DROP COLUMN %s", column))
+
+	up := fmt.Sprintf("ALTER TABLE %s\n    %s;", table, strings.Join(additions, ",\n    "))
+	down := fmt.Sprintf("ALTER TABLE %s\n    %s;", table, strings.Join(removals, ",\n    "))
+
+	return writeMigrationFile(fmt.Sprintf("add_%s_%s_json", table, column), up, down)
+}
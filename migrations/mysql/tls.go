@@ -0,0 +1,93 @@
+package mysql
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/jbarasa/jbmdb/migrations/config"
+)
+
+// tlsConfigName is the name the TLS settings are registered under with the driver
+const tlsConfigName = "custom"
+
+// RegisterTLSConfig registers the CA, client certificate and key of the config with
+// the mysql driver. It returns the name to pass as the tls DSN parameter, or "" when
+// the config has neither TLS files nor a TLSMode. Without files, TLSMode is passed to
+// the driver as is; with files, only skip-verify is allowed besides true, and skips
+// the verification of the server certificate while still sending the client one.
+func RegisterTLSConfig(myConfig *config.MySQLConfig) (string, error) {
+	if myConfig.TLSCAFile == "" && myConfig.TLSCertFile == "" && myConfig.TLSKeyFile == "" {
+		return myConfig.TLSMode, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	switch myConfig.TLSMode {
+	case "", "true":
+	case "skip-verify":
+		tlsConfig.InsecureSkipVerify = true
+	default:
+		return "", fmt.Errorf("tls_mode %q can't be combined with TLS files, use true or skip-verify", myConfig.TLSMode)
+	}
+	if myConfig.TLSCAFile != "" {
+		pem, err := os.ReadFile(myConfig.TLSCAFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("no certificates found in CA file %s", myConfig.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if myConfig.TLSCertFile != "" || myConfig.TLSKeyFile != "" {
+		if myConfig.TLSCertFile == "" || myConfig.TLSKeyFile == "" {
+			return "", fmt.Errorf("a client certificate requires both a cert and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(myConfig.TLSCertFile, myConfig.TLSKeyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if err := driver.RegisterTLSConfig(tlsConfigName, tlsConfig); err != nil {
+		return "", fmt.Errorf("failed to register TLS config: %w", err)
+	}
+	return tlsConfigName, nil
+}
+
+// DSN returns the DSN of the configured database for the application user, with the
+// charset and TLS settings of the config. The CLI and the migrator package both
+// connect through it.
+func DSN(myConfig *config.MySQLConfig) (string, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?multiStatements=true&parseTime=true&%s",
+		myConfig.User, myConfig.Password, myConfig.Host, myConfig.Port, myConfig.DBName, CharsetDSNParams(myConfig))
+
+	tlsName, err := RegisterTLSConfig(myConfig)
+	if err != nil {
+		return "", err
+	}
+	if tlsName != "" {
+		dsn += "&tls=" + tlsName
+	}
+	return dsn, nil
+}
+
+// superuserDSN returns the DSN of the server, without a database, for the super user
+func superuserDSN(myConfig *config.MySQLConfig) (string, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/?%s",
+		myConfig.SuperUser, myConfig.SuperPass, myConfig.Host, myConfig.Port, CharsetDSNParams(myConfig))
+
+	tlsName, err := RegisterTLSConfig(myConfig)
+	if err != nil {
+		return "", err
+	}
+	if tlsName != "" {
+		dsn += "&tls=" + tlsName
+	}
+	return dsn, nil
+}
@@ -0,0 +1,123 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Longest view definition ListViews prints before truncating it
+const maxViewDefinitionLength = 50
+
+// CreateViewMigration creates a migration file for a view. The migration is named
+// create_<name>_view, so it doesn't collide with create_<name>_table migrations.
+func CreateViewMigration(name string) error {
+	viewName := strings.ToLower(name)
+
+	// Views share the namespace of tables
+	if err := checkDuplicateTableName(viewName); err != nil {
+		return err
+	}
+
+	up := fmt.Sprintf(`CREATE OR REPLACE VIEW %s AS
+SELECT -- TODO columns
+FROM -- TODO tables;`, prefixedTable(viewName))
+	down := fmt.Sprintf("DROP VIEW IF EXISTS %s;", prefixedTable(viewName))
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_view", viewName), up, down)
+}
+
+// extractViewName extracts the view name from a create_<name>_view migration name.
+// It returns an empty string for migrations that don't follow that convention.
+func extractViewName(name string) string {
+	if !strings.HasPrefix(name, "create_") || !strings.HasSuffix(name, "_view") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(name, "create_"), "_view")
+}
+
+// ListViews prints the views of the current database with the start of their definition
+func ListViews(db *sql.DB) error {
+	rows, err := db.Query(`
+		SELECT table_name, view_definition
+		FROM information_schema.views
+		WHERE table_schema = DATABASE()
+		ORDER BY table_name
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query views: %w", err)
+	}
+	defer rows.Close()
+
+	// Print header
+	fmt.Printf("\n%sViews%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-28s %s\n", "Name", "Definition")
+	fmt.Println(strings.Repeat("-", 80))
+
+	count := 0
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			return fmt.Errorf("failed to scan view row: %w", err)
+		}
+		definition = strings.Join(strings.Fields(definition), " ")
+		if len(definition) > maxViewDefinitionLength {
+			definition = definition[:maxViewDefinitionLength] + "..."
+		}
+		fmt.Printf("%s%-28s%s %s\n", ColorCyan, name, ColorReset, definition)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating views: %w", err)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("Total: %d views\n", count)
+
+	return nil
+}
+
+// dropAllViews drops the views of the current database, or those starting with the
+// table prefix, except excludeViews. Views go before tables, as they depend on them.
+func dropAllViews(db *sql.DB, excludeViews []string) error {
+	query := `
+		SELECT table_name
+		FROM information_schema.views
+		WHERE table_schema = DATABASE()`
+	var args []any
+	if tablePrefix != "" {
+		query += " AND table_name LIKE ?"
+		args = append(args, strings.ReplaceAll(tablePrefix, "_", `\_`)+"%")
+	}
+	if len(excludeViews) > 0 {
+		query += " AND table_name NOT IN (?" + strings.Repeat(", ?", len(excludeViews)-1) + ")"
+		for _, view := range excludeViews {
+			args = append(args, view)
+		}
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query views: %w", err)
+	}
+	var views []string
+	for rows.Next() {
+		var view string
+		if err := rows.Scan(&view); err != nil {
+			rows.Close()
+			return err
+		}
+		views = append(views, view)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, view := range views {
+		if _, err := db.Exec("DROP VIEW IF EXISTS " + quoteIdentifier(view)); err != nil {
+			return fmt.Errorf("failed to drop view %s: %w", view, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// timelineBarWidth is the width of the longest bar printed by AnalyzeTimeline
+const timelineBarWidth = 50
+
+// hasDurationColumn reports whether the migrations table records migration durations.
+// Tables created by older versions get the column on the next migrate.
+func hasDurationColumn(db *pgxpool.Pool) (bool, error) {
+	var exists bool
+	err := db.QueryRow(context.Background(), `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = '`+migrationsTable+`' AND column_name = 'duration_ms'
+		)
+	`).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migrations table: %w", err)
+	}
+	return exists, nil
+}
+
+// printNoDurations explains why there is nothing to analyze
+func printNoDurations() {
+	fmt.Printf("%sNo migration durations recorded yet, they are recorded from the next migrate on%s\n",
+		ColorYellow, ColorReset)
+}
+
+// AnalyzeSlowest prints the n applied migrations that took the longest to apply
+func AnalyzeSlowest(db *pgxpool.Pool, n int) error {
+	hasDuration, err := hasDurationColumn(db)
+	if err != nil {
+		return err
+	}
+	if !hasDuration {
+		printNoDurations()
+		return nil
+	}
+
+	rows, err := db.Query(context.Background(), `
+		SELECT version, name, duration_ms FROM `+migrationsTable+`
+		WHERE duration_ms IS NOT NULL
+		ORDER BY duration_ms DESC, version
+		LIMIT $1
+	`, n)
+	if err != nil {
+		return fmt.Errorf("failed to query migrations table: %w", err)
+	}
+	defer rows.Close()
+
+	fmt.Printf("\n%sSlowest Migrations%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-20s %-45s %s\n", "Version", "Name", "Duration")
+	fmt.Println(strings.Repeat("-", 80))
+
+	for rows.Next() {
+		var version, durationMs int64
+		var name string
+		if err := rows.Scan(&version, &name, &durationMs); err != nil {
+			return fmt.Errorf("failed to scan migration row: %w", err)
+		}
+		fmt.Printf("%-20d %-45s %.3fs\n", version, name, float64(durationMs)/1000)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read migrations table: %w", err)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
+	return nil
+}
+
+// AnalyzeTimeline prints a bar chart of the total time spent applying migrations
+// in each month, from the first to the last month with an applied migration.
+func AnalyzeTimeline(db *pgxpool.Pool) error {
+	hasDuration, err := hasDurationColumn(db)
+	if err != nil {
+		return err
+	}
+	if !hasDuration {
+		printNoDurations()
+		return nil
+	}
+
+	rows, err := db.Query(context.Background(), `
+		SELECT applied_at, duration_ms FROM `+migrationsTable+`
+		WHERE duration_ms IS NOT NULL AND applied_at IS NOT NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query migrations table: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[time.Time]time.Duration)
+	var first, last time.Time
+	for rows.Next() {
+		var appliedAt time.Time
+		var durationMs int64
+		if err := rows.Scan(&appliedAt, &durationMs); err != nil {
+			return fmt.Errorf("failed to scan migration row: %w", err)
+		}
+
+		appliedAt = appliedAt.In(displayLocation)
+		month := time.Date(appliedAt.Year(), appliedAt.Month(), 1, 0, 0, 0, 0, displayLocation)
+		totals[month] += time.Duration(durationMs) * time.Millisecond
+		if first.IsZero() || month.Before(first) {
+			first = month
+		}
+		if month.After(last) {
+			last = month
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read migrations table: %w", err)
+	}
+
+	if len(totals) == 0 {
+		printNoDurations()
+		return nil
+	}
+
+	var longest time.Duration
+	for _, total := range totals {
+		longest = max(longest, total)
+	}
+
+	fmt.Printf("\n%sMigration Time per Month%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		total := totals[month]
+		width := 0
+		if longest > 0 {
+			width = int(int64(total) * timelineBarWidth / int64(longest))
+		}
+		if width == 0 && total > 0 {
+			width = 1
+		}
+		fmt.Printf("%s  %s%-*s%s %10.3fs\n", month.Format("2006-01"),
+			ColorCyan, timelineBarWidth, strings.Repeat("#", width), ColorReset, total.Seconds())
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
+	return nil
+}
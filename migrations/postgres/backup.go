@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jbarasa/jbmdb/migrations/config"
+)
+
+// defaultBackupPath is used when BackupPath is not configured.
+const defaultBackupPath = "backups"
+
+// Configuration used to back up the database before a fresh migration.
+var backupConfig *config.PostgresConfig
+
+// SetBackupConfig sets the configuration MigrateFresh uses to back up the database.
+// The backup only runs when BackupBeforeFresh is enabled.
+func SetBackupConfig(cfg *config.PostgresConfig) {
+	backupConfig = cfg
+}
+
+// backupDatabase dumps the database with pg_dump into a timestamped file under
+// BackupPath. A missing pg_dump binary only prints a warning; a failed dump is
+// returned as an error so that nothing is dropped without a backup.
+func backupDatabase(ctx context.Context, cfg *config.PostgresConfig) error {
+	pgDump, err := exec.LookPath("pg_dump")
+	if err != nil {
+		fmt.Printf("%s[WARNING]%s pg_dump not found in PATH, skipping backup\n", ColorYellow, ColorReset)
+		return nil
+	}
+
+	backupPath := cfg.BackupPath
+	if backupPath == "" {
+		backupPath = defaultBackupPath
+	}
+	if err := os.MkdirAll(backupPath, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	filename := filepath.Join(backupPath, fmt.Sprintf("backup_%s.sql", time.Now().Format("20060102_150405")))
+	fmt.Printf("%s[BACKUP]%s Dumping database %s to %s...\n", ColorBlue, ColorReset, cfg.DBName, filename)
+
+	cmd := exec.CommandContext(ctx, pgDump,
+		"--host", cfg.Host,
+		"--port", cfg.Port,
+		"--username", cfg.User,
+		"--dbname", cfg.DBName,
+		"--file", filename,
+	)
+	cmd.Env = append(append(os.Environ(), "PGPASSWORD="+cfg.Password), sslEnv(cfg)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pg_dump failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	fmt.Printf("%s[BACKUP]%s Database backed up to %s\n", ColorGreen, ColorReset, filename)
+	return nil
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jbarasa/jbmdb/migrations/internal/checksum"
+)
+
+// Whether migrating skips comparing applied migration files with their checksums,
+// see SetSkipChecksum
+var skipChecksum bool
+
+// SetSkipChecksum makes migrating go ahead even when applied migration files changed
+// since they were applied, for emergencies. Prefer RecomputeChecksums for intended edits.
+func SetSkipChecksum(skip bool) {
+	skipChecksum = skip
+}
+
+// fileChecksum returns the hex encoded SHA-256 of the file of a migration
+func (s *settings) fileChecksum(version int64, name string) (string, error) {
+	return checksum.File(filepath.Join(s.migrationPath, "sql", fmt.Sprintf("%d_%s.sql", version, name)))
+}
+
+// RecomputeChecksums stores the SHA-256 of the current migration files as the
+// checksum of their applied migrations, e.g. after a comment in an applied migration
+// was fixed. Only the given versions are updated, or every applied migration when
+// versions is empty.
+func RecomputeChecksums(db *pgxpool.Pool, versions []int64) error {
+	s := packageSettings()
+	if err := s.createMigrationsTable(db); err != nil {
+		return err
+	}
+
+	applied, err := s.getAppliedMigrations(db)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	byVersion := make(map[int64]Migration, len(applied))
+	for _, migration := range applied {
+		byVersion[migration.Version] = migration
+	}
+
+	if len(versions) == 0 {
+		for _, migration := range applied {
+			versions = append(versions, migration.Version)
+		}
+	}
+
+	for _, version := range versions {
+		migration, ok := byVersion[version]
+		if !ok {
+			return fmt.Errorf("%w: %d is not applied", ErrMigrationNotFound, version)
+		}
+		if migration.FileMissing {
+			fmt.Printf("%s[SKIPPED]%s %d_%s has no migration file\n",
+				ColorYellow, ColorReset, migration.Version, migration.Name)
+			continue
+		}
+
+		sum, err := s.fileChecksum(migration.Version, migration.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(context.Background(),
+			"UPDATE "+migrationsTable+" SET checksum = $1 WHERE version = $2", sum, migration.Version); err != nil {
+			return fmt.Errorf("failed to update checksum of %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		fmt.Printf("%s[UPDATED]%s checksum for %s%d_%s%s\n",
+			ColorGreen, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset)
+	}
+
+	return nil
+}
+
+// verifyChecksums compares the checksum recorded for each applied migration with the
+// SHA-256 of its file and fails with ErrChecksumMismatch when any file changed. Records
+// from before checksums were recorded and migrations whose file is gone aren't checked.
+func (s *settings) verifyChecksums(db *pgxpool.Pool) error {
+	if s.skipChecksum {
+		fmt.Printf("%s[WARNING]%s Checksums of applied migrations are not verified\n", ColorYellow, ColorReset)
+		return nil
+	}
+
+	rows, err := db.Query(s.ctx,
+		"SELECT version, name, checksum FROM "+s.migrationsTable+" WHERE checksum IS NOT NULL ORDER BY version")
+	if err != nil {
+		return fmt.Errorf("failed to read checksums: %w", err)
+	}
+	records, err := pgx.CollectRows(rows, pgx.RowToStructByPos[checksum.Record])
+	if err != nil {
+		return fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	return checksum.Verify(records, s.fileChecksum, ErrChecksumMismatch)
+}
@@ -0,0 +1,327 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// userObject restricts a catalog query to objects in user schemas that are not
+// owned by an extension. %s is the oid column of the object.
+const userObject = `n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%%'
+	AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = %s AND d.deptype = 'e')`
+
+// cloneStage is a group of DDL statements generated from the source catalog. Each
+// query returns one statement per row, and stages run in dependency order.
+type cloneStage struct {
+	kind      string
+	query     string
+	superuser bool // Run as the connecting superuser instead of the target user
+}
+
+// cloneStages generates the DDL of a database. Functions come before tables since
+// column defaults may call them, foreign keys come after all tables exist, and views
+// come before indexes since materialized views can be indexed.
+var cloneStages = []cloneStage{
+	{"schemas", `
+		SELECT format('CREATE SCHEMA IF NOT EXISTS %I', n.nspname)
+		FROM pg_namespace n
+		WHERE n.nspname <> 'public' AND ` + fmt.Sprintf(userObject, "n.oid") + `
+		ORDER BY n.nspname`, false},
+	{"extensions", `
+		SELECT format('CREATE EXTENSION IF NOT EXISTS %I WITH SCHEMA %I', e.extname, n.nspname)
+		FROM pg_extension e
+		JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE e.extname <> 'plpgsql'
+		ORDER BY e.oid`, true},
+	{"enum types", `
+		SELECT format('CREATE TYPE %I.%I AS ENUM (%s)', n.nspname, t.typname,
+			string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder))
+		FROM pg_type t
+		JOIN pg_enum e ON e.enumtypid = t.oid
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE ` + fmt.Sprintf(userObject, "t.oid") + `
+		GROUP BY n.nspname, t.typname, t.oid
+		ORDER BY t.oid`, false},
+	{"composite types", `
+		SELECT format('CREATE TYPE %I.%I AS (%s)', n.nspname, t.typname,
+			string_agg(format('%I %s', a.attname, format_type(a.atttypid, a.atttypmod)), ', ' ORDER BY a.attnum))
+		FROM pg_type t
+		JOIN pg_class c ON c.oid = t.typrelid AND c.relkind = 'c'
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE ` + fmt.Sprintf(userObject, "t.oid") + `
+		GROUP BY n.nspname, t.typname, t.oid
+		ORDER BY t.oid`, false},
+	{"functions", `
+		SELECT pg_get_functiondef(p.oid)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE p.prokind IN ('f', 'p') AND ` + fmt.Sprintf(userObject, "p.oid") + `
+		ORDER BY p.oid`, false},
+	{"sequences", `
+		SELECT format('CREATE SEQUENCE %I.%I AS %s INCREMENT BY %s MINVALUE %s MAXVALUE %s START WITH %s%s',
+			s.schemaname, s.sequencename, s.data_type, s.increment_by, s.min_value, s.max_value,
+			s.start_value, CASE WHEN s.cycle THEN ' CYCLE' ELSE '' END)
+		FROM pg_sequences s
+		JOIN pg_namespace n ON n.nspname = s.schemaname
+		JOIN pg_class c ON c.relname = s.sequencename AND c.relnamespace = n.oid
+		WHERE ` + fmt.Sprintf(userObject, "c.oid") + `
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = c.oid AND d.deptype = 'i')
+		ORDER BY c.oid`, false},
+	{"tables", `
+		SELECT format('CREATE %sTABLE %I.%I (%s)%s',
+			CASE WHEN c.relpersistence = 'u' THEN 'UNLOGGED ' ELSE '' END,
+			n.nspname, c.relname,
+			coalesce((
+				SELECT string_agg(format('%I %s', a.attname, format_type(a.atttypid, a.atttypmod)) ||
+					CASE a.attidentity
+						WHEN 'a' THEN ' GENERATED ALWAYS AS IDENTITY'
+						WHEN 'd' THEN ' GENERATED BY DEFAULT AS IDENTITY'
+						ELSE '' END ||
+					CASE
+						WHEN a.attgenerated = 's' THEN ' GENERATED ALWAYS AS (' || pg_get_expr(ad.adbin, ad.adrelid) || ') STORED'
+						WHEN ad.adbin IS NOT NULL THEN ' DEFAULT ' || pg_get_expr(ad.adbin, ad.adrelid)
+						ELSE '' END ||
+					CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END,
+					', ' ORDER BY a.attnum)
+				FROM pg_attribute a
+				LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+				WHERE a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+			), ''),
+			CASE WHEN c.relkind = 'p' THEN ' PARTITION BY ' || pg_get_partkeydef(c.oid) ELSE '' END)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND NOT c.relispartition AND ` + fmt.Sprintf(userObject, "c.oid") + `
+		ORDER BY c.oid`, false},
+	{"partitions", `
+		SELECT format('CREATE TABLE %I.%I PARTITION OF %I.%I %s%s',
+			n.nspname, c.relname, pn.nspname, p.relname, pg_get_expr(c.relpartbound, c.oid),
+			CASE WHEN c.relkind = 'p' THEN ' PARTITION BY ' || pg_get_partkeydef(c.oid) ELSE '' END)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_inherits i ON i.inhrelid = c.oid
+		JOIN pg_class p ON p.oid = i.inhparent
+		JOIN pg_namespace pn ON pn.oid = p.relnamespace
+		WHERE c.relispartition AND ` + fmt.Sprintf(userObject, "c.oid") + `
+		ORDER BY c.oid`, false},
+	{"sequence ownerships", `
+		SELECT format('ALTER SEQUENCE %I.%I OWNED BY %I.%I.%I', sn.nspname, s.relname, n.nspname, t.relname, a.attname)
+		FROM pg_depend d
+		JOIN pg_class s ON s.oid = d.objid AND s.relkind = 'S'
+		JOIN pg_namespace sn ON sn.oid = s.relnamespace
+		JOIN pg_class t ON t.oid = d.refobjid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+		WHERE d.classid = 'pg_class'::regclass AND d.refclassid = 'pg_class'::regclass
+			AND d.deptype = 'a' AND ` + fmt.Sprintf(userObject, "t.oid") + `
+		ORDER BY s.oid`, false},
+	{"constraints", `
+		SELECT format('ALTER TABLE %I.%I ADD CONSTRAINT %I %s', n.nspname, c.relname, con.conname, pg_get_constraintdef(con.oid))
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE con.contype IN ('p', 'u', 'c', 'x') AND con.conislocal AND con.conparentid = 0
+			AND ` + fmt.Sprintf(userObject, "c.oid") + `
+		ORDER BY con.oid`, false},
+	{"foreign keys", `
+		SELECT format('ALTER TABLE %I.%I ADD CONSTRAINT %I %s', n.nspname, c.relname, con.conname, pg_get_constraintdef(con.oid))
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE con.contype = 'f' AND con.conparentid = 0 AND ` + fmt.Sprintf(userObject, "c.oid") + `
+		ORDER BY con.oid`, false},
+	{"views", `
+		SELECT CASE c.relkind
+			WHEN 'v' THEN format('CREATE VIEW %I.%I AS %s', n.nspname, c.relname, rtrim(pg_get_viewdef(c.oid), ';'))
+			ELSE format('CREATE MATERIALIZED VIEW %I.%I AS %s WITH NO DATA', n.nspname, c.relname, rtrim(pg_get_viewdef(c.oid), ';'))
+			END
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('v', 'm') AND ` + fmt.Sprintf(userObject, "c.oid") + `
+		ORDER BY c.oid`, false},
+	{"indexes", `
+		SELECT pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE ` + fmt.Sprintf(userObject, "i.indrelid") + `
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x'))
+			AND NOT EXISTS (SELECT 1 FROM pg_inherits inh WHERE inh.inhrelid = i.indexrelid)
+		ORDER BY i.indexrelid`, false},
+	{"triggers", `
+		SELECT pg_get_triggerdef(t.oid)
+		FROM pg_trigger t
+		JOIN pg_class c ON c.oid = t.tgrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE NOT t.tgisinternal AND t.tgparentid = 0 AND ` + fmt.Sprintf(userObject, "c.oid") + `
+		ORDER BY t.oid`, false},
+}
+
+// CloneSchema copies the schema of the database srcConn is connected to into
+// targetDB, creating it owned by targetUser if it doesn't exist. The DDL is generated
+// from the source catalog and applied in a single transaction as targetUser, so the
+// objects are owned by it; only extensions are created as the superuser. The target must not contain any tables. Table data is not
+// copied, except for the migrations records so the target is tracked as migrated.
+// srcConn must belong to a superuser.
+func CloneSchema(srcConn *pgx.Conn, targetDB, targetUser string) error {
+	ctx := context.Background()
+
+	if err := createDatabaseIfNotExists(srcConn, targetDB, targetUser); err != nil {
+		return err
+	}
+
+	targetConfig := srcConn.Config().Copy()
+	targetConfig.Database = targetDB
+	targetConn, err := pgx.ConnectConfig(ctx, targetConfig)
+	if err != nil {
+		return fmt.Errorf("%w: target database %s: %w", ErrConnectionFailed, targetDB, err)
+	}
+	defer targetConn.Close(ctx)
+
+	var tableCount int
+	err = targetConn.QueryRow(ctx, `
+		SELECT count(*)
+		FROM information_schema.tables
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+	`).Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check target database: %w", err)
+	}
+	if tableCount > 0 {
+		return fmt.Errorf("target database %s already has %d tables", targetDB, tableCount)
+	}
+
+	tx, err := targetConn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Function bodies may reference tables that are created later
+	if _, err := tx.Exec(ctx, "SET LOCAL check_function_bodies = false"); err != nil {
+		return err
+	}
+
+	for _, stage := range cloneStages {
+		statements, err := queryStatements(srcConn, stage.query)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", stage.kind, err)
+		}
+		if len(statements) == 0 {
+			continue
+		}
+
+		if targetUser != "" {
+			role := "SET LOCAL ROLE " + pgx.Identifier{targetUser}.Sanitize()
+			if stage.superuser {
+				role = "RESET ROLE"
+			}
+			if _, err := tx.Exec(ctx, role); err != nil {
+				return fmt.Errorf("failed to switch role for %s: %w", stage.kind, err)
+			}
+		}
+
+		fmt.Printf("%s[CLONE]%s Creating %d %s... ", ColorCyan, ColorReset, len(statements), stage.kind)
+		for _, stmt := range statements {
+			if _, err := tx.Exec(ctx, stmt); err != nil {
+				fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
+				return fmt.Errorf("failed to create %s: %w\n%s", stage.kind, err, stmt)
+			}
+		}
+		fmt.Printf("%sOK%s\n", ColorGreen, ColorReset)
+	}
+
+	copied, err := copyMigrationRecords(srcConn, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit schema: %w", err)
+	}
+
+	fmt.Printf("%sSchema cloned to '%s' (%d migration records copied)%s\n",
+		ColorGreen, targetDB, copied, ColorReset)
+	return nil
+}
+
+// createDatabaseIfNotExists creates the database owned by owner unless it exists
+func createDatabaseIfNotExists(conn *pgx.Conn, name, owner string) error {
+	ctx := context.Background()
+
+	var exists bool
+	err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error checking database existence: %v", err)
+	}
+	if exists {
+		return nil
+	}
+
+	stmt := "CREATE DATABASE " + pgx.Identifier{name}.Sanitize()
+	if owner != "" {
+		stmt += " OWNER " + pgx.Identifier{owner}.Sanitize()
+	}
+	if _, err := conn.Exec(ctx, stmt); err != nil {
+		return fmt.Errorf("error creating database: %v", err)
+	}
+
+	fmt.Printf("%sDatabase '%s' created successfully%s\n", ColorGreen, name, ColorReset)
+	return nil
+}
+
+// queryStatements runs a catalog query that returns one DDL statement per row
+func queryStatements(conn *pgx.Conn, query string) ([]string, error) {
+	rows, err := conn.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	statements, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	for i, stmt := range statements {
+		statements[i] = strings.TrimSpace(stmt)
+	}
+	return statements, nil
+}
+
+// copyMigrationRecords copies the applied migrations from the source into the cloned
+// migrations table. The target assigns new ids from its own sequence.
+func copyMigrationRecords(srcConn *pgx.Conn, tx pgx.Tx) (int, error) {
+	ctx := context.Background()
+
+	var exists bool
+	if err := srcConn.QueryRow(ctx, "SELECT to_regclass('"+migrationsTable+"') IS NOT NULL").Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to check migrations table: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	rows, err := srcConn.Query(ctx, "SELECT version, name, applied_at FROM "+migrationsTable+" ORDER BY id")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	records, err := pgx.CollectRows(rows, pgx.RowToStructByPos[struct {
+		Version   int64
+		Name      string
+		AppliedAt *time.Time
+	}])
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	for _, r := range records {
+		if _, err := tx.Exec(ctx, "INSERT INTO "+migrationsTable+" (version, name, applied_at) VALUES ($1, $2, $3)",
+			r.Version, r.Name, r.AppliedAt); err != nil {
+			return 0, fmt.Errorf("failed to copy migration record %d: %w", r.Version, err)
+		}
+	}
+	return len(records), nil
+}
@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffOp is a single line of a line-based diff: ' ' unchanged, '-' removed, '+' added.
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// DiffMigrations prints a unified diff of the Up SQL of two migration files.
+// When version2 is 0, version1 is compared against the migration preceding it.
+// No database connection is required.
+func DiffMigrations(version1, version2 int64) error {
+	migrations, err := packageSettings().loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	findIndex := func(version int64) (int, error) {
+		for i, m := range migrations {
+			if m.Version == version {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: %d", ErrMigrationNotFound, version)
+	}
+
+	toIndex, err := findIndex(version1)
+	if err != nil {
+		return err
+	}
+	fromIndex := toIndex - 1
+	if version2 != 0 {
+		fromIndex = toIndex
+		if toIndex, err = findIndex(version2); err != nil {
+			return err
+		}
+	} else if fromIndex < 0 {
+		return fmt.Errorf("migration %d has no previous migration to compare against", version1)
+	}
+
+	from, to := migrations[fromIndex], migrations[toIndex]
+	ops := diffLines(strings.Split(from.UpSQL, "\n"), strings.Split(to.UpSQL, "\n"))
+
+	fmt.Printf("%s--- %d_%s%s\n", ColorRed, from.Version, from.Name, ColorReset)
+	fmt.Printf("%s+++ %d_%s%s\n", ColorGreen, to.Version, to.Name, ColorReset)
+	printUnifiedHunks(ops)
+
+	return nil
+}
+
+// diffLines computes a line diff between a and b using the longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+
+	return ops
+}
+
+// printUnifiedHunks prints the diff operations grouped into unified diff hunks,
+// coloring removed lines red and added lines green.
+func printUnifiedHunks(ops []diffOp) {
+	// Line numbers in the old and new file at the start of each operation
+	oldLine := make([]int, len(ops)+1)
+	newLine := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	changed := false
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		changed = true
+
+		// Extend the hunk until the unchanged run between changes grows too long
+		start := max(0, i-diffContext)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				end = min(end+diffContext, len(ops))
+				break
+			}
+			end = run
+		}
+
+		oldCount, newCount := oldLine[end]-oldLine[start], newLine[end]-newLine[start]
+		oldStart, newStart := oldLine[start], newLine[start]
+		if oldCount > 0 {
+			oldStart++
+		}
+		if newCount > 0 {
+			newStart++
+		}
+		fmt.Printf("%s@@ -%d,%d +%d,%d @@%s\n", ColorCyan, oldStart, oldCount, newStart, newCount, ColorReset)
+
+		for _, op := range ops[start:end] {
+			switch op.kind {
+			case '-':
+				fmt.Printf("%s-%s%s\n", ColorRed, op.text, ColorReset)
+			case '+':
+				fmt.Printf("%s+%s%s\n", ColorGreen, op.text, ColorReset)
+			default:
+				fmt.Printf(" %s\n", op.text)
+			}
+		}
+		i = end
+	}
+
+	if !changed {
+		fmt.Printf("%sNo differences in Up SQL%s\n", ColorYellow, ColorReset)
+	}
+}
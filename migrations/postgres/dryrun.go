@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jbarasa/jbmdb/migrations/internal/report"
+)
+
+// dryRunMigrate prints the Up SQL of the migrations that aren't applied yet instead of
+// applying them. Applied migrations are reported as skipped, as applyMigration does.
+// Nothing is written, so a missing migrations table means no migration is applied, as
+// does fresh, for which the migrations table would be dropped first.
+func (s *settings) dryRunMigrate(db *pgxpool.Pool, migrations []Migration, fresh bool) (MigrateResult, error) {
+	var result MigrateResult
+
+	exists := false
+	if !fresh {
+		if err := db.QueryRow(s.ctx,
+			"SELECT to_regclass('"+s.migrationsTable+"') IS NOT NULL").Scan(&exists); err != nil {
+			return result, fmt.Errorf("failed to check migrations table: %w", err)
+		}
+	}
+
+	var applied func(Migration) (bool, error)
+	if exists {
+		applied = func(migration Migration) (bool, error) {
+			return s.isMigrationApplied(db, migration.Version)
+		}
+	}
+	var err error
+	result.Result, err = report.DryRunMigrate(migrations, applied, func(migration Migration) (string, string) {
+		if migration.NoTransaction || s.noTransaction {
+			return migration.UpSQL, " (no transaction)"
+		}
+		return migration.UpSQL, ""
+	})
+	return result, err
+}
+
+// dryRunRollback prints the Down SQL RollbackSteps would run for migration
+func dryRunRollback(migration Migration) {
+	if migration.FileMissing || migration.NoRollback {
+		fmt.Printf("%s[DRY RUN]%s %s%d_%s%s has no down SQL to run, only its record would be deleted\n",
+			ColorYellow, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset)
+		return
+	}
+	report.DryRun(migration, migration.DownSQL, " (rollback)")
+}
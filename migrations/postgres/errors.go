@@ -0,0 +1,36 @@
+package postgres
+
+import "errors"
+
+// Sentinel errors returned by the postgres package. They are wrapped with context, so
+// test for them with errors.Is, e.g. errors.Is(err, postgres.ErrMigrationNotFound).
+var (
+	// ErrMigrationNotFound is returned when a migration version has no file or record
+	ErrMigrationNotFound = errors.New("migration not found")
+
+	// ErrChecksumMismatch is returned when a migration file changed after it was applied
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+	// ErrDuplicateVersion is returned when two migration files share a version
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+
+	// ErrMigrationInProgress is returned when another process holds the migration lock,
+	// e.g. a second postgres-migrate started while the first is still running
+	ErrMigrationInProgress = errors.New("another migration process is running")
+
+	// ErrFreshMigrationInProgress is returned by MigrateFresh when another process
+	// holds the fresh migration lock
+	ErrFreshMigrationInProgress = errors.New("a fresh migration is already in progress")
+
+	// ErrInvalidTemplate is returned when a migration template fails to parse
+	ErrInvalidTemplate = errors.New("invalid migration template")
+
+	// ErrSchemaDrift is returned when the tables of the database don't match the migrations
+	ErrSchemaDrift = errors.New("schema drift detected")
+
+	// ErrConnectionFailed is returned when the database can't be reached
+	ErrConnectionFailed = errors.New("unable to connect to PostgreSQL")
+
+	// ErrInvalidTablePrefix is returned when a table prefix isn't a plain identifier
+	ErrInvalidTablePrefix = errors.New("invalid table prefix")
+)
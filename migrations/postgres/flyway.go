@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jbarasa/jbmdb/migrations/config"
+	"github.com/jbarasa/jbmdb/migrations/internal/migfile"
+)
+
+// ExportToFlyway converts the jbmdb migrations in srcPath into Flyway migrations in
+// dstPath: V<version>__<name>.sql for the Up SQL and U<version>__<name>.sql for the
+// Down SQL. Migrations marked no-rollback get no undo file. A flyway.conf with the
+// connection settings of cfg is written next to them.
+func ExportToFlyway(srcPath, dstPath string, cfg *config.PostgresConfig) error {
+	migrations, err := loadMigrationsFrom(srcPath)
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		fmt.Printf("%sNo migrations found in %s%s\n", ColorYellow, srcPath, ColorReset)
+		return nil
+	}
+
+	if err := os.MkdirAll(dstPath, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, migration := range migrations {
+		suffix := fmt.Sprintf("%d__%s.sql", migration.Version, migration.Name)
+		up := migfile.StripBanners(migration.UpSQL) + "\n"
+		if err := os.WriteFile(filepath.Join(dstPath, "V"+suffix), []byte(up), 0644); err != nil {
+			return fmt.Errorf("failed to write V%s: %w", suffix, err)
+		}
+
+		files := "V" + suffix
+		if !migration.NoRollback {
+			down := migfile.StripBanners(migration.DownSQL) + "\n"
+			if err := os.WriteFile(filepath.Join(dstPath, "U"+suffix), []byte(down), 0644); err != nil {
+				return fmt.Errorf("failed to write U%s: %w", suffix, err)
+			}
+			files += ", U" + suffix
+		}
+
+		fmt.Printf("%s[EXPORTED]%s %s%d_%s%s -> %s\n",
+			ColorGreen, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset, files)
+	}
+
+	url := fmt.Sprintf("jdbc:postgresql://%s:%s/%s", cfg.Host, cfg.Port, cfg.DBName)
+	if err := migfile.WriteFlywayConf(dstPath, url, cfg.User, cfg.Password); err != nil {
+		return err
+	}
+
+	fmt.Printf("%sExported %d migrations to %s%s\n", ColorGreen, len(migrations), dstPath, ColorReset)
+	return nil
+}
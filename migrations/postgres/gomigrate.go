@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jbarasa/jbmdb/migrations/internal/migfile"
+)
+
+// goMigrateBaseVersion is the timestamp imported golang-migrate migrations are numbered
+// from; the nth migration gets this time plus n seconds.
+var goMigrateBaseVersion = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// goMigrateFilePattern matches golang-migrate file names: <version>_<title>.<up|down>.sql
+var goMigrateFilePattern = regexp.MustCompile(`^(\d+)_(.*)\.(up|down)\.sql$`)
+
+// ExportToGoMigrate converts the jbmdb migrations in srcPath into golang-migrate files in
+// dstPath. golang-migrate versions are a plain sequence, so the migrations are numbered
+// 1, 2, 3... in version order as <000001>_<name>.up.sql and <000001>_<name>.down.sql.
+func ExportToGoMigrate(srcPath, dstPath string) error {
+	migrations, err := loadMigrationsFrom(srcPath)
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		fmt.Printf("%sNo migrations found in %s%s\n", ColorYellow, srcPath, ColorReset)
+		return nil
+	}
+
+	if err := os.MkdirAll(dstPath, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for i, migration := range migrations {
+		base := filepath.Join(dstPath, fmt.Sprintf("%06d_%s", i+1, migration.Name))
+		up := migfile.StripBanners(migration.UpSQL) + "\n"
+		down := migfile.StripBanners(migration.DownSQL) + "\n"
+
+		if err := os.WriteFile(base+".up.sql", []byte(up), 0644); err != nil {
+			return fmt.Errorf("failed to write %s.up.sql: %w", base, err)
+		}
+		if err := os.WriteFile(base+".down.sql", []byte(down), 0644); err != nil {
+			return fmt.Errorf("failed to write %s.down.sql: %w", base, err)
+		}
+
+		fmt.Printf("%s[EXPORTED]%s %s%d_%s%s -> %s.{up,down}.sql\n",
+			ColorGreen, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset, base)
+	}
+
+	fmt.Printf("%sExported %d migrations to %s%s\n", ColorGreen, len(migrations), dstPath, ColorReset)
+	return nil
+}
+
+// goMigration is an up/down file pair read from a golang-migrate directory.
+type goMigration struct {
+	sequence int64
+	name     string
+	up       string
+	down     string
+	hasDown  bool
+}
+
+// ImportFromGoMigrate converts the golang-migrate up/down pairs in srcPath into jbmdb
+// migration files in dstPath. Versions are assigned from 20200101000000 plus the
+// migration's position in seconds, so the original order is preserved. Non-SQL files,
+// such as Go-native migrations, cannot be imported and are reported as warnings.
+func ImportFromGoMigrate(srcPath, dstPath string) error {
+	files, err := os.ReadDir(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to read source directory: %w", err)
+	}
+
+	bySequence := make(map[int64]*goMigration)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		match := goMigrateFilePattern.FindStringSubmatch(file.Name())
+		if match == nil {
+			fmt.Printf("%s[WARNING]%s Skipping %s: not a golang-migrate SQL migration (Go-native migrations cannot be imported)\n",
+				ColorYellow, ColorReset, file.Name())
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(srcPath, file.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Name(), err)
+		}
+
+		sequence, err := parseInt(match[1])
+		if err != nil {
+			return fmt.Errorf("invalid migration file %s: %w", file.Name(), err)
+		}
+		migration, ok := bySequence[sequence]
+		if !ok {
+			migration = &goMigration{sequence: sequence, name: strings.ToLower(match[2])}
+			bySequence[sequence] = migration
+		}
+		if match[3] == "up" {
+			migration.up = strings.TrimSpace(string(content))
+		} else {
+			migration.down = strings.TrimSpace(string(content))
+			migration.hasDown = true
+		}
+	}
+
+	migrations := make([]*goMigration, 0, len(bySequence))
+	for _, migration := range bySequence {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].sequence < migrations[j].sequence
+	})
+
+	for i, migration := range migrations {
+		if !migration.hasDown {
+			fmt.Printf("%s[WARNING]%s Migration %d_%s has no down file; its Down section will be empty\n",
+				ColorYellow, ColorReset, migration.sequence, migration.name)
+		}
+
+		name := migration.name
+		if name == "" {
+			name = fmt.Sprintf("migration_%d", migration.sequence)
+		}
+
+		version := goMigrateBaseVersion.Add(time.Duration(i+1) * time.Second).Format("20060102150405")
+		if err := writeMigrationFileTo(dstPath, version, name, migration.up, migration.down); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%sImported %d migrations into %s%s\n", ColorGreen, len(migrations), dstPath, ColorReset)
+	return nil
+}
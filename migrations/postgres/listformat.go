@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Output format of ListMigrations, see SetListFormat
+var listFormat string
+
+// SetListFormat sets the output format of ListMigrations: "" for plain columns or
+// "table" for columns with box-drawing borders.
+func SetListFormat(format string) error {
+	switch format {
+	case "", "table":
+		listFormat = format
+		return nil
+	default:
+		return fmt.Errorf("invalid list format %q: must be table", format)
+	}
+}
+
+// Columns of ListMigrations: the name is the only one that gets truncated, the status
+// the only one that is colored
+const (
+	nameColumn   = 1
+	statusColumn = 2
+)
+
+// listLayout prints the columns of ListMigrations as wide as their widest value, with
+// the name column truncated to fit the terminal.
+type listLayout struct {
+	widths []int
+	boxed  bool
+}
+
+// newListLayout sizes the columns for the header and rows, shrinking the name column
+// when the table is wider than the terminal.
+func newListLayout(header []string, rows [][]string, boxed bool) listLayout {
+	widths := make([]int, len(header))
+	for i, title := range header {
+		widths[i] = utf8.RuneCountInString(title)
+	}
+	for _, row := range rows {
+		for i, value := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(value))
+		}
+	}
+
+	layout := listLayout{widths: widths, boxed: boxed}
+	if termWidth, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		if excess := layout.totalWidth() - termWidth; excess > 0 {
+			minWidth := max(utf8.RuneCountInString(header[nameColumn]), len("..."))
+			widths[nameColumn] = max(minWidth, widths[nameColumn]-excess)
+		}
+	}
+	return layout
+}
+
+// totalWidth returns the width of a printed row, including separators and borders
+func (l listLayout) totalWidth() int {
+	total := 0
+	for _, width := range l.widths {
+		total += width
+	}
+	if l.boxed {
+		return total + 3*len(l.widths) + 1
+	}
+	return total + len(l.widths) - 1
+}
+
+// printRow prints one row, padding each value to its column and coloring the status
+// with color
+func (l listLayout) printRow(values []string, color string) {
+	cells := make([]string, len(values))
+	for i, value := range values {
+		value = truncate(value, l.widths[i])
+		padding := strings.Repeat(" ", l.widths[i]-utf8.RuneCountInString(value))
+		if color != "" && i == statusColumn {
+			value = color + value + ColorReset
+		}
+		cells[i] = value + padding
+	}
+	if l.boxed {
+		fmt.Printf("│ %s │\n", strings.Join(cells, " │ "))
+		return
+	}
+	fmt.Println(strings.TrimRight(strings.Join(cells, " "), " "))
+}
+
+// printTop, printSeparator and printBottom print the horizontal lines of the table
+func (l listLayout) printTop()       { l.printLine("┌", "┬", "┐") }
+func (l listLayout) printSeparator() { l.printLine("├", "┼", "┤") }
+func (l listLayout) printBottom()    { l.printLine("└", "┴", "┘") }
+
+// printLine prints a horizontal line, with box-drawing joints when boxed
+func (l listLayout) printLine(left, joint, right string) {
+	if !l.boxed {
+		fmt.Println(strings.Repeat("-", l.totalWidth()))
+		return
+	}
+	segments := make([]string, len(l.widths))
+	for i, width := range l.widths {
+		segments[i] = strings.Repeat("─", width+2)
+	}
+	fmt.Println(left + strings.Join(segments, joint) + right)
+}
+
+// truncate shortens value to width runes, ending in ... when cut
+func truncate(value string, width int) string {
+	if utf8.RuneCountInString(value) <= width {
+		return value
+	}
+	runes := []rune(value)
+	return string(runes[:width-len("...")]) + "..."
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Advisory lock keys, "jbmd" in ASCII followed by the lock number. migrationLockID is
+// held while Migrate, MigrateVersions, RollbackLast, RollbackSteps, MigrateFresh and Squash
+// change the database. MigrateFresh takes freshMigrationLockID first, so a second fresh run
+// reports ErrFreshMigrationInProgress rather than ErrMigrationInProgress.
+const (
+	migrationLockID      int64 = 0x6a626d6401
+	freshMigrationLockID int64 = 0x6a626d6402
+)
+
+// acquireAdvisoryLock tries to take a session-level advisory lock without waiting.
+// Advisory locks belong to a connection, so the lock is held on a dedicated connection
+// from the pool; call the returned release function to unlock it and return the
+// connection. acquired is false if another session holds the lock.
+func (s *settings) acquireAdvisoryLock(db *pgxpool.Pool, lockID int64) (release func(), acquired bool, err error) {
+	conn, err := db.Acquire(s.ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire connection for advisory lock: %w", err)
+	}
+
+	if err := conn.QueryRow(s.ctx,
+		"SELECT pg_try_advisory_lock($1)", lockID).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	if !acquired {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	// The lock is released even when the context of the call was canceled, otherwise
+	// the connection would go back to the pool still holding it
+	release = func() {
+		conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+		conn.Release()
+	}
+	return release, true, nil
+}
+
+// lockMigrations takes the migration lock, failing with ErrMigrationInProgress instead
+// of waiting if another process holds it. Call the returned function to release it.
+func (s *settings) lockMigrations(db *pgxpool.Pool) (func(), error) {
+	release, acquired, err := s.acquireAdvisoryLock(db, migrationLockID)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, fmt.Errorf("%w: wait for it to finish and try again (advisory lock %d)",
+			ErrMigrationInProgress, migrationLockID)
+	}
+	return release, nil
+}
@@ -24,8 +24,8 @@
 // Each function is designed to handle errors gracefully and provides detailed
 // logging and error messages to aid in debugging and operational monitoring.
 //
-// The package utilizes context.Background() for database operations, ensuring
-// that each operation is properly scoped and can be canceled if necessary.
+// The package functions utilize context.Background() for database operations, while
+// the methods of a Runner use the context they are given, so they can be canceled.
 //
 // This code is intended to be reusable and adaptable for various PostgreSQL
 // database applications, providing a structured approach to managing database
@@ -39,10 +39,13 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -50,25 +53,141 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jbarasa/jbmdb/migrations/config"
+	"github.com/jbarasa/jbmdb/migrations/internal/migfile"
+	"github.com/jbarasa/jbmdb/migrations/internal/naming"
+	"github.com/jbarasa/jbmdb/migrations/internal/report"
 )
 
 // Migration represents a database migration with its version, name, SQL scripts for
 // applying and rolling back the migration.
 type Migration struct {
-	Version int64  // The version of the migration.
-	Name    string // The name of the migration.
-	UpSQL   string // SQL script for applying the migration.
-	DownSQL string // SQL script for rolling back the migration.
+	Version       int64  // The version of the migration.
+	Name          string // The name of the migration.
+	UpSQL         string // SQL script for applying the migration.
+	DownSQL       string // SQL script for rolling back the migration.
+	NoTransaction bool   // Whether the migration must run outside of a transaction.
+	NoRollback    bool   // Whether rolling back only removes the migration record.
+	Description   string // Description from the header of the migration file.
+	Author        string // Author from the header of the migration file.
+	FileMissing   bool   // Whether the file of an applied migration no longer exists.
 }
 
+// String returns the <version>_<name> of the migration.
+func (m Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// MigrateResult summarizes a migration run.
+type MigrateResult struct {
+	report.Result[Migration]
+	WALBefore string // WAL position before the run, when captured
+	WALAfter  string // WAL position after the run, when captured
+}
+
+// noTransactionDirective marks a migration whose statements cannot run inside a
+// transaction (e.g. CREATE INDEX CONCURRENTLY). Such migrations are executed
+// statement by statement directly on the pool.
+const noTransactionDirective = "-- jbmdb: no-transaction"
+
+// noRollbackDirective marks a migration that cannot be reversed (e.g. adding an enum
+// value). Rolling it back skips the down SQL and only removes the migration record.
+const noRollbackDirective = "-- jbmdb: no-rollback"
+
 // Path to the migration files.
 var migrationPath string
 
 // SetMigrationPath sets the path for migration files
+//
+// Deprecated: use NewRunner, which keeps its own path and settings, when embedding
+// migrations in an application. The CLI still uses it for its commands.
 func SetMigrationPath(path string) {
 	migrationPath = path
 }
 
+// Prefix of the tables this application owns, including the trailing underscore, and
+// the table applied migrations are recorded in. See SetTablePrefix.
+var (
+	tablePrefix     string
+	migrationsTable = "migrations"
+)
+
+// SetTablePrefix scopes jbmdb to the tables starting with prefix, for applications
+// sharing a database: new tables are created as <prefix>_<name>, fresh migrations only
+// drop prefixed tables and migrations are recorded in <prefix>_migrations. The prefix
+// is inlined into queries, so it fails with ErrInvalidTablePrefix unless the prefix has
+// only letters, digits and underscores.
+func SetTablePrefix(prefix string) error {
+	if !naming.ValidTablePrefix(prefix) {
+		return fmt.Errorf("%w %q: only letters, digits and underscores are allowed", ErrInvalidTablePrefix, prefix)
+	}
+
+	tablePrefix = ""
+	if prefix = strings.TrimSuffix(prefix, "_"); prefix != "" {
+		tablePrefix = prefix + "_"
+	}
+	migrationsTable = tablePrefix + "migrations"
+	return nil
+}
+
+// prefixedTable returns the table name with the table prefix, unless it already has it
+func prefixedTable(name string) string {
+	if strings.HasPrefix(name, tablePrefix) {
+		return name
+	}
+	return tablePrefix + name
+}
+
+// Whether every migration runs without a transaction, regardless of its directive.
+var noTransaction bool
+
+// SetNoTransaction makes applyMigration run all migrations outside of a transaction,
+// as if every file carried the no-transaction directive.
+func SetNoTransaction(enabled bool) {
+	noTransaction = enabled
+}
+
+// settings are the context and settings migrations are applied and rolled back with.
+// The package functions use the package-level settings set by the Set* functions,
+// while a Runner uses its own.
+type settings struct {
+	ctx             context.Context
+	migrationPath   string
+	tablePrefix     string
+	migrationsTable string
+	skipChecksum    bool
+	excludePatterns []string
+	noTransaction   bool
+	parallelism     int
+	captureWAL      bool
+	preservedTables []string
+	backupConfig    *config.PostgresConfig
+}
+
+// packageSettings returns the package-level settings with a background context
+func packageSettings() *settings {
+	return &settings{
+		ctx:             context.Background(),
+		migrationPath:   migrationPath,
+		tablePrefix:     tablePrefix,
+		migrationsTable: migrationsTable,
+		skipChecksum:    skipChecksum,
+		excludePatterns: excludePatterns,
+		noTransaction:   noTransaction,
+		parallelism:     parallelism,
+		captureWAL:      captureWAL,
+		preservedTables: preservedTables,
+		backupConfig:    backupConfig,
+	}
+}
+
+// Location used to display timestamps in ListMigrations.
+var displayLocation = time.Local
+
+// SetDisplayLocation sets the timezone used to display applied_at timestamps
+func SetDisplayLocation(loc *time.Location) {
+	displayLocation = loc
+}
+
 // Color constants for terminal output
 const (
 	ColorRed    = "\033[31m"
@@ -84,10 +203,16 @@ const (
 
 // extractTableName extracts the table name from the migration name
 func extractTableName(name string) string {
+	// Column migrations name the table after "_to_" or "_from_"
+	if _, _, table, ok := parseColumnMigrationName(name); ok {
+		return table
+	}
+
 	// Remove common prefixes like "create_" or "add_" and suffixes like "_table"
 	name = strings.TrimPrefix(name, "create_")
 	name = strings.TrimPrefix(name, "add_")
 	name = strings.TrimSuffix(name, "_table")
+	name = strings.TrimSuffix(name, "_materialized_view")
 
 	// Convert to snake_case if it's in CamelCase
 	name = camelToSnakeCase(name)
@@ -96,6 +221,59 @@ func extractTableName(name string) string {
 	return name
 }
 
+// columnMigrationPattern matches add_<column>_to_<table> and remove_<column>_from_<table>.
+var columnMigrationPattern = regexp.MustCompile(`^(add|remove)_([a-z0-9_]+?)_(to|from)_([a-z0-9_]+)$`)
+
+// parseColumnMigrationName splits an add_<column>_to_<table> or
+// remove_<column>_from_<table> migration name into its action, column and table.
+func parseColumnMigrationName(name string) (action, column, table string, ok bool) {
+	match := columnMigrationPattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", "", "", false
+	}
+	if (match[1] == "add") != (match[3] == "to") {
+		return "", "", "", false
+	}
+	return match[1], match[2], match[4], true
+}
+
+// IsColumnMigrationName reports whether name follows the add_<column>_to_<table>
+// or remove_<column>_from_<table> naming convention.
+func IsColumnMigrationName(name string) bool {
+	_, _, _, ok := parseColumnMigrationName(name)
+	return ok
+}
+
+// IsAlterMigrationName reports whether name follows the alter_<table>_<description>
+// naming convention.
+func IsAlterMigrationName(name string) bool {
+	return naming.IsAlter(name)
+}
+
+// migrationTables returns the tables the migrations create, named as in the migration
+// names, which alter migration names are matched against
+func migrationTables() (map[string]bool, error) {
+	migrations, err := packageSettings().loadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	tables := make(map[string]bool, len(migrations))
+	for _, migration := range migrations {
+		tables[strings.ToLower(extractTableName(migration.Name))] = true
+	}
+	return tables, nil
+}
+
+// parseAlterMigrationName parses an alter_<table>_<description> migration name against
+// the tables of the migrations, see naming.ParseAlter
+func parseAlterMigrationName(name string) (naming.Alter, error) {
+	tables, err := migrationTables()
+	if err != nil {
+		return naming.Alter{}, err
+	}
+	return naming.ParseAlter(name, tables)
+}
+
 // camelToSnakeCase converts a string from CamelCase to snake_case
 func camelToSnakeCase(s string) string {
 	var result strings.Builder
@@ -108,9 +286,10 @@ func camelToSnakeCase(s string) string {
 	return result.String()
 }
 
-// checkDuplicateTableName checks if a migration with the same table name already exists
+// checkDuplicateTableName checks if a migration with the same table name already exists,
+// or a domain of that name, since a table and a domain can't share a name.
 func checkDuplicateTableName(newTableName string) error {
-	migrations, err := loadMigrations()
+	migrations, err := packageSettings().loadMigrations()
 	if err != nil {
 		return fmt.Errorf("failed to load migrations: %w", err)
 	}
@@ -121,12 +300,146 @@ func checkDuplicateTableName(newTableName string) error {
 			return fmt.Errorf("%stable name '%s' already exists in migration '%s'%s",
 				ColorRed, newTableName, migration.Name, ColorReset)
 		}
+		if domain := migrationDomainName(migration); strings.EqualFold(domain, newTableName) {
+			return fmt.Errorf("%sname '%s' is already used by a domain in migration '%s'%s",
+				ColorRed, newTableName, migration.Name, ColorReset)
+		}
+	}
+	return nil
+}
+
+// createDomainPattern matches CREATE DOMAIN statements and captures the domain name.
+var createDomainPattern = regexp.MustCompile(`(?i)CREATE\s+DOMAIN\s+(?:[a-z_][a-z0-9_]*\.)?"?([a-z_][a-z0-9_]*)"?`)
+
+// migrationDomainName returns the domain a migration creates, by the
+// create_<name>_domain naming convention or a CREATE DOMAIN statement, or "".
+func migrationDomainName(migration Migration) string {
+	if strings.HasPrefix(migration.Name, "create_") && strings.HasSuffix(migration.Name, "_domain") {
+		return strings.TrimSuffix(strings.TrimPrefix(migration.Name, "create_"), "_domain")
+	}
+	if match := createDomainPattern.FindStringSubmatch(migration.UpSQL); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// extractTypeName extracts the type name from a create_<name>_type migration name.
+// It returns an empty string for migrations that don't follow that convention.
+func extractTypeName(name string) string {
+	if !strings.HasPrefix(name, "create_") || !strings.HasSuffix(name, "_type") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(name, "create_"), "_type")
+}
+
+// createTypePattern matches CREATE TYPE statements and captures the type name.
+var createTypePattern = regexp.MustCompile(`(?i)CREATE\s+TYPE\s+(?:[a-z_][a-z0-9_]*\.)?"?([a-z_][a-z0-9_]*)"?`)
+
+// checkDuplicateTypeName checks if a migration already creates a type or domain with
+// the same name, either by the create_<name>_type naming convention or through a
+// CREATE TYPE statement in its Up SQL.
+func checkDuplicateTypeName(newTypeName string) error {
+	migrations, err := packageSettings().loadMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	for _, migration := range migrations {
+		if strings.EqualFold(extractTypeName(migration.Name), newTypeName) {
+			return fmt.Errorf("%stype name '%s' already exists in migration '%s'%s",
+				ColorRed, newTypeName, migration.Name, ColorReset)
+		}
+		for _, match := range createTypePattern.FindAllStringSubmatch(migration.UpSQL, -1) {
+			if strings.EqualFold(match[1], newTypeName) {
+				return fmt.Errorf("%stype name '%s' already exists in migration '%s'%s",
+					ColorRed, newTypeName, migration.Name, ColorReset)
+			}
+		}
+		if strings.EqualFold(migrationDomainName(migration), newTypeName) {
+			return fmt.Errorf("%stype name '%s' is already used by a domain in migration '%s'%s",
+				ColorRed, newTypeName, migration.Name, ColorReset)
+		}
+	}
+	return nil
+}
+
+// constraintPattern matches named constraints and captures the constraint name.
+var constraintPattern = regexp.MustCompile(`(?i)\bCONSTRAINT\s+"?([a-z_][a-z0-9_]*)"?`)
+
+// checkDuplicateConstraintName checks if a migration already declares a constraint
+// with the same name in its Up SQL.
+func checkDuplicateConstraintName(newConstraintName string) error {
+	migrations, err := packageSettings().loadMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	for _, migration := range migrations {
+		for _, match := range constraintPattern.FindAllStringSubmatch(migration.UpSQL, -1) {
+			if strings.EqualFold(match[1], newConstraintName) {
+				return fmt.Errorf("%sconstraint name '%s' already exists in migration '%s'%s",
+					ColorRed, newConstraintName, migration.Name, ColorReset)
+			}
+		}
 	}
 	return nil
 }
 
+// TableOptions customizes the CREATE TABLE statement generated by CreateMigration.
+type TableOptions struct {
+	Tablespace string   // Tablespace to create the table in, empty for the default
+	PrimaryKey string   // Type of the id column: bigserial (default), uuid, int or none
+	References []string // Tables to add a <table>_id foreign key column for
+	// Range columns as column:range_type, e.g. during:tstzrange, each with a GiST index
+	RangeColumns []string
+}
+
+// rangeColumn splits a column:range_type option into the column and its range type.
+func rangeColumn(option string) (string, string, error) {
+	column, rangeType, ok := strings.Cut(strings.ToLower(option), ":")
+	if !ok || column == "" || rangeType == "" {
+		return "", "", fmt.Errorf("invalid range column %q: expected column:range_type, e.g. during:tstzrange", option)
+	}
+	return column, rangeType, nil
+}
+
+// primaryKeyColumn returns the id column definition for a --primary-key type,
+// or "" for none.
+func primaryKeyColumn(keyType string) (string, error) {
+	switch strings.ToLower(keyType) {
+	case "", "bigserial":
+		return "id BIGSERIAL PRIMARY KEY", nil
+	case "uuid":
+		return "id UUID PRIMARY KEY DEFAULT gen_random_uuid()", nil
+	case "int":
+		return "id SERIAL PRIMARY KEY", nil
+	case "none":
+		return "", nil
+	default:
+		return "", fmt.Errorf("invalid primary key type %q: must be bigserial, uuid, int or none", keyType)
+	}
+}
+
 // CreateMigration creates new migration file with the given name and current timestamp.
-func CreateMigration(name string) error {
+func CreateMigration(name string, opts TableOptions) error {
+	// Column migrations alter an existing table instead of creating one
+	if action, column, table, ok := parseColumnMigrationName(name); ok {
+		if err := checkNoTemplate("column"); err != nil {
+			return err
+		}
+		return createColumnMigration(name, action, column, prefixedTable(table))
+	}
+	if IsAlterMigrationName(name) {
+		if err := checkNoTemplate("alter"); err != nil {
+			return err
+		}
+		alter, err := parseAlterMigrationName(name)
+		if err != nil {
+			return err
+		}
+		return createAlterMigration(name, alter.Action, alter.Column, prefixedTable(alter.Table))
+	}
+
 	// Extract table name from migration name
 	tableName := extractTableName(name)
 
@@ -135,29 +448,129 @@ func CreateMigration(name string) error {
 		return err
 	}
 
+	tablespace := ""
+	if opts.Tablespace != "" {
+		tablespace = " TABLESPACE " + strings.ToLower(opts.Tablespace)
+	}
+
+	idColumn, err := primaryKeyColumn(opts.PrimaryKey)
+	if err != nil {
+		return err
+	}
+
+	// Without an id column the user declares the (composite) key themselves
+	prefix, columns := "", ""
+	switch {
+	case idColumn == "":
+		columns = "\t-- TODO: add the key columns and PRIMARY KEY (...)\n"
+	case strings.HasPrefix(idColumn, "id UUID"):
+		prefix = "-- gen_random_uuid() is built in since PostgreSQL 13, older versions need:\n-- CREATE EXTENSION IF NOT EXISTS \"pgcrypto\";\n"
+		columns = "    " + idColumn + ",\n"
+	default:
+		columns = "    " + idColumn + ",\n"
+	}
+	data := templateData{
+		Name: name, Author: migfile.CurrentUser(), Comment: migrationComment,
+		Tablespace: strings.ToLower(opts.Tablespace), IDColumn: idColumn,
+	}
+	for _, table := range opts.References {
+		table = strings.ToLower(strings.TrimPrefix(table, tablePrefix))
+		columns += fmt.Sprintf("    %s BIGINT NOT NULL REFERENCES %s(id) ON DELETE CASCADE,\n",
+			naming.ReferenceColumn(table), prefixedTable(table))
+		data.References = append(data.References,
+			templateColumn{Name: naming.ReferenceColumn(table), Type: "BIGINT", References: prefixedTable(table)})
+	}
+
+	// Range columns are queried with overlap and containment operators, which need GiST
+	table := prefixedTable(strings.ToLower(tableName))
+	indexes := ""
+	for _, option := range opts.RangeColumns {
+		column, rangeType, err := rangeColumn(option)
+		if err != nil {
+			return err
+		}
+		columns += fmt.Sprintf("    %s %s NOT NULL,\n", column, rangeType)
+		data.RangeColumns = append(data.RangeColumns, templateColumn{Name: column, Type: rangeType})
+		indexes += fmt.Sprintf("\n\nCREATE INDEX IF NOT EXISTS idx_%s_%s ON %s USING GIST (%s);",
+			table, column, table, column)
+	}
+
+	data.Table = table
+	up, down, ok, err := renderTemplate("table", data)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		up = fmt.Sprintf(`%sCREATE TABLE IF NOT EXISTS %s (
+%s	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL
+)%s;%s`, prefix, table, columns, tablespace, indexes)
+		down = fmt.Sprintf("DROP TABLE IF EXISTS %s;", table)
+	}
+
+	return writeMigrationFile(name, up, down)
+}
+
+// createColumnMigration writes an ALTER TABLE migration that adds or removes a column.
+// The column type defaults to TEXT and is meant to be adjusted in the generated file.
+func createColumnMigration(name, action, column, table string) error {
+	addColumn := fmt.Sprintf("-- TODO: adjust the column type\nALTER TABLE %s ADD COLUMN %s TEXT;", table, column)
+	dropColumn := fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s;", table, column)
+
+	if action == "remove" {
+		return writeMigrationFile(name, dropColumn, addColumn)
+	}
+	return writeMigrationFile(name, addColumn, dropColumn)
+}
+
+// createAlterMigration writes an ALTER TABLE migration for an alter_<table>_<description>
+// name. Adding or dropping a column is generated like a column migration, other
+// descriptions get a template to fill in.
+func createAlterMigration(name, action, column, table string) error {
+	switch action {
+	case "add":
+		return createColumnMigration(name, "add", column, table)
+	case "drop":
+		return createColumnMigration(name, "remove", column, table)
+	}
+
+	up := fmt.Sprintf("-- TODO: alter the table, e.g.\n-- ALTER TABLE %s ALTER COLUMN name SET NOT NULL;", table)
+	down := fmt.Sprintf("-- TODO: undo the changes to %s", table)
+	return writeMigrationFile(name, up, down)
+}
+
+// writeMigrationFile writes a new migration file named after the given migration name
+// and the current timestamp, wrapping the up and down SQL in the standard sections.
+func writeMigrationFile(name, up, down string) error {
+	sqlPath := filepath.Join(migrationPath, "sql")
+
 	// Generate a timestamp in the format YYYYMMDDHHMMSS.
-	timestamp := time.Now().Format("20060102150405")
+	timestamp, err := migfile.FreeTimestamp(sqlPath, time.Now(), ErrDuplicateVersion)
+	if err != nil {
+		return err
+	}
+
+	// Create the migration file in the SQL folder within the migration path
+	return writeMigrationFileTo(sqlPath, timestamp, name, up, down)
+}
+
+// writeMigrationFileTo writes a migration file with the given version timestamp into sqlPath.
+func writeMigrationFileTo(sqlPath, timestamp, name, up, down string) error {
 	// Combine the timestamp and name to create a unique filename.
 	filename := fmt.Sprintf("%s_%s.sql", timestamp, name)
 
-	// Write placeholder content to the up and down migration file
-	content := fmt.Sprintf(`-- Up Migration
+	// Write the up and down sections into a single migration file
+	content := migfile.CommentHeader(migrationComment, time.Now()) + fmt.Sprintf(`-- Up Migration
 ----------------------- Write your up migration here ----------------------------
 
-CREATE TABLE IF NOT EXISTS %s (
-    id BIGSERIAL PRIMARY KEY,
-	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
-	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL
-);
+%s
 
 
 -- Down Migration
 ----------------------- Write your down migration here ----------------------------
 
-DROP TABLE IF EXISTS %s;`, strings.ToLower(tableName), strings.ToLower(tableName))
+%s`, up, down)
 
-	// Create the migration file in the SQL folder within the migration path
-	sqlPath := filepath.Join(migrationPath, "sql")
 	if err := os.MkdirAll(sqlPath, 0755); err != nil {
 		return fmt.Errorf("failed to create SQL directory: %w", err)
 	}
@@ -173,18 +586,27 @@ DROP TABLE IF EXISTS %s;`, strings.ToLower(tableName), strings.ToLower(tableName
 	return nil
 }
 
-// parseInt converts a string to an integer.
-func parseInt(s string) int64 {
-	var result int64
-	fmt.Sscanf(s, "%d", &result)
-	return result
+// parseInt converts a migration version to an integer, failing on anything that
+// isn't a number instead of returning 0.
+func parseInt(s string) (int64, error) {
+	result, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid migration version %q: %w", s, err)
+	}
+	return result, nil
 }
 
 // loadMigrations loads all migration files from the migration directory and returns a slice of Migration structs.
-func loadMigrations() ([]Migration, error) {
-	// Get the SQL directory path
-	sqlPath := filepath.Join(migrationPath, "sql")
+func (s *settings) loadMigrations() ([]Migration, error) {
+	migrations, err := loadMigrationsFrom(filepath.Join(s.migrationPath, "sql"))
+	if err != nil {
+		return nil, err
+	}
+	return migfile.Exclude(migrations, func(m Migration) string { return m.Name }, s.excludePatterns)
+}
 
+// loadMigrationsFrom loads all migration files from the given SQL directory.
+func loadMigrationsFrom(sqlPath string) ([]Migration, error) {
 	// Read the migration directory.
 	files, err := os.ReadDir(sqlPath)
 	if err != nil {
@@ -202,7 +624,10 @@ func loadMigrations() ([]Migration, error) {
 			}
 
 			// Get the version from the first part of the filename.
-			version := parts[0]
+			version, err := parseInt(parts[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid migration file %s: %w", file.Name(), err)
+			}
 			// Get the name from the remaining parts of the filename.
 			name := strings.TrimSuffix(strings.Join(parts[1:], "_"), filepath.Ext(file.Name()))
 
@@ -217,15 +642,25 @@ func loadMigrations() ([]Migration, error) {
 				return nil, fmt.Errorf("invalid migration format in file %s", file.Name())
 			}
 
-			up := strings.TrimSpace(strings.TrimPrefix(upDown[0], "-- Up Migration"))
+			// Anything above the Up section is the comment header
+			header, up, found := strings.Cut(upDown[0], "-- Up Migration")
+			if !found {
+				header, up = "", upDown[0]
+			}
+			up = strings.TrimSpace(up)
 			down := strings.TrimSpace(upDown[1])
+			description, author := migfile.ParseCommentHeader(header)
 
 			// Create a new Migration struct.
 			migrations = append(migrations, Migration{
-				Version: parseInt(version),
-				Name:    name,
-				UpSQL:   up,
-				DownSQL: down,
+				Version:       version,
+				Name:          name,
+				UpSQL:         up,
+				DownSQL:       down,
+				NoTransaction: strings.Contains(up, noTransactionDirective),
+				NoRollback:    strings.Contains(string(content), noRollbackDirective),
+				Description:   description,
+				Author:        author,
 			})
 		}
 	}
@@ -238,22 +673,154 @@ func loadMigrations() ([]Migration, error) {
 	return migrations, nil
 }
 
-// Migrate applies all pending migrations to the database.
-func Migrate(db *pgxpool.Pool) error {
+// Migrate applies all pending migrations to the database. With dryRun their SQL is
+// printed instead and the database is left untouched.
+func Migrate(db *pgxpool.Pool, dryRun bool) (MigrateResult, error) {
+	return packageSettings().migrateAll(db, dryRun)
+}
+
+// migrateAll applies all pending migrations, see Migrate.
+func (s *settings) migrateAll(db *pgxpool.Pool, dryRun bool) (MigrateResult, error) {
+	if dryRun {
+		migrations, err := s.loadMigrations()
+		if err != nil {
+			return MigrateResult{}, err
+		}
+		return s.dryRunMigrate(db, migrations, false)
+	}
+
+	// Make sure no other process applies the same migrations.
+	release, err := s.lockMigrations(db)
+	if err != nil {
+		return MigrateResult{}, err
+	}
+	defer release()
+
+	return s.migrate(db)
+}
+
+// migrate applies all pending migrations, the caller holding the migration lock.
+func (s *settings) migrate(db *pgxpool.Pool) (MigrateResult, error) {
+	var result MigrateResult
+	start := time.Now()
+
 	// Create the migrations table if it doesn't exist.
-	if err := createMigrationsTable(db); err != nil {
-		return err
+	if err := s.createMigrationsTable(db); err != nil {
+		return result, err
+	}
+
+	// Refuse to build on applied migrations whose files were edited since.
+	if err := s.verifyChecksums(db); err != nil {
+		return result, err
 	}
 
 	// Load all migrations from the migration directory.
-	migrations, err := loadMigrations()
+	migrations, err := s.loadMigrations()
+	if err != nil {
+		return result, err
+	}
+
+	if s.captureWAL {
+		result.WALBefore = s.walPosition(db)
+	}
+
+	// Apply the migrations, timing the ones that actually run.
+	if s.parallelism > 1 {
+		err = s.migrateParallel(db, migrations, &result)
+	} else {
+		err = s.migrateSequential(db, migrations, &result)
+	}
+	if s.captureWAL {
+		s.printWALPositions(db, &result)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.TotalDuration = time.Since(start)
+	result.Print()
+
+	return result, nil
+}
+
+// migrateSequential applies the migrations one after another in version order,
+// recording the applied and skipped ones in result.
+func (s *settings) migrateSequential(db *pgxpool.Pool, migrations []Migration, result *MigrateResult) error {
+	for _, migration := range migrations {
+		migrationStart := time.Now()
+		applied, err := s.applyMigration(db, migration)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			result.Skipped++
+			continue
+		}
+		result.Record(migration, time.Since(migrationStart))
+	}
+	return nil
+}
+
+// PendingMigrations returns the migrations that have not been applied yet, in version order.
+func PendingMigrations(db *pgxpool.Pool) ([]Migration, error) {
+	s := packageSettings()
+	// Create the migrations table if it doesn't exist.
+	if err := s.createMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	migrations, err := s.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range migrations {
+		applied, err := s.isMigrationApplied(db, migration.Version)
+		if err != nil {
+			return nil, err
+		}
+		if !applied {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// MigrateVersions applies only the migrations with the given versions. Migrations are
+// always applied in version order, regardless of the order of versions.
+func MigrateVersions(db *pgxpool.Pool, versions []int64) error {
+	s := packageSettings()
+	release, err := s.lockMigrations(db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := s.createMigrationsTable(db); err != nil {
+		return err
+	}
+	if err := s.verifyChecksums(db); err != nil {
+		return err
+	}
+
+	migrations, err := s.loadMigrations()
 	if err != nil {
 		return err
 	}
 
-	// Apply each migration in sequence.
+	selected := make(map[int64]bool, len(versions))
+	for _, version := range versions {
+		selected[version] = true
+	}
+
+	// loadMigrations returns migrations sorted by version
 	for _, migration := range migrations {
-		if err := applyMigration(db, migration); err != nil {
+		if !selected[migration.Version] {
+			continue
+		}
+		if _, err := s.applyMigration(db, migration); err != nil {
 			return err
 		}
 	}
@@ -263,8 +830,15 @@ func Migrate(db *pgxpool.Pool) error {
 
 // RollbackLast rolls back the most recently applied migration.
 func RollbackLast(db *pgxpool.Pool) error {
+	s := packageSettings()
+	release, err := s.lockMigrations(db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Get the version of the latest applied migration.
-	latestMigration, err := getLatestMigration(db)
+	latestMigration, err := s.getLatestMigration(db)
 	if err != nil {
 		return err
 	}
@@ -276,7 +850,7 @@ func RollbackLast(db *pgxpool.Pool) error {
 	}
 
 	// Load all migrations from the migration directory.
-	migrations, err := loadMigrations()
+	migrations, err := s.loadMigrations()
 	if err != nil {
 		return err
 	}
@@ -292,11 +866,11 @@ func RollbackLast(db *pgxpool.Pool) error {
 
 	// If the migration to roll back is not found, return an error.
 	if migrationToRollback.Version == 0 {
-		return fmt.Errorf("migration %d not found", latestMigration)
+		return fmt.Errorf("%w: %d", ErrMigrationNotFound, latestMigration)
 	}
 
 	// Roll back the migration.
-	if err := rollbackMigration(db, migrationToRollback); err != nil {
+	if err := s.rollbackMigration(db, migrationToRollback); err != nil {
 		return err
 	}
 
@@ -305,10 +879,24 @@ func RollbackLast(db *pgxpool.Pool) error {
 	return nil
 }
 
-// RollbackSteps rolls back a specified number of migrations
-func RollbackSteps(db *pgxpool.Pool, steps int) error {
+// RollbackSteps rolls back a specified number of migrations. With dryRun their Down SQL
+// is printed instead.
+func RollbackSteps(db *pgxpool.Pool, steps int, dryRun bool) error {
+	return packageSettings().rollbackSteps(db, steps, dryRun)
+}
+
+// rollbackSteps rolls back the given number of migrations, see RollbackSteps.
+func (s *settings) rollbackSteps(db *pgxpool.Pool, steps int, dryRun bool) error {
+	if !dryRun {
+		release, err := s.lockMigrations(db)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
 	// Get all applied migrations
-	appliedMigrations, err := getAppliedMigrations(db)
+	appliedMigrations, err := s.getAppliedMigrations(db)
 	if err != nil {
 		return fmt.Errorf("failed to get applied migrations: %w", err)
 	}
@@ -333,10 +921,15 @@ func RollbackSteps(db *pgxpool.Pool, steps int) error {
 	// Rollback each migration
 	for i := 0; i < steps; i++ {
 		migration := appliedMigrations[i]
+		if dryRun {
+			dryRunRollback(migration)
+			continue
+		}
+
 		fmt.Printf("%s[ROLLBACK]%s Rolling back migration %s%d_%s%s... ",
 			ColorBlue, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset)
 
-		if err := rollbackMigration(db, migration); err != nil {
+		if err := s.rollbackMigration(db, migration); err != nil {
 			fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
 			return fmt.Errorf("failed to rollback migration %d_%s: %w",
 				migration.Version, migration.Name, err)
@@ -348,62 +941,190 @@ func RollbackSteps(db *pgxpool.Pool, steps int) error {
 	return nil
 }
 
-// MigrateFresh drops all tables and applies all migrations from scratch.
-func MigrateFresh(db *pgxpool.Pool) error {
+// Tables MigrateFresh keeps with their data, see SetPreservedTables
+var preservedTables []string
+
+// SetPreservedTables makes MigrateFresh keep the given tables, e.g. lookup tables that
+// are slow to repopulate. Their migrations must create them with IF NOT EXISTS.
+func SetPreservedTables(tables []string) {
+	preservedTables = tables
+}
+
+// MigrateFresh drops all tables and applies all migrations from scratch. With dryRun
+// the SQL of all migrations is printed instead and nothing is dropped.
+func MigrateFresh(db *pgxpool.Pool, dryRun bool) error {
+	return packageSettings().migrateFresh(db, dryRun)
+}
+
+// migrateFresh drops all tables and applies all migrations, see MigrateFresh.
+func (s *settings) migrateFresh(db *pgxpool.Pool, dryRun bool) error {
+	if dryRun {
+		fmt.Printf("%s[DRY RUN]%s All tables would be dropped", ColorYellow, ColorReset)
+		if len(s.preservedTables) > 0 {
+			fmt.Printf(", preserved: %s", strings.Join(s.preservedTables, ", "))
+		}
+		fmt.Println()
+
+		migrations, err := s.loadMigrations()
+		if err != nil {
+			return err
+		}
+		_, err = s.dryRunMigrate(db, migrations, true)
+		return err
+	}
+
+	// Make sure no other fresh migration runs at the same time.
+	fmt.Printf("%s[LOCK]%s Acquiring exclusive lock for fresh migration...\n", ColorBlue, ColorReset)
+	release, acquired, err := s.acquireAdvisoryLock(db, freshMigrationLockID)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return ErrFreshMigrationInProgress
+	}
+	defer release()
+
+	// Nor a regular migration or rollback.
+	releaseMigrations, err := s.lockMigrations(db)
+	if err != nil {
+		return err
+	}
+	defer releaseMigrations()
+
+	// Back up the database before anything is dropped.
+	if s.backupConfig != nil && s.backupConfig.BackupBeforeFresh {
+		if err := backupDatabase(s.ctx, s.backupConfig); err != nil {
+			return err
+		}
+	}
+
 	// Drop all tables in the database.
-	if err := dropAllTables(db); err != nil {
+	if err := s.dropAllTables(db, s.preservedTables); err != nil {
 		return err
 	}
 
-	fmt.Printf("%s[FRESH]%s All tables dropped successfully\n", ColorGreen, ColorReset)
+	if len(s.preservedTables) > 0 {
+		fmt.Printf("%s[FRESH]%s All tables dropped successfully, preserved: %s\n",
+			ColorGreen, ColorReset, strings.Join(s.preservedTables, ", "))
+	} else {
+		fmt.Printf("%s[FRESH]%s All tables dropped successfully\n", ColorGreen, ColorReset)
+	}
 	fmt.Printf("%s[FRESH]%s Reapplying all migrations...\n", ColorBlue, ColorReset)
 
-	// Apply all migrations.
-	return Migrate(db)
+	// Apply all migrations, the migration lock is already held.
+	_, err = s.migrate(db)
+	return err
 }
 
 // createMigrationsTable creates the migrations table if it doesn't exist.
-func createMigrationsTable(db *pgxpool.Pool) error {
-	_, err := db.Exec(context.Background(), `
-		CREATE TABLE IF NOT EXISTS migrations (
+// Tables created by older versions get the duration_ms and checksum columns added.
+func (s *settings) createMigrationsTable(db *pgxpool.Pool) error {
+	_, err := db.Exec(s.ctx, `
+		CREATE TABLE IF NOT EXISTS `+s.migrationsTable+` (
 			id SERIAL PRIMARY KEY,
 			version BIGINT NOT NULL,
 			name TEXT NOT NULL,
-			applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-		)
+			applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			duration_ms BIGINT,
+			checksum TEXT
+		);
+		ALTER TABLE `+s.migrationsTable+` ADD COLUMN IF NOT EXISTS duration_ms BIGINT;
+		ALTER TABLE `+s.migrationsTable+` ADD COLUMN IF NOT EXISTS checksum TEXT
 	`)
 	return err
 }
 
-// applyMigration applies a single migration to the database.
-func applyMigration(db *pgxpool.Pool, migration Migration) error {
+// applyMigration applies a single migration to the database. It reports whether the
+// migration was applied, false meaning it was skipped as already applied.
+func (s *settings) applyMigration(db *pgxpool.Pool, migration Migration) (bool, error) {
 	// Check if the migration has already been applied.
-	applied, err := isMigrationApplied(db, migration.Version)
+	applied, err := s.isMigrationApplied(db, migration.Version)
+	if err != nil {
+		return false, err
+	}
+
+	// If the migration has been applied, print a message and return.
+	if applied {
+		report.Skipped(migration)
+		return false, nil
+	}
+
+	return true, s.applyPendingMigration(db, migration)
+}
+
+// applyPendingMigration applies a migration the caller knows isn't applied yet.
+func (s *settings) applyPendingMigration(db *pgxpool.Pool, migration Migration) error {
+	if err := s.checkTimescaleDB(db, migration); err != nil {
+		return err
+	}
+
+	// Migrations marked with the no-transaction directive run statement by statement.
+	if migration.NoTransaction || s.noTransaction {
+		return s.applyMigrationWithoutTransaction(db, migration)
+	}
+
+	fmt.Printf("%s[MIGRATING]%s %s%d_%s%s... ",
+		ColorYellow,
+		ColorReset,
+		ColorCyan,
+		migration.Version,
+		migration.Name,
+		ColorReset,
+	)
+
+	if err := s.execMigration(db, migration); err != nil {
+		fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
+		return err
+	}
+
+	fmt.Printf("%sDONE%s\n", ColorGreen, ColorReset)
+	return nil
+}
+
+// execMigration runs the Up SQL of a migration and records it in a single
+// transaction, without printing progress.
+func (s *settings) execMigration(db *pgxpool.Pool, migration Migration) error {
+	start := time.Now()
+
+	checksum, err := s.fileChecksum(migration.Version, migration.Name)
 	if err != nil {
 		return err
 	}
 
-	// If the migration has been applied, print a message and return.
-	if applied {
-		fmt.Printf("%s[SKIPPED]%s Migration %s%d_%s%s already applied\n",
-			ColorYellow,
-			ColorReset,
-			ColorCyan,
-			migration.Version,
-			migration.Name,
-			ColorReset,
-		)
-		return nil
+	// Start a new transaction.
+	tx, err := db.Begin(s.ctx)
+	if err != nil {
+		return fmt.Errorf("%sfailed to start transaction: %w%s", ColorRed, err, ColorReset)
+	}
+	defer tx.Rollback(s.ctx)
+
+	// Convert SQL to lowercase before executing
+	lowercaseSQL := strings.ToLower(migration.UpSQL)
+
+	// Execute the up migration SQL script.
+	if _, err := tx.Exec(s.ctx, lowercaseSQL); err != nil {
+		return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	// Insert a record of the applied migration into the migrations table.
+	if _, err := tx.Exec(s.ctx, `
+		INSERT INTO `+s.migrationsTable+` (version, name, duration_ms, checksum) VALUES ($1, $2, $3, $4)
+	`, migration.Version, migration.Name, time.Since(start).Milliseconds(), checksum); err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
 	}
 
-	// Start a new transaction.
-	tx, err := db.Begin(context.Background())
-	if err != nil {
-		return fmt.Errorf("%sfailed to start transaction: %w%s", ColorRed, err, ColorReset)
+	// Commit the transaction.
+	if err := tx.Commit(s.ctx); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %w", migration.Version, migration.Name, err)
 	}
-	defer tx.Rollback(context.Background())
 
-	fmt.Printf("%s[MIGRATING]%s %s%d_%s%s... ",
+	return nil
+}
+
+// applyMigrationWithoutTransaction applies a migration by executing each statement
+// directly on the pool. A failure part-way through leaves earlier statements applied.
+func (s *settings) applyMigrationWithoutTransaction(db *pgxpool.Pool, migration Migration) error {
+	fmt.Printf("%s[MIGRATING]%s %s%d_%s%s (no transaction)... ",
 		ColorYellow,
 		ColorReset,
 		ColorCyan,
@@ -412,40 +1133,179 @@ func applyMigration(db *pgxpool.Pool, migration Migration) error {
 		ColorReset,
 	)
 
-	// Convert SQL to lowercase before executing
-	lowercaseSQL := strings.ToLower(migration.UpSQL)
+	start := time.Now()
 
-	// Execute the up migration SQL script.
-	if _, err := tx.Exec(context.Background(), lowercaseSQL); err != nil {
+	checksum, err := s.fileChecksum(migration.Version, migration.Name)
+	if err != nil {
 		fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
-		return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		return err
 	}
 
-	// Insert a record of the applied migration into the migrations table.
-	if _, err := tx.Exec(context.Background(), `
-		INSERT INTO migrations (version, name) VALUES ($1, $2)
-	`, migration.Version, migration.Name); err != nil {
-		fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
-		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+	// Each statement must be sent on its own, otherwise PostgreSQL wraps a
+	// multi-statement query in an implicit transaction. The SQL is not lowercased
+	// here since these migrations commonly carry literals (connection strings,
+	// enum values) whose case matters.
+	for _, stmt := range splitStatements(migration.UpSQL) {
+		if _, err := db.Exec(s.ctx, stmt); err != nil {
+			fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
+			return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
 	}
 
-	// Commit the transaction.
-	if err := tx.Commit(context.Background()); err != nil {
+	// Record the applied migration
+	if _, err := db.Exec(s.ctx, `
+		INSERT INTO `+s.migrationsTable+` (version, name, duration_ms, checksum) VALUES ($1, $2, $3, $4)
+	`, migration.Version, migration.Name, time.Since(start).Milliseconds(), checksum); err != nil {
 		fmt.Printf("%sFAILED%s\n", ColorRed, ColorReset)
-		return fmt.Errorf("failed to commit migration %d_%s: %w", migration.Version, migration.Name, err)
+		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
 	}
 
 	fmt.Printf("%sDONE%s\n", ColorGreen, ColorReset)
 	return nil
 }
 
+// checkTimescaleDB returns an error when the migration creates a hypertable but the
+// timescaledb extension isn't installed, instead of failing halfway through it.
+func (s *settings) checkTimescaleDB(db *pgxpool.Pool, migration Migration) error {
+	if !strings.Contains(migration.UpSQL, "create_hypertable") {
+		return nil
+	}
+
+	var extname string
+	err := db.QueryRow(s.ctx,
+		"SELECT extname FROM pg_extension WHERE extname = 'timescaledb'").Scan(&extname)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("migration %d_%s calls create_hypertable, but the timescaledb extension is not installed: "+
+			"run CREATE EXTENSION IF NOT EXISTS timescaledb; as a superuser first", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check for the timescaledb extension: %w", err)
+	}
+	return nil
+}
+
+// splitStatements splits a SQL script into individual statements on semicolons,
+// ignoring semicolons inside quoted strings, dollar-quoted bodies and comments.
+// Fragments consisting only of comments are dropped.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	var dollarTag string
+	inSingle, inDouble, inLineComment, inBlockComment := false, false, false, false
+
+	flush := func() {
+		stmt := strings.TrimSpace(current.String())
+		current.Reset()
+		for _, line := range strings.Split(stmt, "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "--") {
+				statements = append(statements, stmt)
+				return
+			}
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		next := byte(0)
+		if i+1 < len(script) {
+			next = script[i+1]
+		}
+
+		switch {
+		case inLineComment:
+			if c == '\n' {
+				inLineComment = false
+			}
+		case inBlockComment:
+			if c == '*' && next == '/' {
+				current.WriteByte(c)
+				i++
+				c = next
+				inBlockComment = false
+			}
+		case inSingle:
+			if c == '\'' {
+				inSingle = false
+			}
+		case inDouble:
+			if c == '"' {
+				inDouble = false
+			}
+		case dollarTag != "":
+			if strings.HasPrefix(script[i:], dollarTag) {
+				current.WriteString(dollarTag)
+				i += len(dollarTag) - 1
+				dollarTag = ""
+				continue
+			}
+		case c == '-' && next == '-':
+			inLineComment = true
+		case c == '/' && next == '*':
+			inBlockComment = true
+		case c == '\'':
+			inSingle = true
+		case c == '"':
+			inDouble = true
+		case c == '$':
+			if tag := dollarQuoteTag(script[i:]); tag != "" {
+				dollarTag = tag
+				current.WriteString(tag)
+				i += len(tag) - 1
+				continue
+			}
+		case c == ';':
+			flush()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	flush()
+
+	return statements
+}
+
+// dollarQuoteTag returns the dollar-quote opening tag ($$ or $name$) at the start of s,
+// or an empty string if s does not start with one.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if c == '$' {
+			return s[:i+1]
+		}
+		if !(c == '_' || unicode.IsLetter(rune(c)) || (i > 1 && unicode.IsDigit(rune(c)))) {
+			return ""
+		}
+	}
+	return ""
+}
+
 // rollbackMigration rolls back a single migration within a transaction
-func rollbackMigration(db *pgxpool.Pool, migration Migration) error {
-	tx, err := db.Begin(context.Background())
+func (s *settings) rollbackMigration(db *pgxpool.Pool, migration Migration) error {
+	if migration.FileMissing || migration.NoRollback {
+		reason := "cannot be reversed"
+		if migration.FileMissing {
+			reason = "has no migration file (deleted or squashed?)"
+		}
+		fmt.Printf("%s[WARN]%s Migration %d_%s %s, only removing its record\n",
+			ColorYellow, ColorReset, migration.Version, migration.Name, reason)
+		if _, err := db.Exec(s.ctx, `
+			DELETE FROM `+s.migrationsTable+` WHERE version = $1
+		`, migration.Version); err != nil {
+			return fmt.Errorf("failed to remove migration record: %w", err)
+		}
+		return nil
+	}
+
+	if migration.NoTransaction {
+		return s.rollbackMigrationWithoutTransaction(db, migration)
+	}
+
+	tx, err := db.Begin(s.ctx)
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %w", err)
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(s.ctx)
 
 	// Execute down migration
 	statements := strings.Split(migration.DownSQL, ";")
@@ -455,29 +1315,48 @@ func rollbackMigration(db *pgxpool.Pool, migration Migration) error {
 			continue
 		}
 
-		if _, err := tx.Exec(context.Background(), stmt); err != nil {
+		if _, err := tx.Exec(s.ctx, stmt); err != nil {
 			return fmt.Errorf("failed to execute down migration: %w", err)
 		}
 	}
 
 	// Remove migration record
-	if _, err := tx.Exec(context.Background(), `
-		DELETE FROM migrations WHERE version = $1
+	if _, err := tx.Exec(s.ctx, `
+		DELETE FROM `+s.migrationsTable+` WHERE version = $1
 	`, migration.Version); err != nil {
 		return fmt.Errorf("failed to remove migration record: %w", err)
 	}
 
-	if err := tx.Commit(context.Background()); err != nil {
+	if err := tx.Commit(s.ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
+// rollbackMigrationWithoutTransaction rolls back a migration marked with the
+// no-transaction directive by executing each down statement directly on the pool.
+func (s *settings) rollbackMigrationWithoutTransaction(db *pgxpool.Pool, migration Migration) error {
+	for _, stmt := range splitStatements(migration.DownSQL) {
+		if _, err := db.Exec(s.ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute down migration: %w", err)
+		}
+	}
+
+	// Remove migration record
+	if _, err := db.Exec(s.ctx, `
+		DELETE FROM `+s.migrationsTable+` WHERE version = $1
+	`, migration.Version); err != nil {
+		return fmt.Errorf("failed to remove migration record: %w", err)
+	}
+
+	return nil
+}
+
 // getAppliedMigrations returns all applied migrations from the database
-func getAppliedMigrations(db *pgxpool.Pool) ([]Migration, error) {
-	rows, err := db.Query(context.Background(), `
-		SELECT version, name FROM migrations 
+func (s *settings) getAppliedMigrations(db *pgxpool.Pool) ([]Migration, error) {
+	rows, err := db.Query(s.ctx, `
+		SELECT version, name FROM `+s.migrationsTable+` 
 		ORDER BY version DESC
 	`)
 	if err != nil {
@@ -494,9 +1373,16 @@ func getAppliedMigrations(db *pgxpool.Pool) ([]Migration, error) {
 
 		// Load migration file content
 		filename := fmt.Sprintf("%d_%s.sql", m.Version, m.Name)
-		filePath := filepath.Join(migrationPath, "sql", filename)
+		filePath := filepath.Join(s.migrationPath, "sql", filename)
 
+		// Files of applied migrations may be gone after squashing; their record can
+		// still be rolled back, without running any down SQL
 		content, err := os.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			m.FileMissing = true
+			migrations = append(migrations, m)
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to read migration file %s: %w", filename, err)
 		}
@@ -508,6 +1394,8 @@ func getAppliedMigrations(db *pgxpool.Pool) ([]Migration, error) {
 		}
 
 		m.DownSQL = strings.TrimSpace(parts[1])
+		m.NoTransaction = strings.Contains(parts[0], noTransactionDirective)
+		m.NoRollback = strings.Contains(string(content), noRollbackDirective)
 		migrations = append(migrations, m)
 	}
 
@@ -519,11 +1407,11 @@ func getAppliedMigrations(db *pgxpool.Pool) ([]Migration, error) {
 }
 
 // isMigrationApplied checks if a migration with a given version has already been applied.
-func isMigrationApplied(db *pgxpool.Pool, version int64) (bool, error) {
+func (s *settings) isMigrationApplied(db *pgxpool.Pool, version int64) (bool, error) {
 	var count int
 	// Query the migrations table to check if the migration has been applied.
-	err := db.QueryRow(context.Background(), `
-		SELECT COUNT(*) FROM migrations WHERE version = $1
+	err := db.QueryRow(s.ctx, `
+		SELECT COUNT(*) FROM `+s.migrationsTable+` WHERE version = $1
 	`, version).Scan(&count)
 
 	if err != nil {
@@ -534,11 +1422,11 @@ func isMigrationApplied(db *pgxpool.Pool, version int64) (bool, error) {
 }
 
 // getLatestMigration gets the version of the latest applied migration.
-func getLatestMigration(db *pgxpool.Pool) (int64, error) {
+func (s *settings) getLatestMigration(db *pgxpool.Pool) (int64, error) {
 	var version int64
 	// Query the migrations table to get the latest migration version.
-	err := db.QueryRow(context.Background(), `
-		SELECT COALESCE(MAX(version), 0) FROM migrations
+	err := db.QueryRow(s.ctx, `
+		SELECT COALESCE(MAX(version), 0) FROM `+s.migrationsTable+`
 	`).Scan(&version)
 
 	if err != nil {
@@ -548,58 +1436,173 @@ func getLatestMigration(db *pgxpool.Pool) (int64, error) {
 	return version, nil
 }
 
+// Number of Up SQL lines ListMigrations previews below each migration, see SetSQLPreview
+var sqlPreviewLines int
+
+// SetSQLPreview makes ListMigrations print the first maxLines non-empty lines of each
+// migration's Up SQL. 0 disables the preview.
+func SetSQLPreview(maxLines int) {
+	sqlPreviewLines = maxLines
+}
+
+// Description written into the header of new migration files, see SetMigrationComment
+var migrationComment string
+
+// SetMigrationComment makes new migration files start with a header holding comment
+// as the description, plus the current user and date. Empty writes no header.
+func SetMigrationComment(comment string) {
+	migrationComment = strings.Join(strings.Fields(comment), " ")
+}
+
+// Whether ListMigrations shows the author and description of each migration, see SetVerbose
+var verboseList bool
+
+// SetVerbose makes ListMigrations print the author and description from the header
+// of each migration below it.
+func SetVerbose(verbose bool) {
+	verboseList = verbose
+}
+
+// Filters of ListMigrations, see SetListFilter
+var listFilter report.ListFilter
+
+// SetListFilter makes ListMigrations show only the migrations that aren't applied yet,
+// and with countOnly print just the number of listed migrations as a bare integer, e.g.
+// for pending=$(jbmdb postgres-list --only-pending --count).
+func SetListFilter(onlyPending, countOnly bool) {
+	listFilter = report.ListFilter{OnlyPending: onlyPending, CountOnly: countOnly}
+}
+
 // ListMigrations retrieves and lists all migrations along with their status (applied or pending).
-func ListMigrations(db *pgxpool.Pool) error {
+// order is "asc" or "desc" by version, and a limit above 0 shows only that many.
+func ListMigrations(db *pgxpool.Pool, order string, limit int) error {
+	s := packageSettings()
 	// Load all migrations from files
-	migrations, err := loadMigrations()
+	migrations, err := s.loadMigrations()
 	if err != nil {
 		return fmt.Errorf("failed to load migrations: %w", err)
 	}
 
 	// Get all applied migrations from the database
-	rows, err := db.Query(context.Background(), "SELECT version, applied_at FROM migrations ORDER BY version")
+	appliedMigrations, err := s.appliedTimes(db)
 	if err != nil {
-		return fmt.Errorf("failed to query migrations table: %w", err)
+		return err
 	}
-	defer rows.Close()
 
-	// Create a map of applied migrations
-	appliedMigrations := make(map[int64]time.Time)
-	for rows.Next() {
-		var version int64
-		var appliedAt time.Time
-		if err := rows.Scan(&version, &appliedAt); err != nil {
-			return fmt.Errorf("failed to scan migration row: %w", err)
-		}
-		appliedMigrations[version] = appliedAt
+	// Filter before ordering, so a limit applies to the pending migrations
+	listed, done := report.FilterList(listFilter, migrations, func(m Migration) bool {
+		_, ok := appliedMigrations[m.Version]
+		return ok
+	})
+	if done {
+		return nil
+	}
+
+	shown, err := migfile.Order(listed, order, limit)
+	if err != nil {
+		return err
 	}
 
 	// Print header
-	fmt.Printf("\n%sMigration Status%s\n", ColorBold, ColorReset)
-	fmt.Println(strings.Repeat("-", 80))
-	fmt.Printf("%-20s %-30s %-15s %s\n", "Version", "Name", "Status", "Applied At")
-	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("\n%sMigration Status%s", ColorBold, ColorReset)
+	if limit > 0 {
+		position := "first"
+		if order == "desc" {
+			position = "last"
+		}
+		fmt.Printf(" (showing %s %d of %d)", position, len(shown), len(listed))
+	}
+	fmt.Println()
 
-	// Print each migration with its status
-	for _, m := range migrations {
+	header := []string{"Version", "Name", "Status", "Applied At"}
+	cells := make([][]string, len(shown))
+	colors := make([]string, len(shown))
+	for i, m := range shown {
 		appliedAt, isApplied := appliedMigrations[m.Version]
-		status := fmt.Sprintf("%sPending%s", ColorYellow, ColorReset)
-		appliedAtStr := "Not Applied"
+		cells[i] = []string{strconv.FormatInt(m.Version, 10), m.Name, "Pending", "Not Applied"}
+		colors[i] = ColorYellow
 		if isApplied {
-			status = fmt.Sprintf("%sApplied%s", ColorGreen, ColorReset)
-			appliedAtStr = appliedAt.Format("2006-01-02 15:04:05")
+			cells[i][2], cells[i][3] = "Applied", appliedAt.In(displayLocation).Format("2006-01-02 15:04:05 MST")
+			colors[i] = ColorGreen
 		}
-		fmt.Printf("%-20d %-30s %-15s %s\n", m.Version, m.Name, status, appliedAtStr)
 	}
-	fmt.Println(strings.Repeat("-", 80))
+	layout := newListLayout(header, cells, listFormat == "table")
+
+	layout.printTop()
+	layout.printRow(header, "")
+	layout.printSeparator()
+
+	// Print each migration with its status
+	for i, m := range shown {
+		layout.printRow(cells[i], colors[i])
+		if verboseList {
+			report.CommentHeader(m.Author, m.Description)
+		}
+		if sqlPreviewLines > 0 {
+			report.SQLPreview(m.UpSQL, sqlPreviewLines)
+		}
+	}
+	layout.printBottom()
 
 	return nil
 }
 
-// dropAllTables drops all user-created tables in the database, excluding system tables and extensions.
-func dropAllTables(db *pgxpool.Pool) error {
+// appliedTimes returns when each applied migration was applied, by version
+func (s *settings) appliedTimes(db *pgxpool.Pool) (map[int64]time.Time, error) {
+	rows, err := db.Query(s.ctx, "SELECT version, applied_at FROM "+s.migrationsTable+" ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query migrations table: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration row: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query migrations table: %w", err)
+	}
+	return applied, nil
+}
+
+// dropAllTables drops all user-created tables in the database, excluding system tables,
+// extensions and the tables in excludeTables. With a table prefix, only the tables,
+// views and types starting with it are dropped.
+func (s *settings) dropAllTables(db *pgxpool.Pool, excludeTables []string) error {
+	// DO blocks take no parameters, so the excluded names are inlined as literals.
+	// Types used by a preserved table are kept too, since dropping them with CASCADE
+	// would drop the table's columns.
+	exclude, excludeTypes, excludeStatistics := "", "", ""
+	if len(excludeTables) > 0 {
+		literals := make([]string, len(excludeTables))
+		for i, table := range excludeTables {
+			literals[i] = "'" + strings.ReplaceAll(table, "'", "''") + "'"
+		}
+		list := strings.Join(literals, ", ")
+		exclude = "AND tablename NOT IN (" + list + ")"
+		excludeTypes = `AND NOT EXISTS (
+						SELECT 1 FROM pg_attribute a
+						JOIN pg_class rel ON rel.oid = a.attrelid
+						WHERE a.atttypid = t.oid AND rel.relname IN (` + list + `)
+					)`
+		excludeStatistics = "AND s.stxrelid NOT IN (SELECT oid FROM pg_class WHERE relname IN (" + list + "))"
+	}
+
+	// With a table prefix only the application's own objects are dropped
+	prefixed := func(column string) string {
+		if s.tablePrefix == "" {
+			return ""
+		}
+		return "AND " + column + " LIKE '" + strings.ReplaceAll(s.tablePrefix, "_", `\_`) + "%'"
+	}
+
 	// Execute a PostgreSQL anonymous code block to drop all user-created tables in the current schema
-	_, err := db.Exec(context.Background(), `
+	_, err := db.Exec(s.ctx, `
 		DO $$ 
 		DECLARE
 			r RECORD;
@@ -607,6 +1610,27 @@ func dropAllTables(db *pgxpool.Pool) error {
 			-- Disable triggers temporarily
 			SET session_replication_role = 'replica';
 			
+			-- Drop materialized views first, then regular views, since both depend on tables
+			FOR r IN (
+				SELECT matviewname
+				FROM pg_matviews
+				WHERE schemaname = current_schema()
+					`+prefixed("matviewname")+`
+			) LOOP
+				EXECUTE 'DROP MATERIALIZED VIEW IF EXISTS ' || quote_ident(r.matviewname) || ' CASCADE';
+			END LOOP;
+			
+			FOR r IN (
+				SELECT viewname
+				FROM pg_views
+				WHERE schemaname = current_schema()
+					AND viewname != 'geography_columns'
+					AND viewname != 'geometry_columns'
+					`+prefixed("viewname")+`
+			) LOOP
+				EXECUTE 'DROP VIEW IF EXISTS ' || quote_ident(r.viewname) || ' CASCADE';
+			END LOOP;
+			
 			-- Drop all user-created tables, excluding system tables and extensions
 			FOR r IN (
 				SELECT tablename 
@@ -616,10 +1640,76 @@ func dropAllTables(db *pgxpool.Pool) error {
 					AND tablename NOT LIKE 'pg_%'       -- Exclude postgres system tables
 					AND tablename != 'geography_columns'
 					AND tablename != 'geometry_columns'
+					`+exclude+`
+					`+prefixed("tablename")+`
 			) LOOP
 				EXECUTE 'DROP TABLE IF EXISTS ' || quote_ident(r.tablename) || ' CASCADE';
 			END LOOP;
 			
+			-- Statistics objects on dropped tables are gone with them, drop any left over
+			FOR r IN (
+				SELECT s.stxname
+				FROM pg_statistic_ext s
+				WHERE s.stxnamespace = (SELECT oid FROM pg_namespace WHERE nspname = current_schema())
+					`+excludeStatistics+`
+					`+prefixed("s.stxname")+`
+			) LOOP
+				EXECUTE 'DROP STATISTICS IF EXISTS ' || quote_ident(r.stxname);
+			END LOOP;
+			
+			-- Drop range types first, as they may be built on the enums and composites below
+			FOR r IN (
+				SELECT t.typname
+				FROM pg_type t
+				WHERE t.typtype = 'r'
+					AND t.typnamespace = (SELECT oid FROM pg_namespace WHERE nspname = current_schema())
+					AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = t.oid AND d.deptype = 'e')
+					`+excludeTypes+`
+					`+prefixed("t.typname")+`
+			) LOOP
+				EXECUTE 'DROP TYPE IF EXISTS ' || quote_ident(r.typname) || ' CASCADE';
+			END LOOP;
+			
+			-- Drop standalone composite types, then enums, skipping types owned by extensions
+			FOR r IN (
+				SELECT t.typname
+				FROM pg_type t
+				JOIN pg_class c ON c.oid = t.typrelid
+				WHERE t.typtype = 'c'
+					AND c.relkind = 'c'
+					AND t.typnamespace = (SELECT oid FROM pg_namespace WHERE nspname = current_schema())
+					AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = t.oid AND d.deptype = 'e')
+					`+excludeTypes+`
+					`+prefixed("t.typname")+`
+			) LOOP
+				EXECUTE 'DROP TYPE IF EXISTS ' || quote_ident(r.typname) || ' CASCADE';
+			END LOOP;
+			
+			FOR r IN (
+				SELECT t.typname
+				FROM pg_type t
+				WHERE t.typtype = 'e'
+					AND t.typnamespace = (SELECT oid FROM pg_namespace WHERE nspname = current_schema())
+					AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = t.oid AND d.deptype = 'e')
+					`+excludeTypes+`
+					`+prefixed("t.typname")+`
+			) LOOP
+				EXECUTE 'DROP TYPE IF EXISTS ' || quote_ident(r.typname) || ' CASCADE';
+			END LOOP;
+			
+			-- Drop domains once the tables using them are gone
+			FOR r IN (
+				SELECT t.typname
+				FROM pg_type t
+				WHERE t.typtype = 'd'
+					AND t.typnamespace = (SELECT oid FROM pg_namespace WHERE nspname = current_schema())
+					AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = t.oid AND d.deptype = 'e')
+					`+excludeTypes+`
+					`+prefixed("t.typname")+`
+			) LOOP
+				EXECUTE 'DROP DOMAIN IF EXISTS ' || quote_ident(r.typname) || ' CASCADE';
+			END LOOP;
+			
 			-- Re-enable triggers
 			SET session_replication_role = 'origin';
 		END $$;
@@ -627,16 +1717,78 @@ func dropAllTables(db *pgxpool.Pool) error {
 	return err
 }
 
+// RefreshMaterializedView refreshes a materialized view without locking out concurrent reads.
+// REFRESH ... CONCURRENTLY requires the view to have at least one unique index. The view
+// may be schema qualified.
+func RefreshMaterializedView(db *pgxpool.Pool, name string) error {
+	if _, err := db.Exec(context.Background(),
+		"REFRESH MATERIALIZED VIEW CONCURRENTLY "+pgx.Identifier(strings.Split(name, ".")).Sanitize()); err != nil {
+		return fmt.Errorf("failed to refresh materialized view %s: %w", name, err)
+	}
+	return nil
+}
+
+// CreatePublication creates a logical replication publication for the given tables,
+// or for all tables when none are given. The connection must belong to a superuser.
+func CreatePublication(conn *pgx.Conn, name string, tables []string) error {
+	if _, err := conn.Exec(context.Background(), publicationSQL(name, tables)); err != nil {
+		return fmt.Errorf("error creating publication: %v", err)
+	}
+
+	fmt.Printf("%sPublication '%s' created successfully%s\n", ColorGreen, name, ColorReset)
+	return nil
+}
+
+// ListPolicies lists the row-level security policies defined in the current schema.
+func ListPolicies(db *pgxpool.Pool) error {
+	rows, err := db.Query(context.Background(), `
+		SELECT tablename, policyname, cmd, permissive, array_to_string(roles, ',')
+		FROM pg_policies
+		WHERE schemaname = current_schema()
+		ORDER BY tablename, policyname
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query policies: %w", err)
+	}
+	defer rows.Close()
+
+	// Print header
+	fmt.Printf("\n%sRow-Level Security Policies%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-20s %-25s %-10s %-12s %s\n", "Table", "Policy", "Command", "Type", "Roles")
+	fmt.Println(strings.Repeat("-", 80))
+
+	count := 0
+	for rows.Next() {
+		var table, policy, cmd, permissive, roles string
+		if err := rows.Scan(&table, &policy, &cmd, &permissive, &roles); err != nil {
+			return fmt.Errorf("failed to scan policy row: %w", err)
+		}
+		fmt.Printf("%-20s %s%-25s%s %-10s %-12s %s\n",
+			table, ColorCyan, policy, ColorReset, cmd, strings.ToLower(permissive), roles)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating policies: %w", err)
+	}
+
+	if count == 0 {
+		fmt.Printf("%sNo policies found%s\n", ColorYellow, ColorReset)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
+	return nil
+}
+
 // CreateDatabase creates a new database if it doesn't exist
 func CreateDatabase(pgConfig *config.PostgresConfig) error {
 	// Connect to postgres database to create new database
-	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/postgres?sslmode=disable",
-		pgConfig.SuperUser, pgConfig.SuperPass, pgConfig.Host, pgConfig.Port)
+	dbURL := ConnString(pgConfig, pgConfig.SuperUser, pgConfig.SuperPass, "postgres")
 
 	// Use pgx.Connect instead of pgxpool for admin operations
 	conn, err := pgx.Connect(context.Background(), dbURL)
 	if err != nil {
-		return fmt.Errorf("unable to connect to PostgreSQL: %v", err)
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
 	}
 	defer conn.Close(context.Background())
 
@@ -651,7 +1803,7 @@ func CreateDatabase(pgConfig *config.PostgresConfig) error {
 
 	if !exists {
 		_, err = conn.Exec(context.Background(),
-			fmt.Sprintf("CREATE DATABASE %s", pgConfig.DBName))
+			"CREATE DATABASE "+pgx.Identifier{pgConfig.DBName}.Sanitize())
 		if err != nil {
 			return fmt.Errorf("error creating database: %v", err)
 		}
@@ -665,16 +1817,57 @@ func CreateDatabase(pgConfig *config.PostgresConfig) error {
 	return nil
 }
 
+// DropDatabase drops the configured database. PostgreSQL refuses to drop a database
+// that still has open connections, so close other sessions first.
+func DropDatabase(pgConfig *config.PostgresConfig) error {
+	// Connect to the postgres database, since the target database cannot be dropped
+	// while connected to it
+	dbURL := ConnString(pgConfig, pgConfig.SuperUser, pgConfig.SuperPass, "postgres")
+
+	conn, err := pgx.Connect(context.Background(), dbURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(context.Background(),
+		"DROP DATABASE IF EXISTS "+pgx.Identifier{pgConfig.DBName}.Sanitize())
+	if err != nil {
+		return fmt.Errorf("error dropping database: %v", err)
+	}
+
+	fmt.Printf("%sDatabase '%s' dropped successfully%s\n",
+		ColorGreen, pgConfig.DBName, ColorReset)
+
+	return nil
+}
+
+// DropTable drops a single table outside of the migration flow. The table may be
+// schema qualified. With cascade, dependent objects such as views and foreign keys
+// are dropped too.
+func DropTable(db *pgxpool.Pool, table string, cascade bool) error {
+	stmt := "DROP TABLE IF EXISTS " + pgx.Identifier(strings.Split(table, ".")).Sanitize()
+	if cascade {
+		stmt += " CASCADE"
+	}
+
+	if _, err := db.Exec(context.Background(), stmt); err != nil {
+		return fmt.Errorf("error dropping table %s: %w", table, err)
+	}
+
+	fmt.Printf("%sTable '%s' dropped successfully%s\n", ColorGreen, table, ColorReset)
+	return nil
+}
+
 // CreateUser creates a new user if it doesn't exist and grants privileges
 func CreateUser(pgConfig *config.PostgresConfig, privileges string) error {
 	// Connect as super user
-	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/postgres?sslmode=disable",
-		pgConfig.SuperUser, pgConfig.SuperPass, pgConfig.Host, pgConfig.Port)
+	dbURL := ConnString(pgConfig, pgConfig.SuperUser, pgConfig.SuperPass, "postgres")
 
 	// Use pgx.Connect for admin operations
 	conn, err := pgx.Connect(context.Background(), dbURL)
 	if err != nil {
-		return fmt.Errorf("unable to connect to PostgreSQL: %v", err)
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
 	}
 	defer conn.Close(context.Background())
 
@@ -707,16 +1900,16 @@ func CreateUser(pgConfig *config.PostgresConfig, privileges string) error {
 	switch privileges {
 	case "all":
 		grantCmd = fmt.Sprintf("GRANT ALL PRIVILEGES ON DATABASE %s TO %s",
-			pgConfig.DBName, pgConfig.User)
+			pgx.Identifier{pgConfig.DBName}.Sanitize(), pgConfig.User)
 	case "read":
 		grantCmd = fmt.Sprintf("GRANT CONNECT, SELECT ON DATABASE %s TO %s",
-			pgConfig.DBName, pgConfig.User)
+			pgx.Identifier{pgConfig.DBName}.Sanitize(), pgConfig.User)
 	case "write":
 		grantCmd = fmt.Sprintf("GRANT CONNECT, SELECT, INSERT, UPDATE, DELETE ON DATABASE %s TO %s",
-			pgConfig.DBName, pgConfig.User)
+			pgx.Identifier{pgConfig.DBName}.Sanitize(), pgConfig.User)
 	case "admin":
 		grantCmd = fmt.Sprintf("GRANT ALL PRIVILEGES ON DATABASE %s TO %s WITH GRANT OPTION",
-			pgConfig.DBName, pgConfig.User)
+			pgx.Identifier{pgConfig.DBName}.Sanitize(), pgConfig.User)
 	default:
 		return fmt.Errorf("invalid privilege level: %s", privileges)
 	}
@@ -731,3 +1924,70 @@ func CreateUser(pgConfig *config.PostgresConfig, privileges string) error {
 
 	return nil
 }
+
+// tablePrivileges are the privileges accepted by GrantTablePrivilege and RevokeTablePrivilege.
+var tablePrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER", "ALL"}
+
+// tablePrivilegeTarget validates the privilege and splits an optionally schema-qualified
+// table name. An unqualified name is left unqualified, so Postgres resolves it through
+// the search_path.
+func tablePrivilegeTarget(table, privilege string) (pgx.Identifier, string, error) {
+	privilege = strings.ToUpper(privilege)
+	valid := false
+	for _, p := range tablePrivileges {
+		if privilege == p {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, "", fmt.Errorf("invalid table privilege: %s (valid: %s)",
+			privilege, strings.Join(tablePrivileges, ", "))
+	}
+
+	schema, name, found := strings.Cut(table, ".")
+	if !found {
+		return pgx.Identifier{table}, privilege, nil
+	}
+
+	return pgx.Identifier{schema, name}, privilege, nil
+}
+
+// GrantTablePrivilege grants a single privilege on a table to a user.
+// The table may be schema-qualified; an unqualified one is resolved through the search_path.
+func GrantTablePrivilege(conn *pgx.Conn, table, user, privilege string) error {
+	target, privilege, err := tablePrivilegeTarget(table, privilege)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(context.Background(), fmt.Sprintf("GRANT %s ON TABLE %s TO %s",
+		privilege, target.Sanitize(), pgx.Identifier{user}.Sanitize()))
+	if err != nil {
+		return fmt.Errorf("error granting privilege: %v", err)
+	}
+
+	fmt.Printf("%sPrivilege '%s' granted to user '%s' on table '%s'%s\n",
+		ColorGreen, privilege, user, strings.Join(target, "."), ColorReset)
+
+	return nil
+}
+
+// RevokeTablePrivilege revokes a single privilege on a table from a user.
+func RevokeTablePrivilege(conn *pgx.Conn, table, user, privilege string) error {
+	target, privilege, err := tablePrivilegeTarget(table, privilege)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(context.Background(), fmt.Sprintf("REVOKE %s ON TABLE %s FROM %s",
+		privilege, target.Sanitize(), pgx.Identifier{user}.Sanitize()))
+	if err != nil {
+		return fmt.Errorf("error revoking privilege: %v", err)
+	}
+
+	fmt.Printf("%sPrivilege '%s' revoked from user '%s' on table '%s'%s\n",
+		ColorGreen, privilege, user, strings.Join(target, "."), ColorReset)
+
+	return nil
+}
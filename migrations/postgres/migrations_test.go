@@ -0,0 +1,254 @@
+package postgres
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tempMigrationPath points the migration path at a new directory with an empty sql
+// directory for the duration of the test, and returns the sql directory
+func tempMigrationPath(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	sqlPath := filepath.Join(dir, "sql")
+	if err := os.Mkdir(sqlPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := migrationPath
+	SetMigrationPath(dir)
+	t.Cleanup(func() { SetMigrationPath(previous) })
+	return sqlPath
+}
+
+func TestParseColumnMigrationName(t *testing.T) {
+	tests := []struct {
+		name                  string
+		action, column, table string
+		ok                    bool
+	}{
+		{name: "add_email_to_users", action: "add", column: "email", table: "users", ok: true},
+		{name: "add_last_login_at_to_user_profiles", action: "add", column: "last_login_at", table: "user_profiles", ok: true},
+		{name: "remove_email_from_users", action: "remove", column: "email", table: "users", ok: true},
+		{name: "remove_avatar_url_from_user_profiles", action: "remove", column: "avatar_url", table: "user_profiles", ok: true},
+		{name: "add_email_from_users"},
+		{name: "remove_email_to_users"},
+		{name: "add_email"},
+		{name: "add__to_users"},
+		{name: "create_users_table"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, column, table, ok := parseColumnMigrationName(tt.name)
+			if ok != tt.ok || action != tt.action || column != tt.column || table != tt.table {
+				t.Errorf("parseColumnMigrationName(%q) = %q, %q, %q, %v, want %q, %q, %q, %v",
+					tt.name, action, column, table, ok, tt.action, tt.column, tt.table, tt.ok)
+			}
+			if got := IsColumnMigrationName(tt.name); got != tt.ok {
+				t.Errorf("IsColumnMigrationName(%q) = %v, want %v", tt.name, got, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExtractTableNameOfColumnMigrations(t *testing.T) {
+	tests := map[string]string{
+		"add_email_to_users":                 "users",
+		"add_last_login_at_to_user_profiles": "user_profiles",
+		"remove_email_from_users":            "users",
+		"create_users_table":                 "users",
+	}
+
+	for name, want := range tests {
+		if got := extractTableName(name); got != want {
+			t.Errorf("extractTableName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCreateColumnMigration(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		up, down string
+	}{
+		{
+			name:   "add_email_to_users",
+			action: "add",
+			up:     "ALTER TABLE users ADD COLUMN email TEXT;",
+			down:   "ALTER TABLE users DROP COLUMN IF EXISTS email;",
+		},
+		{
+			name:   "remove_email_from_users",
+			action: "remove",
+			up:     "ALTER TABLE users DROP COLUMN IF EXISTS email;",
+			down:   "ALTER TABLE users ADD COLUMN email TEXT;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqlPath := tempMigrationPath(t)
+			if err := createColumnMigration(tt.name, tt.action, "email", "users"); err != nil {
+				t.Fatal(err)
+			}
+
+			migrations, err := loadMigrationsFrom(sqlPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(migrations) != 1 || migrations[0].Name != tt.name {
+				t.Fatalf("loaded %v, want a single %s migration", migrations, tt.name)
+			}
+			if !strings.Contains(migrations[0].UpSQL, tt.up) {
+				t.Errorf("Up SQL %q doesn't contain %q", migrations[0].UpSQL, tt.up)
+			}
+			if !strings.Contains(migrations[0].DownSQL, tt.down) {
+				t.Errorf("Down SQL %q doesn't contain %q", migrations[0].DownSQL, tt.down)
+			}
+		})
+	}
+}
+
+func TestLoadMigrationsRejectsNonNumericVersion(t *testing.T) {
+	sqlPath := tempMigrationPath(t)
+	content := "-- Up Migration\nCREATE TABLE users (id BIGINT);\n\n-- Down Migration\nDROP TABLE users;\n"
+	if err := os.WriteFile(filepath.Join(sqlPath, "notanumber_create_users_table.sql"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := packageSettings().loadMigrations()
+	if err == nil {
+		t.Fatalf("loadMigrations() = %v, want an error for the non-numeric version", migrations)
+	}
+	if !strings.Contains(err.Error(), "notanumber_create_users_table.sql") {
+		t.Errorf("error %q doesn't name the migration file", err)
+	}
+}
+
+func TestTemplatesApplyTablePrefix(t *testing.T) {
+	tests := []struct {
+		name      string
+		create    func() error
+		migration string
+		up, down  string
+	}{
+		{
+			name:      "unlogged table",
+			create:    func() error { return CreateUnloggedTableMigration("sessions") },
+			migration: "create_sessions_table",
+			up:        "CREATE UNLOGGED TABLE IF NOT EXISTS app_sessions (",
+			down:      "DROP TABLE IF EXISTS app_sessions;",
+		},
+		{
+			name: "range partition",
+			create: func() error {
+				return CreateRangePartitionMigration("events", "created_on", []string{"2024-01-01", "2025-01-01"})
+			},
+			migration: "create_events_table",
+			up:        "CREATE TABLE IF NOT EXISTS app_events_2024 PARTITION OF app_events",
+			down:      "DROP TABLE IF EXISTS app_events;",
+		},
+		{
+			name:      "hypertable",
+			create:    func() error { return CreateHypertableMigration("readings", "recorded_at") },
+			migration: "create_readings_table",
+			up:        "SELECT create_hypertable('app_readings', 'recorded_at'",
+			down:      "DROP TABLE IF EXISTS app_readings;",
+		},
+		{
+			name:      "materialized view",
+			create:    func() error { return CreateMaterializedViewMigration("daily_sales") },
+			migration: "create_daily_sales_materialized_view",
+			up:        "CREATE MATERIALIZED VIEW IF NOT EXISTS app_daily_sales AS",
+			down:      "DROP MATERIALIZED VIEW IF EXISTS app_daily_sales;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqlPath := tempMigrationPath(t)
+			previous := tablePrefix
+			if err := SetTablePrefix("app"); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { SetTablePrefix(previous) })
+
+			if err := tt.create(); err != nil {
+				t.Fatal(err)
+			}
+
+			migrations, err := loadMigrationsFrom(sqlPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(migrations) != 1 || migrations[0].Name != tt.migration {
+				t.Fatalf("loaded %v, want a single %s migration", migrations, tt.migration)
+			}
+			if !strings.Contains(migrations[0].UpSQL, tt.up) {
+				t.Errorf("Up SQL %q doesn't contain %q", migrations[0].UpSQL, tt.up)
+			}
+			if !strings.Contains(migrations[0].DownSQL, tt.down) {
+				t.Errorf("Down SQL %q doesn't contain %q", migrations[0].DownSQL, tt.down)
+			}
+		})
+	}
+}
+
+func TestTablePrivilegeTarget(t *testing.T) {
+	tests := map[string]string{
+		"users":           `"users"`,
+		"billing.invoice": `"billing"."invoice"`,
+	}
+
+	for table, want := range tests {
+		target, privilege, err := tablePrivilegeTarget(table, "select")
+		if err != nil {
+			t.Fatalf("tablePrivilegeTarget(%q): %v", table, err)
+		}
+		if got := target.Sanitize(); got != want {
+			t.Errorf("tablePrivilegeTarget(%q) target = %s, want %s", table, got, want)
+		}
+		if privilege != "SELECT" {
+			t.Errorf("tablePrivilegeTarget(%q) privilege = %q, want SELECT", table, privilege)
+		}
+	}
+
+	if _, _, err := tablePrivilegeTarget("users", "own"); err == nil {
+		t.Error("tablePrivilegeTarget accepted an invalid privilege")
+	}
+}
+
+func TestCreateDataMigration(t *testing.T) {
+	sqlPath := tempMigrationPath(t)
+
+	if err := CreateDataMigration("Backfill_Users_Full_Name"); err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := loadMigrationsFrom(sqlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 || migrations[0].Name != "backfill_users_full_name" {
+		t.Fatalf("loaded %v, want a single backfill_users_full_name migration", migrations)
+	}
+
+	migration := migrations[0]
+	if migration.NoTransaction {
+		t.Error("data migration runs without a transaction")
+	}
+	schema := strings.Index(migration.UpSQL, "-- Schema change")
+	data := strings.Index(migration.UpSQL, "-- Data migration")
+	cleanup := strings.Index(migration.UpSQL, "-- Post-migration cleanup")
+	if schema < 0 || data < schema || cleanup < data {
+		t.Errorf("Up SQL %q doesn't hold the schema change, data migration and cleanup sections in order", migration.UpSQL)
+	}
+	if !strings.Contains(migration.UpSQL, "-- WARNING: data migrations on large tables may be slow and lock tables") {
+		t.Errorf("Up SQL %q doesn't warn about large tables", migration.UpSQL)
+	}
+}
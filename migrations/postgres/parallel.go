@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jbarasa/jbmdb/migrations/internal/report"
+	"golang.org/x/sync/semaphore"
+)
+
+// Number of migrations Migrate applies concurrently, see SetParallelism.
+var parallelism = 1
+
+// SetParallelism sets how many independent migrations Migrate may apply at once.
+// Values below 2 apply migrations one at a time.
+func SetParallelism(n int) {
+	parallelism = max(n, 1)
+}
+
+// tableReferencePattern captures the names following keywords that introduce a table.
+// It over-matches (e.g. "ON DELETE"), which only makes grouping more conservative.
+var tableReferencePattern = regexp.MustCompile(`(?i)\b(?:TABLE(?:\s+IF\s+(?:NOT\s+)?EXISTS)?|REFERENCES|ON|FROM|JOIN|INTO|UPDATE)\s+(?:ONLY\s+)?"?([a-z_][a-z0-9_.]*)"?`)
+
+// sharedObjectPattern matches statements on objects other than tables that later
+// migrations may depend on without naming a table, such as a column of an enum type.
+var sharedObjectPattern = regexp.MustCompile(`(?i)\b(?:CREATE|ALTER|DROP)\s+(?:OR\s+REPLACE\s+)?(?:TYPE|DOMAIN|EXTENSION|SCHEMA|SEQUENCE|FUNCTION|PROCEDURE|ROLE|PUBLICATION)\b`)
+
+// referencedTables returns the lowercased table names referenced by the SQL
+func referencedTables(sql string) map[string]bool {
+	tables := make(map[string]bool)
+	for _, match := range tableReferencePattern.FindAllStringSubmatch(sql, -1) {
+		tables[strings.ToLower(match[1])] = true
+	}
+	return tables
+}
+
+// groupIndependentMigrations splits migrations, in version order, into consecutive
+// groups whose members reference no common table. A migration that creates shared
+// objects, runs without a transaction or references no table gets a group of its own.
+func (s *settings) groupIndependentMigrations(migrations []Migration) [][]Migration {
+	var groups [][]Migration
+	var current []Migration
+	currentTables := make(map[string]bool)
+
+	flush := func() {
+		if len(current) > 0 {
+			groups = append(groups, current)
+		}
+		current = nil
+		currentTables = make(map[string]bool)
+	}
+
+	for _, migration := range migrations {
+		tables := referencedTables(migration.UpSQL)
+		if len(tables) == 0 || migration.NoTransaction || s.noTransaction ||
+			sharedObjectPattern.MatchString(migration.UpSQL) {
+			flush()
+			groups = append(groups, []Migration{migration})
+			continue
+		}
+
+		for table := range tables {
+			if currentTables[table] {
+				flush()
+				break
+			}
+		}
+
+		current = append(current, migration)
+		for table := range tables {
+			currentTables[table] = true
+		}
+	}
+	flush()
+
+	return groups
+}
+
+// migrateParallel applies the pending migrations in groups of independent migrations,
+// running the members of each group concurrently on up to parallelism connections.
+// Groups run one after another, so a migration still sees every earlier group applied.
+// The applied and skipped migrations are recorded in result.
+func (s *settings) migrateParallel(db *pgxpool.Pool, migrations []Migration, result *MigrateResult) error {
+	var pending []Migration
+	for _, migration := range migrations {
+		alreadyApplied, err := s.isMigrationApplied(db, migration.Version)
+		if err != nil {
+			return err
+		}
+		if alreadyApplied {
+			report.Skipped(migration)
+			result.Skipped++
+			continue
+		}
+		pending = append(pending, migration)
+	}
+
+	for _, group := range s.groupIndependentMigrations(pending) {
+		if len(group) == 1 {
+			migrationStart := time.Now()
+			if err := s.applyPendingMigration(db, group[0]); err != nil {
+				return err
+			}
+			result.Record(group[0], time.Since(migrationStart))
+			continue
+		}
+
+		if err := s.applyConcurrently(db, group, result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyConcurrently applies a group of independent migrations, each in its own
+// transaction, with at most parallelism running at a time. Every migration is
+// attempted unless the context is cancelled; the ones that succeeded are recorded in
+// result and the errors of the failed ones are returned together. It only returns
+// once every started migration has finished.
+func (s *settings) applyConcurrently(db *pgxpool.Pool, group []Migration, result *MigrateResult) error {
+	fmt.Printf("%s[PARALLEL]%s Applying %d independent migrations concurrently\n",
+		ColorCyan, ColorReset, len(group))
+
+	sem := semaphore.NewWeighted(int64(s.parallelism))
+	durations := make([]time.Duration, len(group))
+	errs := make([]error, len(group))
+
+	// Serializes the progress lines of the goroutines
+	var output sync.Mutex
+	var wg sync.WaitGroup
+	var acquireErr error
+	started := 0
+	for i, migration := range group {
+		if err := sem.Acquire(s.ctx, 1); err != nil {
+			acquireErr = err
+			break
+		}
+		started++
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			start := time.Now()
+			errs[i] = s.execMigration(db, migration)
+			durations[i] = time.Since(start)
+
+			status := ColorGreen + "DONE" + ColorReset
+			if errs[i] != nil {
+				status = ColorRed + "FAILED" + ColorReset
+			}
+
+			output.Lock()
+			fmt.Printf("%s[MIGRATING]%s %s%d_%s%s... %s (%.2fs)\n",
+				ColorYellow, ColorReset, ColorCyan, migration.Version, migration.Name, ColorReset,
+				status, durations[i].Seconds())
+			output.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// Each migration commits on its own, so the successful ones stay applied even
+	// when others in the group failed
+	for i, migration := range group[:started] {
+		if errs[i] == nil {
+			result.Record(migration, durations[i])
+		}
+	}
+
+	return errors.Join(append(errs, acquireErr)...)
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RoleOptions holds the attributes of a role created by CreateRole
+type RoleOptions struct {
+	CanLogin          bool // LOGIN, a role that can log in is a user
+	Superuser         bool // SUPERUSER, bypasses all permission checks
+	InheritPrivileges bool // INHERIT, use the privileges of granted roles without SET ROLE
+}
+
+// CreateRole creates a role with the given attributes if it doesn't exist.
+// Grant it to users with GrantRole to share its privileges.
+func CreateRole(conn *pgx.Conn, name string, opts RoleOptions) error {
+	var exists bool
+	if err := conn.QueryRow(context.Background(),
+		"SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)", name).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking role existence: %v", err)
+	}
+	if exists {
+		fmt.Printf("%sRole '%s' already exists%s\n", ColorBlue, name, ColorReset)
+		return nil
+	}
+
+	attributes := []string{"NOLOGIN", "NOSUPERUSER", "NOINHERIT"}
+	if opts.CanLogin {
+		attributes[0] = "LOGIN"
+	}
+	if opts.Superuser {
+		attributes[1] = "SUPERUSER"
+	}
+	if opts.InheritPrivileges {
+		attributes[2] = "INHERIT"
+	}
+
+	_, err := conn.Exec(context.Background(), fmt.Sprintf("CREATE ROLE %s WITH %s",
+		pgx.Identifier{name}.Sanitize(), strings.Join(attributes, " ")))
+	if err != nil {
+		return fmt.Errorf("error creating role: %v", err)
+	}
+
+	fmt.Printf("%sRole '%s' created successfully (%s)%s\n",
+		ColorGreen, name, strings.Join(attributes, ", "), ColorReset)
+	return nil
+}
+
+// GrantRole makes user a member of role, giving it the role's privileges
+func GrantRole(conn *pgx.Conn, role, user string) error {
+	_, err := conn.Exec(context.Background(), fmt.Sprintf("GRANT %s TO %s",
+		pgx.Identifier{role}.Sanitize(), pgx.Identifier{user}.Sanitize()))
+	if err != nil {
+		return fmt.Errorf("error granting role: %v", err)
+	}
+
+	fmt.Printf("%sRole '%s' granted to '%s'%s\n", ColorGreen, role, user, ColorReset)
+	return nil
+}
+
+// ListRoles prints every role except the built-in pg_ roles, with its attributes
+// and the roles it is a member of.
+func ListRoles(db *pgxpool.Pool) error {
+	rows, err := db.Query(context.Background(), `
+		SELECT r.rolname, r.rolcanlogin, r.rolsuper, r.rolinherit,
+			COALESCE(string_agg(m.rolname, ', ' ORDER BY m.rolname), '')
+		FROM pg_roles r
+		LEFT JOIN pg_auth_members am ON am.member = r.oid
+		LEFT JOIN pg_roles m ON m.oid = am.roleid
+		WHERE r.rolname NOT LIKE 'pg\_%'
+		GROUP BY r.rolname, r.rolcanlogin, r.rolsuper, r.rolinherit
+		ORDER BY r.rolname
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query roles: %w", err)
+	}
+	defer rows.Close()
+
+	// Print header
+	fmt.Printf("\n%sRoles%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-25s %-7s %-10s %-8s %s\n", "Name", "Login", "Superuser", "Inherit", "Member Of")
+	fmt.Println(strings.Repeat("-", 80))
+
+	yesNo := map[bool]string{true: "yes", false: "no"}
+	for rows.Next() {
+		var name, memberOf string
+		var canLogin, superuser, inherit bool
+		if err := rows.Scan(&name, &canLogin, &superuser, &inherit, &memberOf); err != nil {
+			return fmt.Errorf("failed to scan role row: %w", err)
+		}
+		fmt.Printf("%s%-25s%s %-7s %-10s %-8s %s\n", ColorCyan, name, ColorReset,
+			yesNo[canLogin], yesNo[superuser], yesNo[inherit], memberOf)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating roles: %w", err)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
+	return nil
+}
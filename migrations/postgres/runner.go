@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MigrationStatus is a migration file and whether it's applied, as returned by Runner.List.
+type MigrationStatus struct {
+	Version   int64     // The version of the migration.
+	Name      string    // The name of the migration.
+	Applied   bool      // Whether the migration is recorded in the migrations table.
+	AppliedAt time.Time // When the migration was applied, zero if it's pending.
+}
+
+// Runner applies the migrations in a directory to a database, for applications that
+// migrate on startup instead of through the CLI. The pool is owned by the caller. A
+// Runner doesn't use the package-level settings set by the Set* functions: it records
+// migrations in the migrations table and applies them one at a time in transactions.
+type Runner struct {
+	pool          *pgxpool.Pool
+	migrationPath string
+}
+
+// NewRunner returns a Runner for the migrations in migrationPath, which holds the sql
+// directory like the migration_path of the config.
+func NewRunner(pool *pgxpool.Pool, migrationPath string) *Runner {
+	return &Runner{pool: pool, migrationPath: migrationPath}
+}
+
+// settings returns the settings the calls of r run with, using ctx for their queries
+func (r *Runner) settings(ctx context.Context) *settings {
+	return &settings{
+		ctx:             ctx,
+		migrationPath:   r.migrationPath,
+		migrationsTable: "migrations",
+		parallelism:     1,
+	}
+}
+
+// Run applies all pending migrations, see Migrate.
+func (r *Runner) Run(ctx context.Context) error {
+	_, err := r.settings(ctx).migrateAll(r.pool, false)
+	return err
+}
+
+// Rollback rolls back the given number of most recently applied migrations, see RollbackSteps.
+func (r *Runner) Rollback(ctx context.Context, steps int) error {
+	return r.settings(ctx).rollbackSteps(r.pool, steps, false)
+}
+
+// Fresh drops all tables and applies all migrations from scratch, see MigrateFresh. It
+// doesn't ask for confirmation.
+func (r *Runner) Fresh(ctx context.Context) error {
+	return r.settings(ctx).migrateFresh(r.pool, false)
+}
+
+// List returns every migration file in version order with whether it's applied,
+// without printing anything.
+func (r *Runner) List(ctx context.Context) ([]MigrationStatus, error) {
+	s := r.settings(ctx)
+	if err := s.createMigrationsTable(r.pool); err != nil {
+		return nil, err
+	}
+
+	migrations, err := s.loadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	applied, err := s.appliedTimes(r.pool)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		statuses[i] = MigrationStatus{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: appliedAt}
+	}
+	return statuses, nil
+}
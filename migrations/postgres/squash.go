@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jbarasa/jbmdb/migrations/internal/migfile"
+)
+
+// baselineName is the name of the migration Squash writes
+const baselineName = "baseline"
+
+// squashDropStages generate the Down SQL of the baseline: tables drop their views,
+// indexes and owned sequences with CASCADE, the remaining sequences, routines and types
+// follow.
+var squashDropStages = []cloneStage{
+	{"tables", `
+		SELECT format('DROP TABLE IF EXISTS %I.%I CASCADE', n.nspname, c.relname)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND NOT c.relispartition AND ` + fmt.Sprintf(userObject, "c.oid") + `
+		ORDER BY c.oid DESC`, false},
+	{"sequences", `
+		SELECT format('DROP SEQUENCE IF EXISTS %I.%I', n.nspname, c.relname)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind = 'S' AND ` + fmt.Sprintf(userObject, "c.oid") + `
+		ORDER BY c.oid DESC`, false},
+	{"routines", `
+		SELECT format('DROP ROUTINE IF EXISTS %I.%I(%s) CASCADE', n.nspname, p.proname, pg_get_function_identity_arguments(p.oid))
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE p.prokind IN ('f', 'p') AND ` + fmt.Sprintf(userObject, "p.oid") + `
+		ORDER BY p.oid DESC`, false},
+	{"types", `
+		SELECT format('DROP TYPE IF EXISTS %I.%I CASCADE', n.nspname, t.typname)
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		LEFT JOIN pg_class c ON c.oid = t.typrelid
+		WHERE (t.typtype = 'e' OR c.relkind = 'c') AND ` + fmt.Sprintf(userObject, "t.oid") + `
+		ORDER BY t.oid DESC`, false},
+}
+
+// migrationsTablePattern matches statements about the migrations table or its id
+// sequence, which the baseline must not create or drop
+func migrationsTablePattern() *regexp.Regexp {
+	return regexp.MustCompile(`\.` + regexp.QuoteMeta(migrationsTable) + `(_id_seq)?\b`)
+}
+
+// Squash replaces all applied migrations with a single baseline migration: its Up SQL
+// recreates the current schema from the catalog, like CloneSchema, and its Down SQL
+// drops it. The old migration files are deleted and the migrations table records only
+// the baseline. Pending migrations must be applied first.
+func Squash(db *pgxpool.Pool) error {
+	ctx := context.Background()
+
+	// The schema of the whole database ends up in the baseline
+	if tablePrefix != "" {
+		return fmt.Errorf("postgres-squash does not support a table prefix: the baseline would include the tables of every application")
+	}
+
+	// No migration may be applied between the pending check and rewriting the records
+	s := packageSettings()
+	release, err := s.lockMigrations(db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d migrations are not applied yet, run postgres-migrate before squashing", len(pending))
+	}
+
+	applied, err := s.getAppliedMigrations(db)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	if len(applied) == 0 {
+		fmt.Printf("%sNo applied migrations to squash%s\n", ColorYellow, ColorReset)
+		return nil
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer conn.Release()
+
+	up, err := squashStatements(conn.Conn(), cloneStages)
+	if err != nil {
+		return err
+	}
+	down, err := squashStatements(conn.Conn(), squashDropStages)
+	if err != nil {
+		return err
+	}
+
+	// Run statement by statement: execMigration lowercases the SQL, which would
+	// change enum labels and string defaults of the dumped schema
+	up = fmt.Sprintf(`%s
+-- Baseline of %d squashed migrations, generated from the database schema
+%s`, noTransactionDirective, len(applied), up)
+
+	sqlPath := filepath.Join(migrationPath, "sql")
+	timestamp, err := migfile.FreeTimestamp(sqlPath, time.Now(), ErrDuplicateVersion)
+	if err != nil {
+		return err
+	}
+	if err := writeMigrationFileTo(sqlPath, timestamp, baselineName, up, down); err != nil {
+		return err
+	}
+	baselineFile := fmt.Sprintf("%s_%s.sql", timestamp, baselineName)
+	version, err := parseInt(timestamp)
+	if err != nil {
+		return err
+	}
+
+	if err := recordBaseline(db, version); err != nil {
+		// Nothing changed yet, so don't leave a pending baseline behind
+		os.Remove(filepath.Join(sqlPath, baselineFile))
+		return err
+	}
+
+	// The records are replaced, so the old files can go
+	files, err := filepath.Glob(filepath.Join(sqlPath, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("failed to list migration files: %w", err)
+	}
+	removed := 0
+	for _, file := range files {
+		if filepath.Base(file) == baselineFile {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", file, err)
+		}
+		removed++
+	}
+
+	fmt.Printf("%s[SQUASH]%s %d migrations squashed into %s%s%s, %d files removed\n",
+		ColorGreen, ColorReset, len(applied), ColorCyan, baselineFile, ColorReset, removed)
+	return nil
+}
+
+// squashStatements runs the catalog queries of stages and joins their statements into
+// a script, leaving out the migrations table
+func squashStatements(conn *pgx.Conn, stages []cloneStage) (string, error) {
+	pattern := migrationsTablePattern()
+
+	var script []string
+	for _, stage := range stages {
+		statements, err := queryStatements(conn, stage.query)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", stage.kind, err)
+		}
+		for _, stmt := range statements {
+			if !pattern.MatchString(stmt) {
+				script = append(script, strings.TrimSuffix(stmt, ";")+";")
+			}
+		}
+	}
+	return strings.Join(script, "\n\n"), nil
+}
+
+// recordBaseline replaces all records of the migrations table with the baseline in a
+// single transaction
+func recordBaseline(db *pgxpool.Pool, version int64) error {
+	ctx := context.Background()
+
+	checksum, err := packageSettings().fileChecksum(version, baselineName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM "+migrationsTable); err != nil {
+		return fmt.Errorf("failed to clear migrations table: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO "+migrationsTable+" (version, name, checksum) VALUES ($1, $2, $3)",
+		version, baselineName, checksum); err != nil {
+		return fmt.Errorf("failed to record baseline: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit baseline: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Statements that create, drop or rename tables, matched at the start of a statement so
+// that SQL inside function bodies doesn't count. Names may be schema qualified and quoted.
+var (
+	statusCreateTablePattern = regexp.MustCompile(`(?i)^CREATE\s+(?:UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?((?:"?[a-z_][a-z0-9_]*"?\.)?"?[a-z_][a-z0-9_]*"?)`)
+	statusDropTablePattern   = regexp.MustCompile(`(?is)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(.+?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	statusRenameTablePattern = regexp.MustCompile(`(?i)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?((?:"?[a-z_][a-z0-9_]*"?\.)?"?[a-z_][a-z0-9_]*"?)\s+RENAME\s+TO\s+"?([a-z_][a-z0-9_]*)"?`)
+	lineCommentPattern       = regexp.MustCompile(`(?m)^\s*--.*$`)
+)
+
+// statusTableName returns the unqualified, unquoted and lower-cased name of a table
+// as written in a statement
+func statusTableName(name string) string {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(strings.Trim(name, `"`))
+}
+
+// expectedTables replays the CREATE, DROP and RENAME TABLE statements of the migrations
+// in order and returns the tables that should exist, with the migration creating each.
+func expectedTables(migrations []Migration) map[string]Migration {
+	tables := make(map[string]Migration)
+	for _, migration := range migrations {
+		for _, stmt := range splitStatements(migration.UpSQL) {
+			stmt = strings.TrimSpace(lineCommentPattern.ReplaceAllString(stmt, ""))
+
+			if match := statusCreateTablePattern.FindStringSubmatch(stmt); match != nil {
+				tables[statusTableName(match[1])] = migration
+			} else if match := statusRenameTablePattern.FindStringSubmatch(stmt); match != nil {
+				from, to := statusTableName(match[1]), statusTableName(match[2])
+				if creator, ok := tables[from]; ok {
+					delete(tables, from)
+					tables[to] = creator
+				}
+			} else if match := statusDropTablePattern.FindStringSubmatch(stmt); match != nil {
+				for _, name := range strings.Split(match[1], ",") {
+					delete(tables, statusTableName(name))
+				}
+			}
+		}
+	}
+	return tables
+}
+
+// databaseTables returns the names of the tables in the database, leaving out the
+// migrations table, system schemas and tables owned by extensions. With a table
+// prefix, only the prefixed tables are returned.
+func databaseTables(db *pgxpool.Pool) (map[string]bool, error) {
+	rows, err := db.Query(context.Background(), `
+		SELECT t.table_name
+		FROM information_schema.tables t
+		WHERE t.table_type = 'BASE TABLE'
+		  AND t.table_schema NOT IN ('pg_catalog', 'information_schema')
+		  AND t.table_schema NOT LIKE '\_timescaledb%'
+		  AND t.table_name <> $1
+		  AND t.table_name LIKE $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.objid = format('%I.%I', t.table_schema, t.table_name)::regclass AND d.deptype = 'e'
+		  )`, migrationsTable, strings.ReplaceAll(tablePrefix, "_", `\_`)+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	return tables, nil
+}
+
+// Status compares the database with the migrations: tables no applied migration
+// creates, tables an applied migration creates that don't exist, and applied
+// migrations whose file is missing are reported as drift, and make Status return
+// ErrSchemaDrift. Pending migrations are only counted, since their tables don't exist yet.
+func Status(db *pgxpool.Pool) error {
+	s := packageSettings()
+	if err := s.createMigrationsTable(db); err != nil {
+		return err
+	}
+
+	migrations, err := s.loadMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	applied, err := s.getAppliedMigrations(db)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	appliedVersions := make(map[int64]bool, len(applied))
+	for _, m := range applied {
+		appliedVersions[m.Version] = true
+	}
+
+	// Only applied migrations should have created their tables by now
+	var appliedFiles []Migration
+	pending := 0
+	for _, m := range migrations {
+		if appliedVersions[m.Version] {
+			appliedFiles = append(appliedFiles, m)
+		} else {
+			pending++
+		}
+	}
+	expected := expectedTables(appliedFiles)
+
+	actual, err := databaseTables(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%sSchema Status%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+
+	drift := 0
+	for _, table := range sortedKeys(actual) {
+		if _, ok := expected[table]; !ok {
+			fmt.Printf("%s[DRIFT]%s Table %s%s%s exists in the database but no migration creates it\n",
+				ColorRed, ColorReset, ColorCyan, table, ColorReset)
+			drift++
+		}
+	}
+	for _, table := range sortedKeys(expected) {
+		if !actual[table] {
+			creator := expected[table]
+			fmt.Printf("%s[DRIFT]%s Table %s%s%s is created by %d_%s but doesn't exist in the database\n",
+				ColorRed, ColorReset, ColorCyan, table, ColorReset, creator.Version, creator.Name)
+			drift++
+		}
+	}
+	// getAppliedMigrations returns the newest first
+	for i := len(applied) - 1; i >= 0; i-- {
+		if applied[i].FileMissing {
+			fmt.Printf("%s[DRIFT]%s Migration %s%d_%s%s is applied but its file is missing\n",
+				ColorRed, ColorReset, ColorCyan, applied[i].Version, applied[i].Name, ColorReset)
+			drift++
+		}
+	}
+
+	if pending > 0 {
+		fmt.Printf("%s[PENDING]%s %d migrations are not applied yet\n", ColorYellow, ColorReset, pending)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
+	if drift > 0 {
+		return fmt.Errorf("%w: %d differences between the database and the migrations", ErrSchemaDrift, drift)
+	}
+	fmt.Printf("%s[OK]%s %d tables match the applied migrations\n", ColorGreen, ColorReset, len(actual))
+	return nil
+}
+
+// sortedKeys returns the keys of m in alphabetical order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
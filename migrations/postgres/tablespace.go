@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CreateTablespace creates a tablespace at the given directory on the database server.
+// The directory must already exist, be empty and be owned by the PostgreSQL OS user,
+// and the connection must belong to a superuser.
+func CreateTablespace(conn *pgx.Conn, name, location string) error {
+	_, err := conn.Exec(context.Background(), fmt.Sprintf("CREATE TABLESPACE %s LOCATION '%s'",
+		pgx.Identifier{name}.Sanitize(), strings.ReplaceAll(location, "'", "''")))
+	if err != nil {
+		return fmt.Errorf("error creating tablespace: %v", err)
+	}
+
+	fmt.Printf("%sTablespace '%s' created at %s%s\n", ColorGreen, name, location, ColorReset)
+	return nil
+}
+
+// DropTablespace drops a tablespace. PostgreSQL refuses to drop a tablespace that
+// still contains objects.
+func DropTablespace(conn *pgx.Conn, name string) error {
+	_, err := conn.Exec(context.Background(),
+		fmt.Sprintf("DROP TABLESPACE IF EXISTS %s", pgx.Identifier{name}.Sanitize()))
+	if err != nil {
+		return fmt.Errorf("error dropping tablespace: %v", err)
+	}
+
+	fmt.Printf("%sTablespace '%s' dropped successfully%s\n", ColorGreen, name, ColorReset)
+	return nil
+}
+
+// ListTablespaces prints every tablespace with its owner and location. The built-in
+// pg_default and pg_global tablespaces live in the data directory and have no location.
+func ListTablespaces(db *pgxpool.Pool) error {
+	rows, err := db.Query(context.Background(), `
+		SELECT spcname, pg_get_userbyid(spcowner), pg_tablespace_location(oid)
+		FROM pg_tablespace
+		ORDER BY spcname
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query tablespaces: %w", err)
+	}
+	defer rows.Close()
+
+	// Print header
+	fmt.Printf("\n%sTablespaces%s\n", ColorBold, ColorReset)
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-25s %-20s %s\n", "Name", "Owner", "Location")
+	fmt.Println(strings.Repeat("-", 80))
+
+	for rows.Next() {
+		var name, owner, location string
+		if err := rows.Scan(&name, &owner, &location); err != nil {
+			return fmt.Errorf("failed to scan tablespace row: %w", err)
+		}
+		if location == "" {
+			location = "(data directory)"
+		}
+		fmt.Printf("%s%-25s%s %-20s %s\n", ColorCyan, name, ColorReset, owner, location)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating tablespaces: %w", err)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
+	return nil
+}
@@ -0,0 +1,632 @@
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jbarasa/jbmdb/migrations/internal/migfile"
+)
+
+// CreateMaterializedViewMigration creates a migration file for a materialized view.
+// The view is created WITH NO DATA so the migration stays fast; populate it
+// afterwards with RefreshMaterializedView.
+func CreateMaterializedViewMigration(name string) error {
+	viewName := strings.ToLower(name)
+
+	// Materialized views share the relation namespace with tables
+	if err := checkDuplicateTableName(viewName); err != nil {
+		return err
+	}
+
+	name = fmt.Sprintf("create_%s_materialized_view", viewName)
+	view := prefixedTable(viewName)
+	up, down, ok, err := renderTemplate("view", templateData{
+		Name: name, Table: view, Author: migfile.CurrentUser(), Comment: migrationComment,
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		up = fmt.Sprintf(`CREATE MATERIALIZED VIEW IF NOT EXISTS %s AS
+SELECT
+    -- TODO columns
+FROM -- TODO source tables
+WITH NO DATA;
+
+-- REFRESH MATERIALIZED VIEW CONCURRENTLY requires a unique index on the view
+-- CREATE UNIQUE INDEX IF NOT EXISTS idx_%s_id ON %s (id);`, view, view, view)
+		down = fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS %s;", view)
+	}
+
+	return writeMigrationFile(name, up, down)
+}
+
+// CreatePolicyMigration creates a migration file that enables row-level security on a
+// table and attaches a policy to it. Enabling RLS cannot run inside a transaction in
+// some configurations, so the migration is marked with the no-transaction directive.
+func CreatePolicyMigration(table, name string) error {
+	table = strings.ToLower(table)
+	name = strings.ToLower(name)
+
+	up := fmt.Sprintf(`%s
+ALTER TABLE %s ENABLE ROW LEVEL SECURITY;
+
+-- TODO restrict the visible rows, e.g. USING (tenant_id = current_setting('app.tenant_id')::bigint)
+CREATE POLICY %s ON %s
+    USING (true);`, noTransactionDirective, table, name, table)
+	down := fmt.Sprintf(`DROP POLICY IF EXISTS %s ON %s;
+ALTER TABLE %s DISABLE ROW LEVEL SECURITY;`, name, table, table)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_policy_on_%s", name, table), up, down)
+}
+
+// publicationSQL builds the CREATE PUBLICATION statement for the given tables,
+// publishing all tables when none are specified.
+func publicationSQL(name string, tables []string) string {
+	if len(tables) == 0 {
+		return fmt.Sprintf("CREATE PUBLICATION %s FOR ALL TABLES", name)
+	}
+	return fmt.Sprintf("CREATE PUBLICATION %s FOR TABLE %s", name, strings.Join(tables, ", "))
+}
+
+// CreatePublicationMigration creates a migration file that sets up a logical replication
+// publication on the publisher side. Applying it requires superuser privileges.
+func CreatePublicationMigration(name string, tables []string) error {
+	name = strings.ToLower(name)
+
+	up := fmt.Sprintf(`-- Requires superuser (or table owner) privileges and wal_level = logical
+%s;`, publicationSQL(name, tables))
+	down := fmt.Sprintf("DROP PUBLICATION IF EXISTS %s;", name)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_publication", name), up, down)
+}
+
+// CreateSubscriptionMigration creates a migration file that subscribes to a publication
+// of the same name on the subscriber side. Applying it requires superuser privileges.
+// CREATE and DROP SUBSCRIPTION cannot run inside a transaction block, so the migration
+// is marked with the no-transaction directive.
+func CreateSubscriptionMigration(name string) error {
+	name = strings.ToLower(name)
+
+	up := fmt.Sprintf(`%s
+-- Requires superuser privileges; point CONNECTION at the publisher database
+CREATE SUBSCRIPTION %s
+    CONNECTION 'host=TODO port=5432 dbname=TODO user=TODO password=TODO'
+    PUBLICATION %s;`, noTransactionDirective, name, name)
+	down := fmt.Sprintf("DROP SUBSCRIPTION IF EXISTS %s;", name)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_subscription", name), up, down)
+}
+
+// CreateUnloggedTableMigration creates a migration file for an unlogged table, which
+// skips the WAL for faster writes of ephemeral data such as caches and queues.
+func CreateUnloggedTableMigration(name string) error {
+	tableName := strings.ToLower(name)
+
+	if err := checkDuplicateTableName(tableName); err != nil {
+		return err
+	}
+
+	table := prefixedTable(tableName)
+	up := fmt.Sprintf(`-- Unlogged tables are not written to the WAL: they are truncated after a crash
+-- or unclean shutdown and are not replicated to standbys. Only keep data here
+-- that can be rebuilt.
+CREATE UNLOGGED TABLE IF NOT EXISTS %s (
+    id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL
+);`, table)
+	down := fmt.Sprintf("DROP TABLE IF EXISTS %s;", table)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_table", tableName), up, down)
+}
+
+// nonIdentifierPattern matches runs of characters that can't appear in a migration name
+var nonIdentifierPattern = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// CreateAlterEnumMigration creates a migration file that adds a value to an enum type.
+// ALTER TYPE ... ADD VALUE is marked with the no-transaction directive, and since
+// PostgreSQL cannot remove enum values the migration is also marked no-rollback.
+func CreateAlterEnumMigration(typeName, value string) error {
+	typeName = strings.ToLower(typeName)
+
+	up := fmt.Sprintf(`%s
+ALTER TYPE %s ADD VALUE IF NOT EXISTS '%s';`,
+		noTransactionDirective, typeName, strings.ReplaceAll(value, "'", "''"))
+	down := fmt.Sprintf(`%s
+-- Cannot reverse: PostgreSQL does not support removing enum values`, noRollbackDirective)
+
+	valueName := strings.Trim(nonIdentifierPattern.ReplaceAllString(strings.ToLower(value), "_"), "_")
+	return writeMigrationFile(fmt.Sprintf("alter_%s_add_%s", typeName, valueName), up, down)
+}
+
+// CreateTextSearchMigration creates a migration file that adds a generated
+// search_vector column over the given columns of table, with a GIN index built
+// CONCURRENTLY so writes to the table aren't blocked. Requires PostgreSQL 12+.
+func CreateTextSearchMigration(table string, columns []string, language string) error {
+	table = strings.ToLower(table)
+	language = strings.ToLower(language)
+	if len(columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+	if language == "" || nonIdentifierPattern.MatchString(language) {
+		return fmt.Errorf("invalid text search configuration %q, e.g. english or simple", language)
+	}
+
+	// NULL in any column would make the concatenation NULL
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		parts[i] = fmt.Sprintf("coalesce(%s, '')", strings.ToLower(column))
+	}
+	document := strings.Join(parts, " || ' ' || ")
+	index := fmt.Sprintf("idx_%s_search", table)
+
+	up := fmt.Sprintf(`%s
+ALTER TABLE %s ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('%s', %s)) STORED;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s USING GIN (search_vector);
+
+-- Example query:
+-- SELECT * FROM %s WHERE search_vector @@ websearch_to_tsquery('%s', 'search terms');`,
+		noTransactionDirective, table, language, document, index, table, table, language)
+	down := fmt.Sprintf(`DROP INDEX CONCURRENTLY IF EXISTS %s;
+ALTER TABLE %s DROP COLUMN IF EXISTS search_vector;`, index, table)
+
+	return writeMigrationFile(fmt.Sprintf("add_%s_text_search", table), up, down)
+}
+
+// CreateGeneratedColumnMigration creates a migration file that adds a stored generated
+// column computed from expression. Requires PostgreSQL 12+.
+func CreateGeneratedColumnMigration(table, column, expression, columnType string) error {
+	table = strings.ToLower(table)
+	column = strings.ToLower(column)
+	if strings.TrimSpace(expression) == "" {
+		return fmt.Errorf("the generated column expression must not be empty")
+	}
+
+	up := fmt.Sprintf(`%s
+-- The expression may only use immutable functions and columns of the same row
+ALTER TABLE %s ADD COLUMN %s %s GENERATED ALWAYS AS (%s) STORED;`,
+		noTransactionDirective, table, column, columnType, expression)
+	down := fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s;", table, column)
+
+	return writeMigrationFile(fmt.Sprintf("add_%s_%s_generated", table, column), up, down)
+}
+
+// setUpdatedAtFunction is the trigger function shared by every table with an
+// auto-updated updated_at column.
+const setUpdatedAtFunction = `CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;`
+
+// autoUpdateTriggerSQL returns the statements that create the set_updated_at()
+// function if needed and attach it to table, and the statement detaching it again.
+func autoUpdateTriggerSQL(table string) (string, string) {
+	trigger := fmt.Sprintf("trg_%s_updated_at", table)
+	create := fmt.Sprintf(`%s
+
+DROP TRIGGER IF EXISTS %s ON %s;
+CREATE TRIGGER %s
+    BEFORE UPDATE ON %s
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();`, setUpdatedAtFunction, trigger, table, trigger, table)
+	drop := fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;", trigger, table)
+	return create, drop
+}
+
+// CreateAutoUpdateMigration creates a migration file that keeps the updated_at column
+// of table current with a BEFORE UPDATE trigger. Rolling it back only removes the
+// trigger, as other tables may still use the set_updated_at() function.
+func CreateAutoUpdateMigration(table string) error {
+	table = strings.ToLower(table)
+	create, drop := autoUpdateTriggerSQL(table)
+
+	up := noTransactionDirective + "\n" + create
+	return writeMigrationFile(fmt.Sprintf("add_%s_autoupdate", table), up, drop)
+}
+
+// CreateRemoveAutoUpdateMigration creates a migration file that removes the updated_at
+// trigger CreateAutoUpdateMigration added to table.
+func CreateRemoveAutoUpdateMigration(table string) error {
+	table = strings.ToLower(table)
+	create, drop := autoUpdateTriggerSQL(table)
+
+	up := noTransactionDirective + "\n" + drop
+	return writeMigrationFile(fmt.Sprintf("remove_%s_autoupdate", table), up, create)
+}
+
+// rangePartitionSuffixLayout returns the layout naming partitions after their lower
+// boundary: the year when every boundary is on January 1st, year and month when every
+// boundary is the first of a month, otherwise the whole date. It returns "" when the
+// boundaries aren't dates.
+func rangePartitionSuffixLayout(boundaries []string) string {
+	layout := "2006"
+	for _, boundary := range boundaries {
+		date, err := time.Parse("2006-01-02", boundary)
+		switch {
+		case err != nil:
+			return ""
+		case date.Day() != 1:
+			layout = "2006_01_02"
+		case date.Month() != time.January && layout == "2006":
+			layout = "2006_01"
+		}
+	}
+	return layout
+}
+
+// rangePartitionSuffix names the partition starting at boundary
+func rangePartitionSuffix(boundary, layout string) string {
+	if date, err := time.Parse("2006-01-02", boundary); err == nil && layout != "" {
+		return date.Format(layout)
+	}
+	return strings.Trim(nonIdentifierPattern.ReplaceAllString(strings.ToLower(boundary), "_"), "_")
+}
+
+// generateRangePartitions returns the CREATE TABLE ... PARTITION OF statements for the
+// partitions of table between consecutive boundaries, and their names.
+func generateRangePartitions(table string, boundaries []string) (string, []string, error) {
+	if len(boundaries) < 2 {
+		return "", nil, fmt.Errorf("at least two partition boundaries are required, got %d", len(boundaries))
+	}
+
+	layout := rangePartitionSuffixLayout(boundaries)
+	var statements, names []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(boundaries)-1; i++ {
+		name := table + "_" + rangePartitionSuffix(boundaries[i], layout)
+		if seen[name] {
+			return "", nil, fmt.Errorf("partition boundaries %q produce the duplicate partition name %s", boundaries, name)
+		}
+		seen[name] = true
+
+		statements = append(statements, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s\n    FOR VALUES FROM ('%s') TO ('%s');",
+			name, table, strings.ReplaceAll(boundaries[i], "'", "''"), strings.ReplaceAll(boundaries[i+1], "'", "''")))
+		names = append(names, name)
+	}
+	return strings.Join(statements, "\n\n"), names, nil
+}
+
+// CreateRangePartitionMigration creates a migration file for a table partitioned by
+// range on column, with one partition between each pair of consecutive boundaries.
+// Rows outside the boundaries are rejected until a partition covering them is added.
+func CreateRangePartitionMigration(name, column string, boundaries []string) error {
+	tableName := strings.ToLower(name)
+	column = strings.ToLower(column)
+
+	if err := checkDuplicateTableName(tableName); err != nil {
+		return err
+	}
+
+	table := prefixedTable(tableName)
+	partitions, names, err := generateRangePartitions(table, boundaries)
+	if err != nil {
+		return err
+	}
+
+	// Date boundaries suggest a date column, anything else a number
+	columnType := "BIGINT"
+	if rangePartitionSuffixLayout(boundaries) != "" {
+		columnType = "DATE"
+	}
+
+	up := fmt.Sprintf(`-- The primary key of a partitioned table must include the partition column
+CREATE TABLE IF NOT EXISTS %s (
+    id BIGSERIAL,
+    %s %s NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (id, %s)
+) PARTITION BY RANGE (%s);
+
+%s`, table, column, columnType, column, column, partitions)
+
+	var down []string
+	for _, partition := range names {
+		down = append(down, fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE;", partition))
+	}
+	down = append(down, fmt.Sprintf("DROP TABLE IF EXISTS %s;", table))
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_table", tableName), up, strings.Join(down, "\n"))
+}
+
+// CreateConstraintExclusionMigration creates a migration file for a child table of
+// parent in the inheritance based partitioning of PostgreSQL before 10: the child
+// INHERITS the parent and a CHECK constraint on column lets the planner skip it through
+// constraint exclusion. A trigger on the parent routes inserts into the child.
+func CreateConstraintExclusionMigration(parent, child, column, from, to string) error {
+	parent = prefixedTable(strings.ToLower(parent))
+	column = strings.ToLower(column)
+	if err := checkDuplicateTableName(strings.ToLower(child)); err != nil {
+		return err
+	}
+	child = prefixedTable(strings.ToLower(child))
+	from, to = strings.ReplaceAll(from, "'", "''"), strings.ReplaceAll(to, "'", "''")
+
+	up := fmt.Sprintf(`-- Requires constraint_exclusion = partition (the default) for the planner to skip children
+CREATE TABLE IF NOT EXISTS %s (
+    CONSTRAINT %s_%s_check CHECK (%s >= '%s' AND %s < '%s')
+) INHERITS (%s);
+
+CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s);
+
+-- Inserts into %s are routed to the child covering the row.
+-- TODO add an ELSIF branch for each other child of %s
+CREATE OR REPLACE FUNCTION %s_insert_router() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.%s >= '%s' AND NEW.%s < '%s' THEN
+        INSERT INTO %s VALUES (NEW.*);
+    ELSE
+        RAISE EXCEPTION 'No partition of %s for %s = %%', NEW.%s;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS %s_insert_trigger ON %s;
+CREATE TRIGGER %s_insert_trigger
+    BEFORE INSERT ON %s
+    FOR EACH ROW EXECUTE PROCEDURE %s_insert_router();`,
+		child, child, column, column, from, column, to, parent,
+		child, column, child, column,
+		parent, parent,
+		parent,
+		column, from, column, to,
+		child,
+		parent, column, column,
+		parent, parent, parent, parent, parent)
+	down := fmt.Sprintf(`-- TODO remove the %s branch from %s_insert_router()
+DROP TABLE IF EXISTS %s;`, child, parent, child)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_table", child), up, down)
+}
+
+// CreateDataMigration creates a migration file for a schema change that is backfilled
+// from existing data, with the schema change, the data migration and the cleanup in
+// sections of the Up block.
+func CreateDataMigration(name string) error {
+	name = strings.ToLower(name)
+
+	// The hint must not spell out the directive, which would mark this migration too
+	up := `-- WARNING: data migrations on large tables may be slow and lock tables
+-- The sections run in one transaction. A schema change that can't, e.g. CREATE INDEX
+-- CONCURRENTLY, goes in a migration of its own with the jbmdb no-transaction directive
+
+-- Schema change
+-- TODO e.g. ALTER TABLE users ADD COLUMN full_name TEXT;
+
+-- Data migration
+-- TODO e.g. UPDATE users SET full_name = first_name || ' ' || last_name WHERE full_name IS NULL;
+
+-- Post-migration cleanup
+-- TODO e.g. ALTER TABLE users ALTER COLUMN full_name SET NOT NULL;`
+	down := `-- Reverse the schema change, the backfilled data goes with it
+-- TODO e.g. ALTER TABLE users DROP COLUMN IF EXISTS full_name;`
+
+	return writeMigrationFile(name, up, down)
+}
+
+// CreateHypertableMigration creates a migration file for a TimescaleDB hypertable
+// partitioned by timeColumn. Like any table created by a migration it needs a plural
+// name; the timescaledb extension must be installed before it is applied.
+func CreateHypertableMigration(name, timeColumn string) error {
+	tableName := strings.ToLower(name)
+	timeColumn = strings.ToLower(timeColumn)
+
+	if err := checkDuplicateTableName(tableName); err != nil {
+		return err
+	}
+
+	table := prefixedTable(tableName)
+	up := fmt.Sprintf(`%s
+-- Requires the timescaledb extension: CREATE EXTENSION IF NOT EXISTS timescaledb;
+-- Unique indexes of a hypertable, including its primary key, must contain the time column
+CREATE TABLE IF NOT EXISTS %s (
+    %s TIMESTAMPTZ NOT NULL,
+    -- TODO: add the measurement columns
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+SELECT create_hypertable('%s', '%s', if_not_exists => TRUE);`,
+		noTransactionDirective, table, timeColumn, table, timeColumn)
+
+	// Dropping a hypertable drops its chunks with it
+	down := fmt.Sprintf("DROP TABLE IF EXISTS %s;", table)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_table", tableName), up, down)
+}
+
+// CreateDomainMigration creates a migration file for a domain, a base type with
+// constraints such as a format check for email addresses.
+func CreateDomainMigration(name, baseType string) error {
+	domainName := strings.ToLower(name)
+
+	// Domains share the type namespace, and may not reuse a table name
+	if err := checkDuplicateTableName(domainName); err != nil {
+		return err
+	}
+	if err := checkDuplicateTypeName(domainName); err != nil {
+		return err
+	}
+
+	up := fmt.Sprintf(`CREATE DOMAIN %s AS %s
+    CHECK (VALUE IS NOT NULL); -- TODO replace with the domain constraint, e.g. VALUE ~ '^[^@]+@[^@]+$'`,
+		domainName, baseType)
+	down := fmt.Sprintf("DROP DOMAIN IF EXISTS %s;", domainName)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_domain", domainName), up, down)
+}
+
+// CreateRangeTypeMigration creates a migration file for a range type over subtype, e.g.
+// a floatrange over float8. Built-in ranges such as daterange need no migration.
+func CreateRangeTypeMigration(name, subtype string) error {
+	typeName := strings.ToLower(name)
+
+	// Range types share the type namespace with enums, composites and domains
+	if err := checkDuplicateTableName(typeName); err != nil {
+		return err
+	}
+	if err := checkDuplicateTypeName(typeName); err != nil {
+		return err
+	}
+
+	up := fmt.Sprintf("CREATE TYPE %s AS RANGE (SUBTYPE = %s);", typeName, strings.ToLower(subtype))
+	down := fmt.Sprintf("DROP TYPE IF EXISTS %s;", typeName)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_type", typeName), up, down)
+}
+
+// CreateCompositeTypeMigration creates a migration file for a composite (row) type,
+// e.g. for use in function signatures.
+func CreateCompositeTypeMigration(name string) error {
+	typeName := strings.ToLower(name)
+
+	// Composite types share the relation namespace with tables
+	if err := checkDuplicateTableName(typeName); err != nil {
+		return err
+	}
+	if err := checkDuplicateTypeName(typeName); err != nil {
+		return err
+	}
+
+	up := fmt.Sprintf(`CREATE TYPE %s AS (
+    field1 text,
+    field2 integer
+);`, typeName)
+	down := fmt.Sprintf("DROP TYPE IF EXISTS %s;", typeName)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_type", typeName), up, down)
+}
+
+// CreateCheckConstraintMigration creates a migration file that adds a CHECK constraint
+// to a table. The condition is left as a placeholder to fill in.
+func CreateCheckConstraintMigration(table, name string) error {
+	table = strings.ToLower(table)
+	name = strings.ToLower(name)
+
+	if err := checkDuplicateConstraintName(name); err != nil {
+		return err
+	}
+
+	up := fmt.Sprintf(`ALTER TABLE %s ADD CONSTRAINT %s CHECK (
+    -- TODO condition, e.g. price >= 0
+    true
+);`, table, name)
+	down := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", table, name)
+
+	return writeMigrationFile(fmt.Sprintf("add_%s_constraint", name), up, down)
+}
+
+// CreateUniqueConstraintMigration creates a migration file that adds a UNIQUE constraint
+// named uq_<table>_<columns> on the given columns.
+func CreateUniqueConstraintMigration(table string, columns []string) error {
+	table = strings.ToLower(table)
+	columns = slices.Clone(columns)
+	for i, column := range columns {
+		columns[i] = strings.ToLower(column)
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+
+	name := fmt.Sprintf("uq_%s_%s", table, strings.Join(columns, "_"))
+	if err := checkDuplicateConstraintName(name); err != nil {
+		return err
+	}
+
+	up := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s);", table, name, strings.Join(columns, ", "))
+	down := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", table, name)
+
+	return writeMigrationFile(fmt.Sprintf("add_%s_constraint", name), up, down)
+}
+
+// statisticsKinds are the valid kinds of an extended statistics object
+var statisticsKinds = []string{"ndistinct", "dependencies", "mcv"}
+
+// CreateStatisticsMigration creates a migration file for an extended statistics
+// object on correlated columns of a table, so the planner can estimate their
+// combined selectivity. Empty kinds builds every kind the server supports.
+func CreateStatisticsMigration(name, table string, columns, kinds []string) error {
+	name = strings.ToLower(name)
+	table = strings.ToLower(table)
+	columns = slices.Clone(columns)
+	for i, column := range columns {
+		columns[i] = strings.ToLower(column)
+	}
+	if len(columns) < 2 {
+		return fmt.Errorf("statistics need at least two columns, got %d", len(columns))
+	}
+
+	kindClause := ""
+	if len(kinds) > 0 {
+		kinds = slices.Clone(kinds)
+		for i, kind := range kinds {
+			kinds[i] = strings.ToLower(kind)
+			if !slices.Contains(statisticsKinds, kinds[i]) {
+				return fmt.Errorf("invalid statistics kind %q: must be one of %s",
+					kind, strings.Join(statisticsKinds, ", "))
+			}
+		}
+		kindClause = " (" + strings.Join(kinds, ", ") + ")"
+	}
+
+	up := fmt.Sprintf(`CREATE STATISTICS IF NOT EXISTS %s%s ON %s FROM %s;
+
+-- Statistics are collected by the next ANALYZE
+ANALYZE %s;`, name, kindClause, strings.Join(columns, ", "), table, table)
+	down := fmt.Sprintf("DROP STATISTICS IF EXISTS %s;", name)
+
+	return writeMigrationFile(fmt.Sprintf("create_%s_statistics", name), up, down)
+}
+
+// referentialActions are the valid ON DELETE / ON UPDATE actions for foreign keys.
+var referentialActions = []string{"CASCADE", "RESTRICT", "SET NULL", "SET DEFAULT", "NO ACTION"}
+
+// normalizeReferentialAction validates a foreign key action, accepting forms such as
+// "set-null" or "set_null" for SET NULL.
+func normalizeReferentialAction(action string) (string, error) {
+	normalized := strings.ToUpper(strings.NewReplacer("-", " ", "_", " ").Replace(action))
+	for _, valid := range referentialActions {
+		if normalized == valid {
+			return normalized, nil
+		}
+	}
+	return "", fmt.Errorf("invalid referential action: %s (valid: %s)", action, strings.Join(referentialActions, ", "))
+}
+
+// CreateForeignKeyMigration creates a migration file that adds a foreign key from
+// fromTable.fromColumn to toTable(id), named fk_<from_table>_<to_table>.
+func CreateForeignKeyMigration(fromTable, fromColumn, toTable, onDelete, onUpdate string) error {
+	fromTable = strings.ToLower(fromTable)
+	fromColumn = strings.ToLower(fromColumn)
+	toTable = strings.ToLower(toTable)
+
+	onDelete, err := normalizeReferentialAction(onDelete)
+	if err != nil {
+		return err
+	}
+	onUpdate, err = normalizeReferentialAction(onUpdate)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("fk_%s_%s", fromTable, toTable)
+	if err := checkDuplicateConstraintName(name); err != nil {
+		return err
+	}
+
+	up := fmt.Sprintf(`-- On large tables, add NOT VALID to skip the full-table check while holding the lock,
+-- then run ALTER TABLE %s VALIDATE CONSTRAINT %s in a later migration
+ALTER TABLE %s ADD CONSTRAINT %s
+    FOREIGN KEY (%s) REFERENCES %s(id)
+    ON DELETE %s ON UPDATE %s;`, fromTable, name, fromTable, name, fromColumn, toTable, onDelete, onUpdate)
+	down := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", fromTable, name)
+
+	return writeMigrationFile(fmt.Sprintf("add_%s_constraint", name), up, down)
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"net"
+	"net/url"
+
+	"github.com/jbarasa/jbmdb/migrations/config"
+)
+
+// defaultSSLMode is used when the config has no ssl_mode, as before TLS was configurable
+const defaultSSLMode = "disable"
+
+// ConnString returns the connection URL of dbName on the configured server for user,
+// with the sslmode and certificate files of the config: require encrypts the
+// connection, verify-ca also checks the server certificate against SSLRootCert and
+// verify-full its host name too. SSLCert and SSLKey authenticate with a client
+// certificate.
+func ConnString(pgConfig *config.PostgresConfig, user, password, dbName string) string {
+	params := url.Values{}
+	params.Set("sslmode", sslMode(pgConfig))
+	if pgConfig.SSLRootCert != "" {
+		params.Set("sslrootcert", pgConfig.SSLRootCert)
+	}
+	if pgConfig.SSLCert != "" {
+		params.Set("sslcert", pgConfig.SSLCert)
+	}
+	if pgConfig.SSLKey != "" {
+		params.Set("sslkey", pgConfig.SSLKey)
+	}
+
+	// url.URL escapes the credentials, which may contain @, : or /
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(user, password),
+		Host:     net.JoinHostPort(pgConfig.Host, pgConfig.Port),
+		Path:     "/" + dbName,
+		RawQuery: params.Encode(),
+	}
+	return u.String()
+}
+
+// sslMode returns the sslmode of the config, defaulting to disable
+func sslMode(pgConfig *config.PostgresConfig) string {
+	if pgConfig.SSLMode == "" {
+		return defaultSSLMode
+	}
+	return pgConfig.SSLMode
+}
+
+// sslEnv returns the libpq environment variables that give client tools such as
+// pg_dump the TLS settings of the config
+func sslEnv(pgConfig *config.PostgresConfig) []string {
+	env := []string{"PGSSLMODE=" + sslMode(pgConfig)}
+	if pgConfig.SSLRootCert != "" {
+		env = append(env, "PGSSLROOTCERT="+pgConfig.SSLRootCert)
+	}
+	if pgConfig.SSLCert != "" {
+		env = append(env, "PGSSLCERT="+pgConfig.SSLCert)
+	}
+	if pgConfig.SSLKey != "" {
+		env = append(env, "PGSSLKEY="+pgConfig.SSLKey)
+	}
+	return env
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// baseTemplates matches the files holding the blocks shared by all templates of the
+// template directory: base.tmpl, and base_*.tmpl for splitting them up
+const baseTemplates = "base*.tmpl"
+
+// Directory of the .tmpl files new migrations are generated from, see SetTemplateDir
+var templateDir string
+
+// Template selected with --template, see SetTemplate
+var templateName string
+
+// SetTemplateDir sets the directory of the user's migration templates. Without one the
+// built-in SQL is used.
+func SetTemplateDir(dir string) {
+	templateDir = dir
+}
+
+// SetTemplate selects the template new migrations are generated from instead of the
+// one named after the migration type, e.g. "audited_table" for audited_table.tmpl.
+func SetTemplate(name string) {
+	templateName = strings.TrimSuffix(name, ".tmpl")
+}
+
+// templateData is what the user's templates are executed with
+type templateData struct {
+	Name    string // Migration name, e.g. create_users_table
+	Table   string // Table or view the migration creates
+	Author  string // Current OS user
+	Comment string // --comment of the run
+
+	Tablespace   string           // --tablespace, empty for the default tablespace
+	IDColumn     string           // Definition of the id column for --primary-key, empty for none
+	References   []templateColumn // Foreign key columns added with --references
+	RangeColumns []templateColumn // Range columns added with --range-column, which need a GiST index
+}
+
+// templateColumn is a column of templateData, e.g. {user_id BIGINT users} for
+// --references users
+type templateColumn struct {
+	Name       string // Column name
+	Type       string // Column type
+	References string // Referenced table, empty for range columns
+}
+
+// isBaseTemplate reports whether the template file name is one of the shared base templates
+func isBaseTemplate(file string) bool {
+	ok, _ := filepath.Match(baseTemplates, file)
+	return ok
+}
+
+// parseTemplate parses the base templates, when present, and then <name>.tmpl. Blocks
+// of the base are overridden by defining them again in <name>.tmpl, since later
+// definitions win.
+func parseTemplate(name string) (*template.Template, error) {
+	tmpl := template.New(name + ".tmpl")
+
+	bases, err := filepath.Glob(filepath.Join(templateDir, baseTemplates))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	if len(bases) > 0 {
+		if tmpl, err = tmpl.ParseGlob(filepath.Join(templateDir, baseTemplates)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+	}
+	if isBaseTemplate(name + ".tmpl") {
+		return tmpl, nil
+	}
+
+	tmpl, err = tmpl.ParseFiles(filepath.Join(templateDir, name+".tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// checkNoTemplate returns an error when --template is set for a migration that isn't
+// generated from templates, instead of ignoring it
+func checkNoTemplate(kind string) error {
+	if templateName != "" {
+		return fmt.Errorf("--template is not supported for %s migrations, only for new tables and materialized views", kind)
+	}
+	return nil
+}
+
+// renderTemplate executes the "up" and "down" templates of the template for kind, e.g.
+// "table". ok is false when no template directory is configured or it has no template
+// for kind, and the built-in SQL applies. A template selected with --template replaces
+// kind and must exist.
+func renderTemplate(kind string, data templateData) (up, down string, ok bool, err error) {
+	if templateDir == "" {
+		if templateName != "" {
+			return "", "", false, fmt.Errorf("--template requires template_dir in the postgres config")
+		}
+		return "", "", false, nil
+	}
+
+	name := kind
+	if templateName != "" {
+		name = templateName
+	}
+	if _, err := os.Stat(filepath.Join(templateDir, name+".tmpl")); err != nil {
+		if os.IsNotExist(err) && templateName == "" {
+			return "", "", false, nil
+		}
+		return "", "", false, fmt.Errorf("template %s: %w", name, err)
+	}
+
+	tmpl, err := parseTemplate(name)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	var sections [2]strings.Builder
+	for i, section := range []string{"up", "down"} {
+		if err := tmpl.ExecuteTemplate(&sections[i], section, data); err != nil {
+			return "", "", false, fmt.Errorf("failed to execute template %s: %w", name, err)
+		}
+	}
+	return strings.TrimSpace(sections[0].String()), strings.TrimSpace(sections[1].String()), true, nil
+}
+
+// ValidateTemplates parses every template of the template directory together with
+// base.tmpl and prints whether it is valid, with the syntax error if not.
+func ValidateTemplates() error {
+	if templateDir == "" {
+		return fmt.Errorf("no template_dir configured in the postgres config")
+	}
+
+	files, err := filepath.Glob(filepath.Join(templateDir, "*.tmpl"))
+	if err != nil {
+		return fmt.Errorf("failed to list templates: %w", err)
+	}
+	if len(files) == 0 {
+		fmt.Printf("%sNo templates found in %s%s\n", ColorYellow, templateDir, ColorReset)
+		return nil
+	}
+
+	invalid := 0
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+		if _, err := parseTemplate(name); err != nil {
+			invalid++
+			fmt.Printf("%s[INVALID]%s %s: %v\n", ColorRed, ColorReset, filepath.Base(file), err)
+			continue
+		}
+		fmt.Printf("%s[VALID]%s %s\n", ColorGreen, ColorReset, filepath.Base(file))
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%w: %d of %d templates", ErrInvalidTemplate, invalid, len(files))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Whether Migrate records the WAL position before and after the run.
+var captureWAL bool
+
+// SetCaptureWALPosition makes Migrate print the WAL position before and after
+// applying migrations, for targeted recovery with pg_waldump or WAL replay.
+func SetCaptureWALPosition(enabled bool) {
+	captureWAL = enabled
+}
+
+// GetWALPosition returns the current write-ahead log location, e.g. "0/1A2B3C".
+// It fails on a standby, where no WAL is written.
+func GetWALPosition(db *pgxpool.Pool) (string, error) {
+	return getWALPosition(context.Background(), db)
+}
+
+// getWALPosition returns the current write-ahead log location, see GetWALPosition.
+func getWALPosition(ctx context.Context, db *pgxpool.Pool) (string, error) {
+	var lsn string
+	if err := db.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		return "", fmt.Errorf("failed to read WAL position: %w", err)
+	}
+	return lsn, nil
+}
+
+// walPosition returns the current WAL position for a migration run, printing a
+// warning instead of failing the run when it can't be read.
+func (s *settings) walPosition(db *pgxpool.Pool) string {
+	lsn, err := getWALPosition(s.ctx, db)
+	if err != nil {
+		fmt.Printf("%s[WARNING]%s %v\n", ColorYellow, ColorReset, err)
+	}
+	return lsn
+}
+
+// printWALPositions captures the position after a run and prints both positions
+func (s *settings) printWALPositions(db *pgxpool.Pool, result *MigrateResult) {
+	result.WALAfter = s.walPosition(db)
+	fmt.Printf("%s[WAL]%s Before: %s, After: %s\n", ColorCyan, ColorReset, result.WALBefore, result.WALAfter)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// errSelectionCancelled is returned when the user aborts an interactive selection.
+var errSelectionCancelled = errors.New("selection cancelled")
+
+// selectItems shows a checkbox prompt for the given items and returns the indexes of
+// the checked items in ascending order. Arrow keys (or j/k) move the cursor, space
+// toggles an item, a toggles all items, enter confirms and q or Ctrl-C cancels.
+func selectItems(title string, items []string) ([]int, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("interactive selection requires a terminal")
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable raw terminal mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	checked := make([]bool, len(items))
+	cursor := 0
+
+	// Raw mode disables newline translation, so every line ends with \r\n
+	render := func(redraw bool) {
+		if redraw {
+			fmt.Printf("\033[%dA", len(items))
+		}
+		for i, item := range items {
+			pointer := " "
+			if i == cursor {
+				pointer = colorCyan + ">" + colorReset
+			}
+			box := "[ ]"
+			if checked[i] {
+				box = colorGreen + "[x]" + colorReset
+			}
+			fmt.Printf("\r\033[2K%s %s %s\r\n", pointer, box, item)
+		}
+	}
+
+	fmt.Printf("%s%s%s\r\n", textBold, title, colorReset)
+	fmt.Printf("(up/down move, space toggle, a toggle all, enter confirm, q cancel)\r\n")
+	render(false)
+
+	buf := make([]byte, 3)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input: %w", err)
+		}
+		key := string(buf[:n])
+
+		switch key {
+		case "\033[A", "k":
+			if cursor > 0 {
+				cursor--
+			}
+		case "\033[B", "j":
+			if cursor < len(items)-1 {
+				cursor++
+			}
+		case " ":
+			checked[cursor] = !checked[cursor]
+		case "a":
+			all := true
+			for _, c := range checked {
+				all = all && c
+			}
+			for i := range checked {
+				checked[i] = !all
+			}
+		case "\r", "\n":
+			var selected []int
+			for i, c := range checked {
+				if c {
+					selected = append(selected, i)
+				}
+			}
+			return selected, nil
+		case "q", "\x03":
+			return nil, errSelectionCancelled
+		}
+		render(true)
+	}
+}
@@ -1,6 +1,7 @@
 package update
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -92,7 +93,25 @@ func CheckForUpdates(currentVersion string) (*Release, error) {
 		currentVersion = "v0.0.0"
 	}
 
-	resp, err := http.Get(githubAPI)
+	release, err := NewerRelease(context.Background(), currentVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if release == nil {
+		fmt.Printf("You are already on the latest version (%s)\n", currentVersion)
+	}
+	return release, nil
+}
+
+// LatestRelease fetches the latest release from GitHub, giving up when ctx is done
+func LatestRelease(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubAPI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check for updates: %v", err)
 	}
@@ -103,6 +122,17 @@ func CheckForUpdates(currentVersion string) (*Release, error) {
 		return nil, fmt.Errorf("failed to parse release info: %v", err)
 	}
 
+	return &release, nil
+}
+
+// NewerRelease returns the latest release if it is newer than currentVersion, or nil
+// when currentVersion is up to date. Unlike CheckForUpdates it prints nothing.
+func NewerRelease(ctx context.Context, currentVersion string) (*Release, error) {
+	release, err := LatestRelease(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Compare versions
 	newer, err := isNewer(release.TagName, currentVersion)
 	if err != nil {
@@ -110,15 +140,14 @@ func CheckForUpdates(currentVersion string) (*Release, error) {
 	}
 
 	if !newer {
-		fmt.Printf("You are already on the latest version (%s)\n", currentVersion)
 		return nil, nil
 	}
-
-	return &release, nil
+	return release, nil
 }
 
-// DownloadUpdate downloads and replaces the current binary with the new version
-func DownloadUpdate(release *Release) error {
+// DownloadUpdate downloads and replaces the current binary with the new version,
+// giving up when ctx is done
+func DownloadUpdate(ctx context.Context, release *Release) error {
 	// Determine which binary to download based on OS
 	var binaryName string
 	switch runtime.GOOS {
@@ -146,7 +175,11 @@ func DownloadUpdate(release *Release) error {
 	}
 
 	// Download the new binary with progress
-	resp, err := http.Get(downloadURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download update: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to download update: %v", err)
 	}